@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/synchhans/ecommerce-backend/internal/module/cart"
+	"github.com/synchhans/ecommerce-backend/internal/module/wishlist"
+)
+
+// wishlistCarts lets the wishlist move items into the user's cart. Cart
+// rejections are translated to the wishlist's errors so its handler can
+// answer with the same codes the cart endpoints use.
+type wishlistCarts struct {
+	svc *cart.Service
+}
+
+func (c wishlistCarts) AddToUserCart(ctx context.Context, userID, variantID string, qty int) (string, error) {
+	cartID, err := c.svc.AddToUserCart(ctx, userID, variantID, qty)
+	switch {
+	case errors.Is(err, cart.ErrNotFound):
+		return "", wishlist.ErrUnavailable
+	case errors.Is(err, cart.ErrInsufficientStock):
+		return "", wishlist.ErrInsufficientStock
+	case errors.Is(err, cart.ErrPriceUnavailable):
+		return "", wishlist.ErrPriceUnavailable
+	case errors.Is(err, cart.ErrInvalidQty):
+		return "", wishlist.ErrInvalidQty
+	}
+	return cartID, err
+}
@@ -15,7 +15,10 @@ import (
 	"github.com/synchhans/ecommerce-backend/internal/module/inventory"
 	"github.com/synchhans/ecommerce-backend/internal/module/order"
 	"github.com/synchhans/ecommerce-backend/internal/module/payment"
+	"github.com/synchhans/ecommerce-backend/internal/module/pricing"
+	"github.com/synchhans/ecommerce-backend/internal/module/review"
 	"github.com/synchhans/ecommerce-backend/internal/module/user"
+	"github.com/synchhans/ecommerce-backend/internal/module/wishlist"
 	"github.com/synchhans/ecommerce-backend/internal/platform/database"
 	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
@@ -48,24 +51,24 @@ func main() {
 	// ======================
 
 	// Catalog
+	catalogRepo := catalog.NewPostgresRepository(pg.Pool)
 	catalogHandler := catalog.NewHandler(
-		catalog.NewService(
-			catalog.NewPostgresRepository(pg.Pool),
-		),
+		catalog.NewService(catalogRepo),
+		cfg.SiteURL,
 	)
 
 	// Cart
-	cartHandler := cart.NewHandler(
-		cart.NewService(
-			cart.NewPostgresRepository(pg.Pool),
-		),
-	)
+	cartRepo := cart.NewPostgresRepository(pg.Pool)
+	cartService := cart.NewService(cartRepo)
+	cartHandler := cart.NewHandler(cartService, cfg.JWTSecret, cfg.SiteURL)
 
 	// Order
 	orderHandler := order.NewHandler(
 		order.NewService(
 			order.NewPostgresRepository(pg.Pool),
+			order.ShippingRate{BaseFee: cfg.ShippingBaseFee, PerKg: cfg.ShippingRatePerKg},
 		),
+		cfg.JWTSecret, // verifies guest cart tokens signed by the cart handler
 	)
 
 	// Payment
@@ -76,9 +79,15 @@ func main() {
 	)
 
 	// Inventory
+	inventoryRepo := inventory.NewPostgresRepository(pg.Pool)
 	inventoryHandler := inventory.NewHandler(
-		inventory.NewService(
-			inventory.NewPostgresRepository(pg.Pool),
+		inventory.NewService(inventoryRepo),
+	)
+
+	// Pricing (currencies, FX rates, price lists)
+	pricingHandler := pricing.NewHandler(
+		pricing.NewService(
+			pricing.NewPostgresRepository(pg.Pool),
 		),
 	)
 
@@ -86,11 +95,27 @@ func main() {
 	userHandler := user.NewHandler(
 		user.NewService(
 			user.NewPostgresRepository(pg.Pool),
+			cartService, // merges the guest cart at login
 			cfg.JWTSecret,
 		),
 		cfg.JWTSecret,
 	)
 
+	// Reviews
+	reviewHandler := review.NewHandler(
+		review.NewService(
+			review.NewPostgresRepository(pg.Pool),
+		),
+	)
+
+	// Wishlist (protected)
+	wishlistHandler := wishlist.NewHandler(
+		wishlist.NewService(
+			wishlist.NewPostgresRepository(pg.Pool),
+			wishlistCarts{svc: cartService}, // "move to cart"
+		),
+	)
+
 	// Address (protected)
 	addressHandler := address.NewHandler(
 		address.NewService(
@@ -98,6 +123,35 @@ func main() {
 		),
 	)
 
+	// ======================
+	// Background jobs
+	// ======================
+
+	go inventory.NewLowStockChecker(
+		inventoryRepo,
+		inventory.LogNotifier{},
+		cfg.LowStockCheckInterval,
+	).Run(ctx)
+
+	go catalog.NewPriceScheduler(
+		catalogRepo,
+		cfg.PriceScheduleInterval,
+	).Run(ctx)
+
+	go catalog.NewCoPurchaseBuilder(
+		catalogRepo,
+		cfg.RecommendationInterval,
+	).Run(ctx)
+
+	go cart.NewAbandonedCartDetector(
+		cartRepo,
+		cart.LogNotifier{},
+		cfg.CartAbandonCheckInterval,
+		cfg.CartAbandonAfter,
+		cfg.APIURL+"/v1/cart/recover", // served by cartHandler
+		cfg.JWTSecret,
+	).Run(ctx)
+
 	// ======================
 	// Routes
 	// ======================
@@ -105,16 +159,37 @@ func main() {
 	r.Route("/v1", func(v1 chi.Router) {
 		// Public
 		catalogHandler.Routes(v1)
-		cartHandler.Routes(v1)
-		orderHandler.Routes(v1)
 		paymentHandler.Routes(v1)
 		inventoryHandler.Routes(v1)
+		pricingHandler.Routes(v1)
+		reviewHandler.Routes(v1)
 		userHandler.Routes(v1)
 
+		// Signed in or guest; carts and orders record the user when there is one
+		v1.Group(func(or chi.Router) {
+			or.Use(httpx.OptionalAuth([]byte(cfg.JWTSecret)))
+			cartHandler.Routes(or)
+			orderHandler.Routes(or)
+		})
+
 		// Protected
 		v1.Group(func(pr chi.Router) {
 			pr.Use(httpx.AuthMiddleware([]byte(cfg.JWTSecret)))
 			addressHandler.Routes(pr)
+			cartHandler.UserRoutes(pr)
+			reviewHandler.UserRoutes(pr)
+			wishlistHandler.Routes(pr)
+		})
+
+		// Admin
+		v1.Group(func(ar chi.Router) {
+			ar.Use(httpx.AuthMiddleware([]byte(cfg.JWTSecret)))
+			ar.Use(httpx.RequireRole("admin"))
+			catalogHandler.AdminRoutes(ar)
+			cartHandler.AdminRoutes(ar)
+			inventoryHandler.AdminRoutes(ar)
+			pricingHandler.AdminRoutes(ar)
+			reviewHandler.AdminRoutes(ar)
 		})
 	})
 
@@ -0,0 +1,109 @@
+// Command catalog bulk-imports and exports products in the same CSV/JSON
+// format as the admin /admin/catalog/import and /admin/catalog/export
+// endpoints.
+//
+//	catalog import [-dry-run] [-format csv|json] FILE
+//	catalog export [-format csv|json] [-o FILE]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/synchhans/ecommerce-backend/internal/config"
+	"github.com/synchhans/ecommerce-backend/internal/module/catalog"
+	"github.com/synchhans/ecommerce-backend/internal/platform/database"
+)
+
+const usage = "usage: catalog import [-dry-run] [-format csv|json] FILE | catalog export [-format csv|json] [-o FILE]"
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	default:
+		log.Fatal(usage)
+	}
+}
+
+func newService(ctx context.Context) (*catalog.Service, func()) {
+	cfg := config.Load()
+	pg, err := database.New(ctx, database.Config{DSN: cfg.DatabaseDSN})
+	if err != nil {
+		log.Fatalf("db init error: %v", err)
+	}
+	return catalog.NewService(catalog.NewPostgresRepository(pg.Pool)), pg.Close
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "validate only, write nothing")
+	format := fs.String("format", "", "csv or json (default: from the file extension)")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal(usage)
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	svc, closeDB := newService(ctx)
+	defer closeDB()
+
+	rep, err := svc.Import(ctx, f, *format, *dryRun, "cli")
+	if err != nil {
+		log.Fatalf("import failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(rep)
+	if len(rep.Errors) > 0 {
+		closeDB()
+		os.Exit(1)
+	}
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", catalog.FormatCSV, "csv or json")
+	out := fs.String("o", "", "output file (default: stdout)")
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	svc, closeDB := newService(ctx)
+	defer closeDB()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := svc.Export(ctx, w, *format); err != nil {
+		log.Fatalf("export failed: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/synchhans/ecommerce-backend/internal/module/catalog"
+)
+
+// department drives the generated products of one category: names are
+// "<brand> <adjective> <noun>", prices in whole rupiah.
+type department struct {
+	category   string // slug path
+	brands     []string
+	adjectives []string
+	nouns      []string
+	options    []option
+	minPrice   int64
+	maxPrice   int64
+	minWeight  int
+	maxWeight  int
+}
+
+type option struct {
+	name   string
+	values []string
+}
+
+var (
+	apparelSizes  = option{"Size", []string{"S", "M", "L", "XL", "XXL"}}
+	apparelColors = option{"Color", []string{"Black", "White", "Navy", "Olive", "Maroon", "Grey"}}
+	shoeSizes     = option{"Size", []string{"39", "40", "41", "42", "43", "44"}}
+	phoneStorage  = option{"Storage", []string{"128GB", "256GB", "512GB"}}
+	deviceColors  = option{"Color", []string{"Black", "Silver", "Blue", "Green"}}
+	volumes       = option{"Volume", []string{"30ml", "50ml", "100ml"}}
+)
+
+var departments = []department{
+	{
+		category: "electronics/phones", brands: []string{"Nusa", "Orbit", "Kilat", "Vega"},
+		adjectives: []string{"Pro", "Lite", "Max", "Neo", "Ultra"}, nouns: []string{"Smartphone", "Phone"},
+		options: []option{phoneStorage, deviceColors}, minPrice: 1_500_000, maxPrice: 20_000_000, minWeight: 150, maxWeight: 250,
+	},
+	{
+		category: "electronics/audio", brands: []string{"Gema", "Sonik", "Irama"},
+		adjectives: []string{"Wireless", "Noise Cancelling", "Studio", "Sport"}, nouns: []string{"Earbuds", "Headphones", "Speaker"},
+		options: []option{deviceColors}, minPrice: 150_000, maxPrice: 4_000_000, minWeight: 50, maxWeight: 900,
+	},
+	{
+		category: "electronics/accessories", brands: []string{"Kabel", "Daya", "Orbit"},
+		adjectives: []string{"Fast", "Braided", "Compact", "Magnetic"}, nouns: []string{"Charger", "Power Bank", "USB-C Cable", "Keyboard", "Mouse"},
+		options: []option{deviceColors}, minPrice: 35_000, maxPrice: 1_500_000, minWeight: 40, maxWeight: 700,
+	},
+	{
+		category: "fashion/men", brands: []string{"Batik Kita", "Urban Jawa", "Rimba"},
+		adjectives: []string{"Oversized", "Slim Fit", "Linen", "Cotton", "Heavyweight"}, nouns: []string{"T-Shirt", "Hoodie", "Shirt", "Chinos", "Jacket"},
+		options: []option{apparelSizes, apparelColors}, minPrice: 79_000, maxPrice: 899_000, minWeight: 150, maxWeight: 900,
+	},
+	{
+		category: "fashion/women", brands: []string{"Kirana", "Melati", "Urban Jawa"},
+		adjectives: []string{"Flowy", "Pleated", "Linen", "Knitted", "Cropped"}, nouns: []string{"Blouse", "Dress", "Skirt", "Cardigan", "Hijab"},
+		options: []option{apparelSizes, apparelColors}, minPrice: 69_000, maxPrice: 799_000, minWeight: 100, maxWeight: 700,
+	},
+	{
+		category: "fashion/shoes", brands: []string{"Langkah", "Rimba", "Tapak"},
+		adjectives: []string{"Canvas", "Leather", "Running", "Slip-On"}, nouns: []string{"Sneakers", "Loafers", "Sandals", "Boots"},
+		options: []option{shoeSizes, apparelColors}, minPrice: 149_000, maxPrice: 1_899_000, minWeight: 500, maxWeight: 1500,
+	},
+	{
+		category: "home/kitchen", brands: []string{"Dapur", "Rumahku", "Kayu Jati"},
+		adjectives: []string{"Non-Stick", "Stainless", "Ceramic", "Bamboo"}, nouns: []string{"Frying Pan", "Knife Set", "Mug", "Cutting Board", "Rice Cooker"},
+		options: []option{deviceColors}, minPrice: 29_000, maxPrice: 1_299_000, minWeight: 200, maxWeight: 4000,
+	},
+	{
+		category: "home/decor", brands: []string{"Rumahku", "Anyaman", "Kayu Jati"},
+		adjectives: []string{"Minimalist", "Rattan", "Warm LED", "Handwoven"}, nouns: []string{"Desk Lamp", "Basket", "Wall Clock", "Cushion"},
+		minPrice: 49_000, maxPrice: 999_000, minWeight: 300, maxWeight: 3000,
+	},
+	{
+		category: "beauty/skincare", brands: []string{"Sari Ayu", "Embun", "Kulit"},
+		adjectives: []string{"Hydrating", "Brightening", "Gentle", "Niacinamide"}, nouns: []string{"Serum", "Moisturizer", "Toner", "Sunscreen", "Face Wash"},
+		options: []option{volumes}, minPrice: 39_000, maxPrice: 499_000, minWeight: 50, maxWeight: 300,
+	},
+	{
+		category: "sports/outdoor", brands: []string{"Rimba", "Puncak", "Arus"},
+		adjectives: []string{"Waterproof", "Lightweight", "Foldable", "Insulated"}, nouns: []string{"Backpack", "Tent", "Water Bottle", "Trekking Pole"},
+		options: []option{apparelColors}, minPrice: 59_000, maxPrice: 2_999_000, minWeight: 200, maxWeight: 5000,
+	},
+}
+
+// productRows generates product i: 1-maxVariants variants with distinct
+// option combinations. Each product draws from its own stream, so the output
+// for a given seed doesn't depend on the batch size.
+func productRows(seed uint64, i, maxVariants int, location string) []catalog.TransferRow {
+	rng := rand.New(rand.NewPCG(seed, uint64(i)))
+	d := departments[rng.IntN(len(departments))]
+
+	name := fmt.Sprintf("%s %s %s", pick(rng, d.brands), pick(rng, d.adjectives), pick(rng, d.nouns))
+	slug := fmt.Sprintf("%s-%d", catalog.Slugify(name), i)
+	active := rng.IntN(50) != 0
+
+	images := make([]string, 1+rng.IntN(4))
+	for k := range images {
+		images[k] = fmt.Sprintf("https://picsum.photos/seed/%s-%d/800/800", slug, k+1)
+	}
+	categories := []string{d.category}
+	if rng.IntN(5) == 0 {
+		categories = append(categories, "sale")
+	}
+
+	base := roundTo(d.minPrice+rng.Int64N(d.maxPrice-d.minPrice+1), 1000)
+	weight := d.minWeight + rng.IntN(d.maxWeight-d.minWeight+1)
+
+	combos := combinations(rng, d.options, 1+rng.IntN(maxVariants))
+	rows := make([]catalog.TransferRow, 0, len(combos))
+	for j, combo := range combos {
+		price := base
+		if j > 0 && rng.IntN(3) == 0 {
+			price = roundTo(base+base*int64(rng.IntN(20))/100, 1000)
+		}
+		var compareAt *int64
+		if rng.IntN(5) == 0 {
+			c := roundTo(price*int64(110+rng.IntN(40))/100, 1000)
+			compareAt = &c
+		}
+		w := weight
+		variantActive := rng.IntN(20) != 0
+
+		variantName := "Default"
+		if len(combo) > 0 {
+			parts := make([]string, 0, len(d.options))
+			for _, o := range d.options {
+				parts = append(parts, combo[o.name])
+			}
+			variantName = strings.Join(parts, " / ")
+		}
+
+		rows = append(rows, catalog.TransferRow{
+			ProductSlug:        slug,
+			ProductName:        name,
+			ProductDescription: fmt.Sprintf("%s by %s. Generated demo product.", name, strings.Split(name, " ")[0]),
+			ProductActive:      &active,
+			Categories:         categories,
+			Images:             images,
+			SKU:                fmt.Sprintf("SEED-%06d-%02d", i, j+1),
+			VariantName:        variantName,
+			Price:              &price,
+			CompareAtPrice:     compareAt,
+			WeightGrams:        &w,
+			VariantActive:      &variantActive,
+			Options:            combo,
+			Stock:              map[string]int{location: stockLevel(rng)},
+		})
+	}
+	return rows
+}
+
+// combinations picks up to n distinct value combinations of opts; with no
+// options there is a single variant.
+func combinations(rng *rand.Rand, opts []option, n int) []map[string]string {
+	if len(opts) == 0 {
+		return []map[string]string{nil}
+	}
+	total := 1
+	for _, o := range opts {
+		total *= len(o.values)
+	}
+	var out []map[string]string
+	for _, k := range rng.Perm(total)[:min(n, total)] {
+		combo := make(map[string]string, len(opts))
+		for _, o := range opts {
+			combo[o.name] = o.values[k%len(o.values)]
+			k /= len(o.values)
+		}
+		out = append(out, combo)
+	}
+	return out
+}
+
+// stockLevel is mostly healthy stock with some low and sold-out variants.
+func stockLevel(rng *rand.Rand) int {
+	switch r := rng.IntN(20); {
+	case r == 0:
+		return 0
+	case r < 3:
+		return 1 + rng.IntN(5)
+	default:
+		return 20 + rng.IntN(200)
+	}
+}
+
+func pick(rng *rand.Rand, s []string) string {
+	return s[rng.IntN(len(s))]
+}
+
+func roundTo(n, unit int64) int64 {
+	return (n + unit/2) / unit * unit
+}
@@ -1,105 +1,374 @@
+// Command seed loads a deterministic demo dataset through the real
+// repositories: a categorised catalog with variants, images and stock,
+// users with addresses, and a year of orders and payments.
+//
+//	seed [-seed 1] [-products 200] [-max-variants 4] [-users 50] [-orders 300]
+//
+// Catalog rows and users are upserted, so re-running with the same seed is
+// safe; stock is only set for new variants, since existing ones may hold
+// reservations from earlier runs. Every run adds -orders new orders.
 package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"os"
+	"math/rand/v2"
 	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/synchhans/ecommerce-backend/internal/config"
+	"github.com/synchhans/ecommerce-backend/internal/module/address"
+	"github.com/synchhans/ecommerce-backend/internal/module/cart"
+	"github.com/synchhans/ecommerce-backend/internal/module/catalog"
+	"github.com/synchhans/ecommerce-backend/internal/module/order"
+	"github.com/synchhans/ecommerce-backend/internal/module/payment"
+	"github.com/synchhans/ecommerce-backend/internal/module/user"
 	"github.com/synchhans/ecommerce-backend/internal/platform/database"
 )
 
-type Product struct {
-	Name        string
-	Description string
-	Price       int
-	Category    string
-	ImageURL    string
-	Stock       int
+// stream offsets keep the per-item random streams of each stage apart
+const (
+	userStream  = 1 << 62
+	orderStream = 1 << 61
+)
+
+type options struct {
+	seed        uint64
+	products    int
+	maxVariants int
+	users       int
+	orders      int
+	batch       int
+	password    string
+	location    string
 }
 
 func main() {
-	_ = godotenv.Load()
-
-	dsn := os.Getenv("DATABASE_DSN")
-	if dsn == "" {
-		log.Fatal("DATABASE_DSN is required")
+	var o options
+	flag.Uint64Var(&o.seed, "seed", 1, "random seed; the same seed produces the same dataset")
+	flag.IntVar(&o.products, "products", 200, "number of products")
+	flag.IntVar(&o.maxVariants, "max-variants", 4, "maximum variants per product")
+	flag.IntVar(&o.users, "users", 50, "number of customer accounts")
+	flag.IntVar(&o.orders, "orders", 300, "number of historical orders")
+	flag.IntVar(&o.batch, "batch", 500, "products per import transaction")
+	flag.StringVar(&o.password, "password", "password123", "password of every seeded user")
+	flag.StringVar(&o.location, "location", "MAIN", "location code that receives the stock")
+	flag.Parse()
+	if o.products < 0 || o.maxVariants < 1 || o.users < 0 || o.orders < 0 || o.batch < 1 {
+		log.Fatal("invalid flags")
+	}
+	if o.orders > 0 && (o.users == 0 || o.products == 0) {
+		log.Fatal("-orders needs -users and -products")
 	}
 
+	cfg := config.Load()
 	ctx := context.Background()
 
-	db, err := database.New(ctx, database.Config{DSN: dsn})
+	pg, err := database.New(ctx, database.Config{DSN: cfg.DatabaseDSN})
 	if err != nil {
 		log.Fatalf("db init error: %v", err)
 	}
-	defer db.Close()
-
-	products := []Product{
-		{
-			Name:        "iPhone 15 Pro",
-			Description: "Apple smartphone with A17 Pro chip",
-			Price:       19999000,
-			Category:    "electronics",
-			ImageURL:    "https://images.unsplash.com/photo-1695048133142-1a2045d3fda2",
-			Stock:       20,
-		},
-		{
-			Name:        "Mechanical Keyboard RGB",
-			Description: "Hot-swappable mechanical keyboard",
-			Price:       1299000,
-			Category:    "electronics",
-			ImageURL:    "https://images.unsplash.com/photo-1517336714731-489689fd1ca8",
-			Stock:       50,
-		},
-		{
-			Name:        "Men Oversized Hoodie",
-			Description: "Premium cotton hoodie",
-			Price:       349000,
-			Category:    "fashion",
-			ImageURL:    "https://images.unsplash.com/photo-1520975922215-36c8e0c42b97",
-			Stock:       100,
-		},
-		{
-			Name:        "Skincare Serum",
-			Description: "Hydrating facial serum",
-			Price:       189000,
-			Category:    "beauty",
-			ImageURL:    "https://images.unsplash.com/photo-1611930022073-b7a4ba5fcccd",
-			Stock:       75,
-		},
-		{
-			Name:        "Minimalist Desk Lamp",
-			Description: "Warm LED desk lamp",
-			Price:       499000,
-			Category:    "home",
-			ImageURL:    "https://images.unsplash.com/photo-1507473885765-e6ed057f782c",
-			Stock:       40,
-		},
-	}
-
-	for _, p := range products {
-		_, err := db.Pool.Exec(ctx, `
-			INSERT INTO products (
-				name, description, price_cents, category, image_url, stock, created_at
-			)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-			ON CONFLICT (name) DO NOTHING
-		`,
-			p.Name,
-			p.Description,
-			p.Price,
-			p.Category,
-			p.ImageURL,
-			p.Stock,
-			time.Now(),
-		)
+	defer pg.Close()
+
+	start := time.Now()
+	if err := seedCatalog(ctx, pg.Pool, catalog.NewService(catalog.NewPostgresRepository(pg.Pool)), o); err != nil {
+		log.Fatalf("seed catalog: %v", err)
+	}
+	customers, err := seedUsers(ctx, pg.Pool, o)
+	if err != nil {
+		log.Fatalf("seed users: %v", err)
+	}
+	if err := seedOrders(ctx, pg.Pool, cfg, customers, o); err != nil {
+		log.Fatalf("seed orders: %v", err)
+	}
+	log.Printf("seed completed in %s", time.Since(start).Round(time.Millisecond))
+}
+
+func seedCatalog(ctx context.Context, pool *pgxpool.Pool, svc *catalog.Service, o options) error {
+	for from := 0; from < o.products; from += o.batch {
+		to := min(from+o.batch, o.products)
+		var rows []catalog.TransferRow
+		for i := from; i < to; i++ {
+			rows = append(rows, productRows(o.seed, i, o.maxVariants, o.location)...)
+		}
+		if err := keepExistingStock(ctx, pool, rows); err != nil {
+			return err
+		}
+
+		rep, err := svc.ImportRows(ctx, rows, false, "seed")
+		if err != nil {
+			return err
+		}
+		if len(rep.Errors) > 0 {
+			e := rep.Errors[0]
+			return fmt.Errorf("row %d (%s): %s %s, %d errors in total", e.Row, e.SKU, e.Field, e.Message, len(rep.Errors))
+		}
+		log.Printf("products %d/%d (%d variants created, %d updated)", to, o.products, rep.VariantsCreated, rep.VariantsUpdated)
+	}
+	return nil
+}
+
+// keepExistingStock drops the stock column of rows whose variant is already
+// there, so a re-run leaves on-hand and reservations from seeded orders alone.
+func keepExistingStock(ctx context.Context, pool *pgxpool.Pool, rows []catalog.TransferRow) error {
+	skus := make([]string, len(rows))
+	for i, row := range rows {
+		skus[i] = row.SKU
+	}
+	res, err := pool.Query(ctx, `SELECT sku FROM product_variants WHERE sku = ANY($1);`, skus)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	existing := map[string]bool{}
+	for res.Next() {
+		var sku string
+		if err := res.Scan(&sku); err != nil {
+			return err
+		}
+		existing[sku] = true
+	}
+	if err := res.Err(); err != nil {
+		return err
+	}
+	for i := range rows {
+		if existing[rows[i].SKU] {
+			rows[i].Stock = nil
+		}
+	}
+	return nil
+}
+
+type customer struct {
+	user    user.User
+	address address.Address
+}
+
+var (
+	firstNames = []string{"Adi", "Budi", "Citra", "Dewi", "Eka", "Fajar", "Gita", "Hadi", "Indah", "Joko", "Kartika", "Lestari", "Made", "Nanda", "Putri", "Rizky", "Sari", "Teguh", "Wulan", "Yoga"}
+	lastNames  = []string{"Santoso", "Wijaya", "Saputra", "Pratama", "Lestari", "Hidayat", "Nugroho", "Siregar", "Halim", "Kusuma", "Setiawan", "Gunawan"}
+	cities     = []struct{ city, province, postal string }{
+		{"Jakarta Selatan", "DKI Jakarta", "12190"},
+		{"Bandung", "Jawa Barat", "40115"},
+		{"Surabaya", "Jawa Timur", "60271"},
+		{"Yogyakarta", "DI Yogyakarta", "55213"},
+		{"Semarang", "Jawa Tengah", "50134"},
+		{"Medan", "Sumatera Utara", "20112"},
+		{"Denpasar", "Bali", "80231"},
+		{"Makassar", "Sulawesi Selatan", "90111"},
+		{"Balikpapan", "Kalimantan Timur", "76112"},
+		{"Palembang", "Sumatera Selatan", "30126"},
+	}
+	streets = []string{"Jl. Merdeka", "Jl. Sudirman", "Jl. Diponegoro", "Jl. Gajah Mada", "Jl. Pahlawan", "Jl. Melati", "Jl. Kenanga"}
+)
+
+// seedUsers creates (or finds, on a re-run) each customer with a default
+// address. One bcrypt hash is shared so large runs aren't bound by hashing.
+func seedUsers(ctx context.Context, pool *pgxpool.Pool, o options) ([]customer, error) {
+	users := user.NewPostgresRepository(pool)
+	addresses := address.NewService(address.NewPostgresRepository(pool))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(o.password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]customer, 0, o.users)
+	for i := range o.users {
+		rng := rand.New(rand.NewPCG(o.seed, userStream+uint64(i)))
+		name := pick(rng, firstNames) + " " + pick(rng, lastNames)
+		email := fmt.Sprintf("customer%05d@seed.example.com", i+1)
+
+		u, err := users.CreateUser(ctx, email, string(hash), name)
+		if errors.Is(err, user.ErrEmailTaken) {
+			u, _, err = users.GetUserByEmail(ctx, email)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", email, err)
+		}
+
+		existing, err := addresses.List(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		var addr *address.Address
+		for k := range existing {
+			if existing[k].IsDefault {
+				addr = &existing[k]
+			}
+		}
+		if addr == nil {
+			c := cities[rng.IntN(len(cities))]
+			addr, err = addresses.Create(ctx, u.ID, address.Address{
+				Label:         "Home",
+				RecipientName: name,
+				Phone:         fmt.Sprintf("+62812%08d", rng.IntN(100_000_000)),
+				AddressLine1:  fmt.Sprintf("%s No. %d", pick(rng, streets), 1+rng.IntN(200)),
+				City:          c.city,
+				Province:      c.province,
+				PostalCode:    c.postal,
+				Country:       "ID",
+				IsDefault:     true,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%s address: %w", email, err)
+			}
+		}
+		out = append(out, customer{user: *u, address: *addr})
+	}
+	log.Printf("users %d", len(out))
+	return out, nil
+}
+
+// seedOrders checks out carts of seeded variants like a shopper would, then
+// settles the payment. Repositories stamp everything with now(), so orders
+// are backdated afterwards to spread them over the past year.
+func seedOrders(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config, customers []customer, o options) error {
+	if o.orders == 0 {
+		return nil
+	}
+	carts := cart.NewService(cart.NewPostgresRepository(pool))
+	orders := order.NewService(
+		order.NewPostgresRepository(pool),
+		order.ShippingRate{BaseFee: cfg.ShippingBaseFee, PerKg: cfg.ShippingRatePerKg},
+	)
+	payments := payment.NewService(payment.NewPostgresRepository(pool))
+
+	variants, err := sellableVariants(ctx, pool)
+	if err != nil {
+		return err
+	}
+	if len(variants) == 0 {
+		return errors.New("no sellable seeded variants")
+	}
+
+	now := time.Now().UTC()
+	placed := 0
+	for k := range o.orders {
+		rng := rand.New(rand.NewPCG(o.seed, orderStream+uint64(k)))
+		c := customers[rng.IntN(len(customers))]
+
+		cartID, err := carts.CreateCart(ctx, "", "") // guest carts; checkout records the user
+		if err != nil {
+			return err
+		}
+		lines := 0
+		for range 1 + rng.IntN(4) {
+			err := carts.AddOrReplaceItem(ctx, cartID, variants[rng.IntN(len(variants))], 1+rng.IntN(3))
+			if errors.Is(err, cart.ErrInsufficientStock) {
+				continue // sold out by earlier orders
+			}
+			if err != nil {
+				return err
+			}
+			lines++
+		}
+		if lines == 0 {
+			continue
+		}
+
+		a := c.address
+		// the seed created the guest cart itself, so it holds its token
+		orderID, err := orders.Checkout(ctx, cartID, c.user.ID, true, order.AddressSnapshot{
+			RecipientName: a.RecipientName,
+			Phone:         a.Phone,
+			AddressLine1:  a.AddressLine1,
+			City:          a.City,
+			Province:      a.Province,
+			PostalCode:    a.PostalCode,
+			Country:       a.Country,
+		})
+		if errors.Is(err, order.ErrOutOfStock) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
 
+		pay, err := payments.Initiate(ctx, orderID, "manual")
 		if err != nil {
-			log.Fatalf("insert product failed: %v", err)
+			return err
+		}
+		// most orders are paid; the rest failed, expired or are still open
+		status := ""
+		switch r := rng.IntN(20); {
+		case r < 15:
+			status = "paid"
+		case r < 17:
+			status = "failed"
+		case r < 18:
+			status = "expired"
+		}
+		if status != "" {
+			if _, err := payments.Webhook(ctx, pay.Provider, pay.ProviderRef, status, []byte(`{"source":"seed"}`)); err != nil {
+				return err
+			}
+		}
+
+		at := now.Add(-time.Duration(rng.Int64N(int64(365 * 24 * time.Hour))))
+		if err := backdateOrder(ctx, pool, orderID, cartID, at); err != nil {
+			return err
+		}
+		placed++
+		if placed%1000 == 0 {
+			log.Printf("orders %d/%d", placed, o.orders)
 		}
 	}
+	log.Printf("orders %d placed (%d skipped for stock)", placed, o.orders-placed)
+	return nil
+}
 
-	log.Println("✅ product seed completed")
+// sellableVariants lists the seeded variants a shopper can add to a cart.
+func sellableVariants(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
+	rows, err := pool.Query(ctx, `
+SELECT v.id::text
+FROM product_variants v
+JOIN products p ON p.id = v.product_id
+WHERE v.sku LIKE 'SEED-%' AND v.is_active AND p.is_active
+ORDER BY v.sku;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// backdateOrder moves an order, its cart and payments to at.
+func backdateOrder(ctx context.Context, pool *pgxpool.Pool, orderID, cartID string, at time.Time) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `
+UPDATE orders SET created_at = $2, updated_at = $2 WHERE id = $1;
+`, orderID, at); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE carts SET created_at = $2, updated_at = $2 WHERE id = $1;
+`, cartID, at); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE payments SET created_at = $2, updated_at = $2 WHERE order_id = $1;
+`, orderID, at); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,6 +12,23 @@ import (
 type Config struct {
 	DatabaseDSN string
 	JWTSecret   string
+	// storefront origin used for canonical and sitemap URLs
+	SiteURL string
+	// public origin of this API, for links sent outside the storefront
+	APIURL string
+
+	LowStockCheckInterval time.Duration
+	PriceScheduleInterval time.Duration
+	// how often "frequently bought together" pairs are recomputed
+	RecommendationInterval time.Duration
+	// carts with no activity for CartAbandonAfter are marked abandoned
+	CartAbandonCheckInterval time.Duration
+	CartAbandonAfter         time.Duration
+
+	// Shipping: base fee + per started kg, in the base currency (IDR);
+	// converted with fx_rates for carts in other currencies
+	ShippingBaseFee   int64
+	ShippingRatePerKg int64
 }
 
 func Load() *Config {
@@ -23,5 +42,48 @@ func Load() *Config {
 	return &Config{
 		DatabaseDSN: dsn,
 		JWTSecret:   os.Getenv("JWT_SECRET"),
+		SiteURL:     stringEnv("SITE_URL", "http://localhost:3000"),
+		APIURL:      stringEnv("API_URL", "http://localhost:8080"),
+
+		LowStockCheckInterval:  durationEnv("LOW_STOCK_CHECK_INTERVAL", 5*time.Minute),
+		PriceScheduleInterval:  durationEnv("PRICE_SCHEDULE_INTERVAL", time.Minute),
+		RecommendationInterval: durationEnv("RECOMMENDATION_INTERVAL", 6*time.Hour),
+
+		CartAbandonCheckInterval: durationEnv("CART_ABANDON_CHECK_INTERVAL", 15*time.Minute),
+		CartAbandonAfter:         durationEnv("CART_ABANDON_AFTER", 24*time.Hour),
+
+		ShippingBaseFee:   int64Env("SHIPPING_BASE_FEE", 0),
+		ShippingRatePerKg: int64Env("SHIPPING_RATE_PER_KG", 0),
+	}
+}
+
+func stringEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s: invalid duration %q", key, v)
+	}
+	return d
+}
+
+func int64Env(key string, def int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		log.Fatalf("%s: invalid amount %q", key, v)
 	}
+	return n
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
 )
 
 type Handler struct {
@@ -35,7 +36,7 @@ func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+	writeJSON(w, http.StatusOK, pagination.All(items))
 }
 
 func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,146 @@
+package cart
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AbandonmentNotifier receives abandoned carts that belong to a user with an
+// email, e.g. to send a reminder with the recovery link.
+type AbandonmentNotifier interface {
+	NotifyCartAbandoned(ctx context.Context, a AbandonedCart) error
+}
+
+// LogNotifier writes events to the standard logger until we wire an email
+// provider. It leaves out the email and the recovery link: the link restores
+// the cart for a week, so it must not end up in logs.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyCartAbandoned(_ context.Context, a AbandonedCart) error {
+	log.Printf("cart abandoned cart=%s user=%s", a.CartID, a.UserID)
+	return nil
+}
+
+// recoveryTokenTTL is how long the link in an abandonment event works.
+const recoveryTokenTTL = 7 * 24 * time.Hour
+
+// AbandonedCartDetector periodically marks carts with no activity for the
+// abandon window as abandoned and emits an event, with a one-click recovery
+// link, for those whose user has an email.
+type AbandonedCartDetector struct {
+	repo       Repository
+	notifier   AbandonmentNotifier
+	interval   time.Duration
+	after      time.Duration
+	recoverURL string
+	secret     []byte
+	now        func() time.Time
+}
+
+// NewAbandonedCartDetector builds recovery links as recoverURL?token=...,
+// signed with secret; recoverURL should reach Handler's GET /cart/recover.
+func NewAbandonedCartDetector(repo Repository, notifier AbandonmentNotifier, interval, after time.Duration, recoverURL, secret string) *AbandonedCartDetector {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	if after <= 0 {
+		after = 24 * time.Hour
+	}
+	return &AbandonedCartDetector{
+		repo:       repo,
+		notifier:   notifier,
+		interval:   interval,
+		after:      after,
+		recoverURL: recoverURL,
+		secret:     []byte(secret),
+		now:        time.Now,
+	}
+}
+
+// Run checks once immediately and then on every tick until ctx is done.
+func (d *AbandonedCartDetector) Run(ctx context.Context) {
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("abandoned cart check failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce returns the events emitted during this pass.
+func (d *AbandonedCartDetector) RunOnce(ctx context.Context) ([]AbandonedCart, error) {
+	now := d.now()
+	carts, err := d.repo.MarkAbandoned(ctx, now.Add(-d.after))
+	if err != nil {
+		return nil, err
+	}
+
+	var emitted []AbandonedCart
+	for _, a := range carts {
+		if a.Email == "" {
+			continue // guest carts are only counted in the report
+		}
+		token := signRecoveryToken(a.CartID, now.Add(recoveryTokenTTL), d.secret)
+		a.RecoveryURL = d.recoverURL + "?token=" + url.QueryEscape(token)
+		if err := d.notifier.NotifyCartAbandoned(ctx, a); err != nil {
+			log.Printf("notify cart abandoned cart=%s: %v", a.CartID, err)
+		}
+		emitted = append(emitted, a)
+	}
+	return emitted, nil
+}
+
+var errInvalidRecoveryToken = errors.New("invalid or expired recovery token")
+
+// signRecoveryToken returns "<cartID>.<expiry unix>.<signature>". The
+// signature covers a different prefix than cart tokens, so one can't stand
+// in for the other.
+func signRecoveryToken(cartID string, expires time.Time, secret []byte) string {
+	payload := cartID + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + recoverySignature(payload, secret)
+}
+
+// parseRecoveryToken returns the cart the token restores.
+func parseRecoveryToken(token string, secret []byte, now time.Time) (string, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", errInvalidRecoveryToken
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(recoverySignature(payload, secret))) {
+		return "", errInvalidRecoveryToken
+	}
+	cartID, exp, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", errInvalidRecoveryToken
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return "", errInvalidRecoveryToken
+	}
+	return cartID, nil
+}
+
+func recoverySignature(payload string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("cart-recovery:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,70 @@
+package cart
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	events []AbandonedCart
+}
+
+func (n *recordingNotifier) NotifyCartAbandoned(_ context.Context, a AbandonedCart) error {
+	n.events = append(n.events, a)
+	return nil
+}
+
+func TestAbandonedCartDetector_NotifiesUsersWithEmail(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var idleSince time.Time
+	repo := fakeRepo{
+		abandonFn: func(ctx context.Context, since time.Time) ([]AbandonedCart, error) {
+			idleSince = since
+			return []AbandonedCart{
+				{CartID: "c-guest", Currency: "IDR", Items: 1, Value: 50000},
+				{CartID: "c-user", UserID: "u-1", Email: "a@example.com", Currency: "IDR", Items: 2, Value: 120000},
+				{CartID: "c-blocked", UserID: "u-2", Currency: "IDR", Items: 1, Value: 10000}, // no email
+			}, nil
+		},
+	}
+	n := &recordingNotifier{}
+	d := NewAbandonedCartDetector(repo, n, 0, 48*time.Hour, "https://api.example/v1/cart/recover", testSecret)
+	d.now = func() time.Time { return now }
+
+	emitted, err := d.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, now.Add(-48*time.Hour), idleSince)
+	require.Len(t, emitted, 1)
+	require.Equal(t, emitted, n.events)
+
+	link, err := url.Parse(emitted[0].RecoveryURL)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(emitted[0].RecoveryURL, "https://api.example/v1/cart/recover?token="))
+	cartID, err := parseRecoveryToken(link.Query().Get("token"), []byte(testSecret), now)
+	require.NoError(t, err)
+	require.Equal(t, "c-user", cartID)
+}
+
+func TestRecoveryToken(t *testing.T) {
+	now := time.Now()
+	secret := []byte(testSecret)
+	token := signRecoveryToken("c-1", now.Add(time.Hour), secret)
+
+	id, err := parseRecoveryToken(token, secret, now)
+	require.NoError(t, err)
+	require.Equal(t, "c-1", id)
+
+	_, err = parseRecoveryToken(token, secret, now.Add(2*time.Hour))
+	require.ErrorIs(t, err, errInvalidRecoveryToken, "expired")
+	_, err = parseRecoveryToken(token, []byte("other"), now)
+	require.ErrorIs(t, err, errInvalidRecoveryToken, "wrong secret")
+	_, err = parseRecoveryToken(strings.Replace(token, "c-1", "c-2", 1), secret, now)
+	require.ErrorIs(t, err, errInvalidRecoveryToken, "tampered cart")
+	_, err = parseRecoveryToken("", secret, now)
+	require.ErrorIs(t, err, errInvalidRecoveryToken)
+}
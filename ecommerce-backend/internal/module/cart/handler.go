@@ -3,41 +3,209 @@ package cart
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
 
 type Handler struct {
-	svc *Service
+	svc         *Service
+	tokenSecret []byte
+	siteURL     string
+	now         func() time.Time
 }
 
-func NewHandler(svc *Service) *Handler {
-	return &Handler{svc: svc}
+// NewHandler signs guest cart tokens and checks recovery links with
+// tokenSecret; a recovered cart redirects to the storefront at siteURL.
+func NewHandler(svc *Service, tokenSecret, siteURL string) *Handler {
+	return &Handler{
+		svc:         svc,
+		tokenSecret: []byte(tokenSecret),
+		siteURL:     strings.TrimRight(siteURL, "/"),
+		now:         time.Now,
+	}
 }
 
+// Routes should run behind OptionalAuth: a user's cart is only served to
+// them, a guest cart only with the X-Cart-Token returned by POST /cart.
 func (h *Handler) Routes(r chi.Router) {
 	r.Post("/cart", h.createCart)
-	r.Get("/cart/{id}", h.getCart)
+	r.Get("/cart/recover", h.recoverCart)
+	r.Route("/cart/{id}", func(cr chi.Router) {
+		cr.Use(h.requireAccess)
+		cr.Get("/", h.getCart)
+		cr.Put("/currency", h.setCurrency)
 
-	r.Post("/cart/{id}/items", h.upsertItem)
-	r.Patch("/cart/{id}/items/{itemId}", h.updateItemQty)
-	r.Delete("/cart/{id}/items/{itemId}", h.deleteItem)
+		cr.Post("/items", h.upsertItem)
+		cr.Patch("/items/{itemId}", h.updateItemQty)
+		cr.Delete("/items/{itemId}", h.deleteItem)
+	})
 }
 
-func (h *Handler) createCart(w http.ResponseWriter, r *http.Request) {
-	id, err := h.svc.CreateCart(r.Context())
+// requireAccess answers 404 for an unknown cart and 403 when the caller is
+// neither its owner nor holds its token.
+func (h *Handler) requireAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cartID := chi.URLParam(r, "id")
+		userID, _ := httpx.UserIDFromContext(r.Context())
+		guest := httpx.VerifyCartToken(r.Header.Get(httpx.CartTokenHeader), cartID, h.tokenSecret)
+
+		if err := h.svc.Authorize(r.Context(), cartID, userID, guest); err != nil {
+			switch {
+			case errors.Is(err, ErrNotFound):
+				httpx.Fail(w, http.StatusNotFound, "not_found")
+			case errors.Is(err, ErrForbidden):
+				httpx.Fail(w, http.StatusForbidden, "forbidden")
+			default:
+				httpx.Fail(w, http.StatusInternalServerError, "internal_error")
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UserRoutes must be mounted behind AuthMiddleware.
+func (h *Handler) UserRoutes(r chi.Router) {
+	r.Get("/me/cart", h.myCart)
+}
+
+// AdminRoutes must be mounted behind AuthMiddleware + RequireRole("admin").
+func (h *Handler) AdminRoutes(r chi.Router) {
+	r.Get("/admin/carts/abandonment", h.abandonmentReport)
+}
+
+// recoverCart is the one-click link from an abandonment event. It restores
+// the cart and sends the shopper to the storefront cart page; a link that is
+// expired or whose cart was already checked out lands there too, flagged.
+func (h *Handler) recoverCart(w http.ResponseWriter, r *http.Request) {
+	target := h.siteURL + "/cart"
+
+	cartID, err := parseRecoveryToken(r.URL.Query().Get("token"), h.tokenSecret, h.now())
+	if err == nil {
+		_, err = h.svc.RecoverCart(r.Context(), cartID)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, errInvalidRecoveryToken), errors.Is(err, ErrNotFound):
+		target += "?recovery=failed"
+	default:
+		httpx.Fail(w, http.StatusInternalServerError, "internal_error")
+		return
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// abandonmentReport takes ?from and ?to as RFC3339 and defaults to the last
+// 30 days.
+func (h *Handler) abandonmentReport(w http.ResponseWriter, r *http.Request) {
+	to := h.now()
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			httpx.Fail(w, http.StatusBadRequest, "invalid_to")
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			httpx.Fail(w, http.StatusBadRequest, "invalid_from")
+			return
+		}
+		from = t
+	}
+
+	rep, err := h.svc.AbandonmentReport(r.Context(), from, to)
 	if err != nil {
+		if errors.Is(err, ErrInvalidRange) {
+			httpx.Fail(w, http.StatusBadRequest, "invalid_range")
+			return
+		}
 		httpx.Fail(w, http.StatusInternalServerError, "internal_error")
 		return
 	}
-	httpx.Created(w, httpx.Envelope{"id": id})
+	httpx.OK(w, rep)
+}
+
+type currencyReq struct {
+	Currency string `json:"currency"`
+}
+
+func (h *Handler) createCart(w http.ResponseWriter, r *http.Request) {
+	// body is optional; without it the cart uses the base currency
+	var req currencyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httpx.Fail(w, http.StatusBadRequest, "invalid_json")
+		return
+	}
+	// signed in: the user's active cart, created on first use
+	userID, _ := httpx.UserIDFromContext(r.Context())
+	id, err := h.svc.CreateCart(r.Context(), req.Currency, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCurrency):
+			httpx.Fail(w, http.StatusBadRequest, "invalid_currency")
+		case errors.Is(err, ErrPriceUnavailable):
+			// the user's cart has items that can't be sold in that currency
+			httpx.Fail(w, http.StatusConflict, "price_unavailable")
+		default:
+			httpx.Fail(w, http.StatusInternalServerError, "internal_error")
+		}
+		return
+	}
+	httpx.Created(w, httpx.Envelope{"id": id, "token": httpx.SignCartToken(id, h.tokenSecret)})
+}
+
+func (h *Handler) setCurrency(w http.ResponseWriter, r *http.Request) {
+	var req currencyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.Fail(w, http.StatusBadRequest, "invalid_json")
+		return
+	}
+	if err := h.svc.SetCurrency(r.Context(), chi.URLParam(r, "id"), req.Currency); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCurrency):
+			httpx.Fail(w, http.StatusBadRequest, "invalid_currency")
+		case errors.Is(err, ErrNotFound):
+			httpx.Fail(w, http.StatusNotFound, "not_found")
+		case errors.Is(err, ErrPriceUnavailable):
+			httpx.Fail(w, http.StatusConflict, "price_unavailable")
+		default:
+			httpx.Fail(w, http.StatusInternalServerError, "internal_error")
+		}
+		return
+	}
+	httpx.OK(w, httpx.Envelope{"ok": true})
 }
 
 func (h *Handler) getCart(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	c, err := h.svc.GetCart(r.Context(), id)
+	c, err := h.svc.GetCart(r.Context(), id, httpx.Includes(r, "availability"))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			httpx.Fail(w, http.StatusNotFound, "not_found")
+			return
+		}
+		httpx.Fail(w, http.StatusInternalServerError, "internal_error")
+		return
+	}
+	httpx.OK(w, c)
+}
+
+func (h *Handler) myCart(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.Fail(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	c, err := h.svc.MyCart(r.Context(), userID, httpx.Includes(r, "availability"))
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			httpx.Fail(w, http.StatusNotFound, "not_found")
@@ -68,11 +236,20 @@ func (h *Handler) upsertItem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.svc.AddOrReplaceItem(r.Context(), cartID, req.VariantID, req.Qty); err != nil {
-		if errors.Is(err, ErrInvalidQty) {
+		switch {
+		case errors.Is(err, ErrInvalidQty):
 			httpx.Fail(w, http.StatusBadRequest, "invalid_qty")
-			return
+		case errors.Is(err, ErrNotFound):
+			httpx.Fail(w, http.StatusNotFound, "variant_not_found")
+		case errors.Is(err, ErrInsufficientStock):
+			httpx.Fail(w, http.StatusConflict, "insufficient_stock")
+		case errors.Is(err, ErrPriceUnavailable):
+			httpx.Fail(w, http.StatusConflict, "price_unavailable")
+		case errors.Is(err, ErrCartClosed):
+			httpx.Fail(w, http.StatusConflict, "cart_closed")
+		default:
+			httpx.Fail(w, http.StatusInternalServerError, "internal_error")
 		}
-		httpx.Fail(w, http.StatusInternalServerError, "internal_error")
 		return
 	}
 
@@ -106,6 +283,14 @@ func (h *Handler) updateItemQty(w http.ResponseWriter, r *http.Request) {
 			httpx.Fail(w, http.StatusBadRequest, "invalid_qty")
 			return
 		}
+		if errors.Is(err, ErrInsufficientStock) {
+			httpx.Fail(w, http.StatusConflict, "insufficient_stock")
+			return
+		}
+		if errors.Is(err, ErrCartClosed) {
+			httpx.Fail(w, http.StatusConflict, "cart_closed")
+			return
+		}
 		httpx.Fail(w, http.StatusInternalServerError, "internal_error")
 		return
 	}
@@ -122,6 +307,10 @@ func (h *Handler) deleteItem(w http.ResponseWriter, r *http.Request) {
 			httpx.Fail(w, http.StatusNotFound, "not_found")
 			return
 		}
+		if errors.Is(err, ErrCartClosed) {
+			httpx.Fail(w, http.StatusConflict, "cart_closed")
+			return
+		}
 		httpx.Fail(w, http.StatusInternalServerError, "internal_error")
 		return
 	}
@@ -4,23 +4,90 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
 
 type fakeRepo struct {
-	createFn func(ctx context.Context) (string, error)
+	createFn func(ctx context.Context, currency, userID string) (string, error)
 	getFn    func(ctx context.Context, cartID string) (*Cart, error)
 	upsertFn func(ctx context.Context, cartID, variantID string, qty int) error
 	updateFn func(ctx context.Context, cartID, itemID string, qty int) error
 	deleteFn func(ctx context.Context, cartID, itemID string) error
+	availFn  func(ctx context.Context, variantIDs []string) (map[string]int, error)
+	stockFn  func(ctx context.Context, variantID string) (*VariantStock, error)
+
+	setCurrencyFn func(ctx context.Context, cartID, currency string) error
+	unpricedFn    func(ctx context.Context, cartID string, variantIDs []string) ([]string, error)
+
+	activeFn func(ctx context.Context, userID string) (string, error)
+	reviveFn func(ctx context.Context, userID string) (string, error)
+	claimFn  func(ctx context.Context, cartID, userID string) error
+	mergeFn  func(ctx context.Context, guestCartID, userCartID string, qty map[string]int) error
+	ownerFn  func(ctx context.Context, cartID string) (string, string, error)
+
+	abandonFn    func(ctx context.Context, idleSince time.Time) ([]AbandonedCart, error)
+	reactivateFn func(ctx context.Context, cartID string) error
+	recoverFn    func(ctx context.Context, cartID string) (string, error)
+	reportFn     func(ctx context.Context, from, to time.Time) ([]AbandonmentStats, error)
 }
 
-func (f fakeRepo) CreateCart(ctx context.Context) (string, error) { return f.createFn(ctx) }
+const (
+	testSecret  = "secret"
+	testSiteURL = "https://shop.example"
+)
+
+// guestRequest carries the cart token of the cart in target (/cart/{id}...).
+func guestRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	cartID := strings.Split(strings.SplitN(target, "?", 2)[0], "/")[2]
+	req.Header.Set(httpx.CartTokenHeader, httpx.SignCartToken(cartID, []byte(testSecret)))
+	return req
+}
+
+func (f fakeRepo) CreateCart(ctx context.Context, currency, userID string) (string, error) {
+	return f.createFn(ctx, currency, userID)
+}
+func (f fakeRepo) CartOwner(ctx context.Context, cartID string) (string, string, error) {
+	if f.ownerFn == nil {
+		return "", "active", nil // a guest cart
+	}
+	return f.ownerFn(ctx, cartID)
+}
+func (f fakeRepo) ActiveCartID(ctx context.Context, userID string) (string, error) {
+	return f.activeFn(ctx, userID)
+}
+func (f fakeRepo) ReactivateUserCart(ctx context.Context, userID string) (string, error) {
+	if f.reviveFn == nil {
+		return "", ErrNotFound // nothing abandoned
+	}
+	return f.reviveFn(ctx, userID)
+}
+func (f fakeRepo) ClaimCart(ctx context.Context, cartID, userID string) error {
+	return f.claimFn(ctx, cartID, userID)
+}
+func (f fakeRepo) MergeCart(ctx context.Context, guestCartID, userCartID string, qty map[string]int) error {
+	return f.mergeFn(ctx, guestCartID, userCartID, qty)
+}
+func (f fakeRepo) SetCurrency(ctx context.Context, cartID, currency string) error {
+	return f.setCurrencyFn(ctx, cartID, currency)
+}
+func (f fakeRepo) UnpricedVariants(ctx context.Context, cartID string, variantIDs []string) ([]string, error) {
+	if f.unpricedFn == nil {
+		return nil, nil
+	}
+	return f.unpricedFn(ctx, cartID, variantIDs)
+}
 func (f fakeRepo) GetCart(ctx context.Context, cartID string) (*Cart, error) {
 	return f.getFn(ctx, cartID)
 }
@@ -33,17 +100,35 @@ func (f fakeRepo) UpdateItemQty(ctx context.Context, cartID, itemID string, qty
 func (f fakeRepo) DeleteItem(ctx context.Context, cartID, itemID string) error {
 	return f.deleteFn(ctx, cartID, itemID)
 }
+func (f fakeRepo) AvailableQty(ctx context.Context, variantIDs []string) (map[string]int, error) {
+	return f.availFn(ctx, variantIDs)
+}
+func (f fakeRepo) GetVariantStock(ctx context.Context, variantID string) (*VariantStock, error) {
+	return f.stockFn(ctx, variantID)
+}
+func (f fakeRepo) MarkAbandoned(ctx context.Context, idleSince time.Time) ([]AbandonedCart, error) {
+	return f.abandonFn(ctx, idleSince)
+}
+func (f fakeRepo) ReactivateCart(ctx context.Context, cartID string) error {
+	return f.reactivateFn(ctx, cartID)
+}
+func (f fakeRepo) RecoverCart(ctx context.Context, cartID string) (string, error) {
+	return f.recoverFn(ctx, cartID)
+}
+func (f fakeRepo) AbandonmentReport(ctx context.Context, from, to time.Time) ([]AbandonmentStats, error) {
+	return f.reportFn(ctx, from, to)
+}
 
 func TestCart_CreateCart_201(t *testing.T) {
 	repo := fakeRepo{
-		createFn: func(ctx context.Context) (string, error) { return "cart-1", nil },
+		createFn: func(ctx context.Context, currency, userID string) (string, error) { return "cart-1", nil },
 		getFn:    func(ctx context.Context, cartID string) (*Cart, error) { return nil, nil },
 		upsertFn: func(ctx context.Context, cartID, variantID string, qty int) error { return nil },
 		updateFn: func(ctx context.Context, cartID, itemID string, qty int) error { return nil },
 		deleteFn: func(ctx context.Context, cartID, itemID string) error { return nil },
 	}
 	svc := NewService(repo)
-	h := NewHandler(svc)
+	h := NewHandler(svc, testSecret, testSiteURL)
 
 	r := chi.NewRouter()
 	h.Routes(r)
@@ -57,11 +142,12 @@ func TestCart_CreateCart_201(t *testing.T) {
 	var body map[string]any
 	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
 	require.Equal(t, "cart-1", body["id"])
+	require.True(t, httpx.VerifyCartToken(body["token"].(string), "cart-1", []byte(testSecret)))
 }
 
 func TestCart_GetCart_404(t *testing.T) {
 	repo := fakeRepo{
-		createFn: func(ctx context.Context) (string, error) { return "", nil },
+		createFn: func(ctx context.Context, currency, userID string) (string, error) { return "", nil },
 		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
 			return nil, ErrNotFound
 		},
@@ -70,12 +156,12 @@ func TestCart_GetCart_404(t *testing.T) {
 		deleteFn: func(ctx context.Context, cartID, itemID string) error { return nil },
 	}
 	svc := NewService(repo)
-	h := NewHandler(svc)
+	h := NewHandler(svc, testSecret, testSiteURL)
 
 	r := chi.NewRouter()
 	h.Routes(r)
 
-	req := httptest.NewRequest(http.MethodGet, "/cart/x", nil)
+	req := guestRequest(http.MethodGet, "/cart/x", nil)
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, req)
 
@@ -84,22 +170,712 @@ func TestCart_GetCart_404(t *testing.T) {
 
 func TestCart_UpsertItem_400_InvalidPayload(t *testing.T) {
 	repo := fakeRepo{
-		createFn: func(ctx context.Context) (string, error) { return "", nil },
+		createFn: func(ctx context.Context, currency, userID string) (string, error) { return "", nil },
 		getFn:    func(ctx context.Context, cartID string) (*Cart, error) { return nil, nil },
 		upsertFn: func(ctx context.Context, cartID, variantID string, qty int) error { return nil },
 		updateFn: func(ctx context.Context, cartID, itemID string, qty int) error { return nil },
 		deleteFn: func(ctx context.Context, cartID, itemID string) error { return nil },
 	}
 	svc := NewService(repo)
-	h := NewHandler(svc)
+	h := NewHandler(svc, testSecret, testSiteURL)
 
 	r := chi.NewRouter()
 	h.Routes(r)
 
 	reqBody := []byte(`{"variant_id":"","qty":0}`)
-	req := httptest.NewRequest(http.MethodPost, "/cart/1/items", bytes.NewReader(reqBody))
+	req := guestRequest(http.MethodPost, "/cart/1/items", bytes.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCart_GetCart_IncludeAvailability(t *testing.T) {
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, Status: "active", Items: []CartItem{
+				{ID: "i-1", VariantID: "v-1", Qty: 2},
+				{ID: "i-2", VariantID: "v-2", Qty: 5},
+			}}, nil
+		},
+		availFn: func(ctx context.Context, variantIDs []string) (map[string]int, error) {
+			require.Equal(t, []string{"v-1", "v-2"}, variantIDs)
+			return map[string]int{"v-1": 3, "v-2": 1}, nil
+		},
+	}
+	svc := NewService(repo)
+	h := NewHandler(svc, testSecret, testSiteURL)
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := guestRequest(http.MethodGet, "/cart/c-1?include=availability", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out Cart
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Len(t, out.Items, 2)
+	require.True(t, out.Items[0].Availability.InStock)
+	require.False(t, out.Items[1].Availability.InStock)
+	require.Equal(t, 1, out.Items[1].Availability.Available)
+}
+
+func TestCart_UpsertItem_409_InsufficientStock(t *testing.T) {
+	repo := fakeRepo{
+		stockFn: func(ctx context.Context, variantID string) (*VariantStock, error) {
+			return &VariantStock{VariantID: variantID, Active: true, Policy: "deny", Available: 2}, nil
+		},
+		upsertFn: func(ctx context.Context, cartID, variantID string, qty int) error {
+			t.Fatal("upsert must not be called")
+			return nil
+		},
+	}
+	svc := NewService(repo)
+	h := NewHandler(svc, testSecret, testSiteURL)
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := guestRequest(http.MethodPost, "/cart/c-1/items", bytes.NewReader([]byte(`{"variant_id":"v-1","qty":3}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestCart_UpsertItem_Backorder_OK(t *testing.T) {
+	limit := 5
+	repo := fakeRepo{
+		stockFn: func(ctx context.Context, variantID string) (*VariantStock, error) {
+			return &VariantStock{VariantID: variantID, Active: true, Policy: "backorder", Available: 2, BackorderLimit: &limit, Backordered: 1}, nil
+		},
+		upsertFn: func(ctx context.Context, cartID, variantID string, qty int) error {
+			require.Equal(t, 6, qty)
+			return nil
+		},
+	}
+	svc := NewService(repo)
+	h := NewHandler(svc, testSecret, testSiteURL)
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	// 2 in stock + (5 limit - 1 outstanding) = 6 sellable
+	req := guestRequest(http.MethodPost, "/cart/c-1/items", bytes.NewReader([]byte(`{"variant_id":"v-1","qty":6}`)))
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, req)
 
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCart_CreateCart_Currency(t *testing.T) {
+	var got string
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, currency, userID string) (string, error) {
+			got = currency
+			return "cart-1", nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/cart", bytes.NewReader([]byte(`{"currency":"usd"}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "USD", got)
+
+	req = httptest.NewRequest(http.MethodPost, "/cart", bytes.NewReader([]byte(`{"currency":"dollars"}`)))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
 	require.Equal(t, http.StatusBadRequest, rec.Code)
 }
+
+func TestCart_CreateCart_SignedIn_SwitchesCurrency(t *testing.T) {
+	secret := []byte(testSecret)
+	token, err := httpx.SignJWTWithRole("u-1", "customer", secret, time.Hour)
+	require.NoError(t, err)
+
+	// u-1 already has an IDR cart; POST /cart hands it back
+	currency := "IDR"
+	var setErr error
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, _, userID string) (string, error) {
+			require.Equal(t, "u-1", userID)
+			return "user-cart", nil
+		},
+		setCurrencyFn: func(ctx context.Context, cartID, c string) error {
+			require.Equal(t, "user-cart", cartID)
+			if setErr != nil {
+				return setErr
+			}
+			currency = c
+			return nil
+		},
+	}
+	r := chi.NewRouter()
+	r.Use(httpx.OptionalAuth(secret))
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/cart", bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"currency":"usd"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Contains(t, rec.Body.String(), "user-cart")
+	require.Equal(t, "USD", currency)
+
+	// no currency asked for: the cart keeps its own
+	rec = post(``)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "USD", currency)
+
+	setErr = ErrPriceUnavailable
+	rec = post(`{"currency":"sgd"}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "price_unavailable")
+	require.Equal(t, "USD", currency)
+}
+
+func TestCart_UpsertItem_409_PriceUnavailable(t *testing.T) {
+	repo := fakeRepo{
+		stockFn: func(ctx context.Context, variantID string) (*VariantStock, error) {
+			return &VariantStock{VariantID: variantID, Active: true, Policy: "deny", Available: 10}, nil
+		},
+		unpricedFn: func(ctx context.Context, cartID string, variantIDs []string) ([]string, error) {
+			return variantIDs, nil
+		},
+		upsertFn: func(ctx context.Context, cartID, variantID string, qty int) error {
+			t.Fatal("upsert must not be called")
+			return nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	req := guestRequest(http.MethodPost, "/cart/c-1/items", bytes.NewReader([]byte(`{"variant_id":"v-1","qty":1}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "price_unavailable")
+}
+
+func TestCart_SetCurrency(t *testing.T) {
+	repo := fakeRepo{
+		setCurrencyFn: func(ctx context.Context, cartID, currency string) error {
+			if currency == "JPY" {
+				return ErrPriceUnavailable
+			}
+			return nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	req := guestRequest(http.MethodPut, "/cart/c-1/currency", bytes.NewReader([]byte(`{"currency":"SGD"}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req = guestRequest(http.MethodPut, "/cart/c-1/currency", bytes.NewReader([]byte(`{"currency":"jpy"}`)))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestCart_CreateCart_SignedInUser(t *testing.T) {
+	secret := []byte(testSecret)
+	token, err := httpx.SignJWTWithRole("u-1", "customer", secret, time.Hour)
+	require.NoError(t, err)
+
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, currency, userID string) (string, error) {
+			require.Equal(t, "u-1", userID)
+			return "cart-u1", nil
+		},
+	}
+	r := chi.NewRouter()
+	r.Use(httpx.OptionalAuth(secret))
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/cart", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Contains(t, rec.Body.String(), "cart-u1")
+}
+
+func TestCart_MyCart(t *testing.T) {
+	secret := []byte(testSecret)
+	token, err := httpx.SignJWTWithRole("u-1", "customer", secret, time.Hour)
+	require.NoError(t, err)
+
+	active := ""
+	repo := fakeRepo{
+		activeFn: func(ctx context.Context, userID string) (string, error) {
+			require.Equal(t, "u-1", userID)
+			if active == "" {
+				return "", ErrNotFound
+			}
+			return active, nil
+		},
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, UserID: "u-1", Status: "active", Items: []CartItem{{ID: "i-1", VariantID: "v-1", Qty: 2}}}, nil
+		},
+	}
+	r := chi.NewRouter()
+	r.Use(httpx.AuthMiddleware(secret))
+	NewHandler(NewService(repo), testSecret, testSiteURL).UserRoutes(r)
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me/cart", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusNotFound, do().Code)
+
+	active = "cart-u1"
+	rec := do()
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"id":"cart-u1"`)
+	require.NotContains(t, rec.Body.String(), "u-1")
+}
+
+func TestCart_MergeGuestCart_SumsAndCapsAtStock(t *testing.T) {
+	limit := 2
+	stock := map[string]*VariantStock{
+		"v-1": {VariantID: "v-1", Active: true, Policy: "deny", Available: 10},
+		"v-2": {VariantID: "v-2", Active: true, Policy: "deny", Available: 3},
+		"v-3": {VariantID: "v-3", Active: true, Policy: "backorder", Available: 0, BackorderLimit: &limit},
+		"v-4": {VariantID: "v-4", Active: false, Policy: "deny", Available: 10},
+		"v-5": {VariantID: "v-5", Active: true, Policy: "deny", Available: 10},
+	}
+	var merged map[string]int
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			if cartID == "guest" {
+				return &Cart{ID: "guest", Status: "active", Items: []CartItem{
+					{VariantID: "v-1", Qty: 2},
+					{VariantID: "v-2", Qty: 2},
+					{VariantID: "v-3", Qty: 5},
+					{VariantID: "v-4", Qty: 1},
+					{VariantID: "v-5", Qty: 1},
+				}}, nil
+			}
+			return &Cart{ID: "mine", UserID: "u-1", Status: "active", Items: []CartItem{
+				{VariantID: "v-1", Qty: 1},
+				{VariantID: "v-2", Qty: 2},
+			}}, nil
+		},
+		activeFn: func(ctx context.Context, userID string) (string, error) { return "mine", nil },
+		unpricedFn: func(ctx context.Context, cartID string, variantIDs []string) ([]string, error) {
+			require.Equal(t, "mine", cartID)
+			return []string{"v-5"}, nil
+		},
+		stockFn: func(ctx context.Context, variantID string) (*VariantStock, error) {
+			return stock[variantID], nil
+		},
+		mergeFn: func(ctx context.Context, guestCartID, userCartID string, qty map[string]int) error {
+			require.Equal(t, "guest", guestCartID)
+			require.Equal(t, "mine", userCartID)
+			merged = qty
+			return nil
+		},
+	}
+
+	id, err := NewService(repo).MergeGuestCart(context.Background(), "guest", "u-1")
+	require.NoError(t, err)
+	require.Equal(t, "mine", id)
+	// v-2 capped at stock, v-3 at the backorder limit; v-4 inactive, v-5 unpriced
+	require.Equal(t, map[string]int{"v-1": 3, "v-2": 3, "v-3": 2}, merged)
+}
+
+func TestCart_MergedCart_RejectsGuestWrites(t *testing.T) {
+	status := map[string]string{"guest": "active", "mine": "active"}
+	write := func(cartID string) error {
+		if status[cartID] != "active" {
+			return ErrCartClosed
+		}
+		return nil
+	}
+	repo := fakeRepo{
+		ownerFn: func(ctx context.Context, cartID string) (string, string, error) {
+			return "", status[cartID], nil
+		},
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, Status: status[cartID], Items: []CartItem{{ID: "i-1", VariantID: "v-1", Qty: 1}}}, nil
+		},
+		activeFn: func(ctx context.Context, userID string) (string, error) { return "mine", nil },
+		stockFn: func(ctx context.Context, variantID string) (*VariantStock, error) {
+			return &VariantStock{VariantID: variantID, Active: true, Policy: "deny", Available: 10}, nil
+		},
+		mergeFn: func(ctx context.Context, guestCartID, userCartID string, qty map[string]int) error {
+			status[guestCartID] = "merged"
+			return nil
+		},
+		upsertFn: func(ctx context.Context, cartID, variantID string, qty int) error { return write(cartID) },
+		updateFn: func(ctx context.Context, cartID, itemID string, qty int) error { return write(cartID) },
+		deleteFn: func(ctx context.Context, cartID, itemID string) error { return write(cartID) },
+	}
+	svc := NewService(repo)
+	r := chi.NewRouter()
+	NewHandler(svc, testSecret, testSiteURL).Routes(r)
+
+	_, err := svc.MergeGuestCart(context.Background(), "guest", "u-1")
+	require.NoError(t, err)
+
+	// the guest still holds a valid token for the merged cart
+	for _, req := range []*http.Request{
+		guestRequest(http.MethodPost, "/cart/guest/items", strings.NewReader(`{"variant_id":"v-1","qty":2}`)),
+		guestRequest(http.MethodPatch, "/cart/guest/items/i-1", strings.NewReader(`{"qty":2}`)),
+		guestRequest(http.MethodDelete, "/cart/guest/items/i-1", nil),
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusConflict, rec.Code, req.Method)
+		require.Contains(t, rec.Body.String(), "cart_closed")
+	}
+}
+
+func TestCart_MergeGuestCart_ClaimsOrIgnores(t *testing.T) {
+	guest := &Cart{ID: "guest", Status: "active"}
+	claimed := ""
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			if cartID != guest.ID {
+				return nil, ErrNotFound
+			}
+			return guest, nil
+		},
+		activeFn: func(ctx context.Context, userID string) (string, error) { return "", ErrNotFound },
+		claimFn: func(ctx context.Context, cartID, userID string) error {
+			claimed = cartID
+			return nil
+		},
+	}
+	svc := NewService(repo)
+
+	// no cart yet: the guest cart becomes theirs
+	id, err := svc.MergeGuestCart(context.Background(), "guest", "u-1")
+	require.NoError(t, err)
+	require.Equal(t, "guest", id)
+	require.Equal(t, "guest", claimed)
+
+	// stale or someone else's cart: nothing to do
+	claimed = ""
+	guest.UserID = "u-2"
+	id, err = svc.MergeGuestCart(context.Background(), "guest", "u-1")
+	require.NoError(t, err)
+	require.Empty(t, id)
+	id, err = svc.MergeGuestCart(context.Background(), "gone", "u-1")
+	require.NoError(t, err)
+	require.Empty(t, id)
+	require.Empty(t, claimed)
+}
+
+func TestCart_Access(t *testing.T) {
+	secret := []byte(testSecret)
+	owners := map[string]string{"guest-cart": "", "user-cart": "u-1"}
+	repo := fakeRepo{
+		ownerFn: func(ctx context.Context, cartID string) (string, string, error) {
+			owner, ok := owners[cartID]
+			if !ok {
+				return "", "", ErrNotFound
+			}
+			return owner, "active", nil
+		},
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, Status: "active"}, nil
+		},
+	}
+	r := chi.NewRouter()
+	r.Use(httpx.OptionalAuth(secret))
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	u1, err := httpx.SignJWTWithRole("u-1", "customer", secret, time.Hour)
+	require.NoError(t, err)
+	u2, err := httpx.SignJWTWithRole("u-2", "customer", secret, time.Hour)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name, cart, token, cartToken string
+		want                         int
+	}{
+		{"unknown cart", "nope", "", httpx.SignCartToken("nope", secret), http.StatusNotFound},
+		{"guest with token", "guest-cart", "", httpx.SignCartToken("guest-cart", secret), http.StatusOK},
+		{"guest without token", "guest-cart", "", "", http.StatusForbidden},
+		{"token of another cart", "guest-cart", "", httpx.SignCartToken("user-cart", secret), http.StatusForbidden},
+		{"forged token", "guest-cart", "", httpx.SignCartToken("guest-cart", []byte("other")), http.StatusForbidden},
+		{"owner", "user-cart", u1, "", http.StatusOK},
+		{"other user", "user-cart", u2, "", http.StatusForbidden},
+		{"user cart with cart token only", "user-cart", "", httpx.SignCartToken("user-cart", secret), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/cart/"+tc.cart, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			if tc.cartToken != "" {
+				req.Header.Set(httpx.CartTokenHeader, tc.cartToken)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			require.Equal(t, tc.want, rec.Code)
+		})
+	}
+}
+
+func TestCart_GetCart_PricedWithWarnings(t *testing.T) {
+	price := func(n int64) *int64 { return &n }
+	ok := &VariantStock{Active: true, Policy: "deny", Available: 10}
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, Status: "active", Currency: "IDR", Items: []CartItem{
+				{ID: "i-1", VariantID: "v-1", Qty: 2, UnitPrice: price(50000), PriceAtAdd: price(50000), Stock: ok},
+				{ID: "i-2", VariantID: "v-2", Qty: 1, UnitPrice: price(90000), PriceAtAdd: price(100000), Stock: ok},
+				{ID: "i-3", VariantID: "v-3", Qty: 5, UnitPrice: price(10000), Stock: &VariantStock{Active: true, Policy: "deny", Available: 3}},
+				{ID: "i-4", VariantID: "v-4", Qty: 1, UnitPrice: price(20000), Stock: &VariantStock{Active: false}},
+				{ID: "i-5", VariantID: "v-5", Qty: 1, Stock: ok},
+			}}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, guestRequest(http.MethodGet, "/cart/c-1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out Cart
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, int64(100000+90000+50000), out.Subtotal)
+
+	lines := map[string]CartItem{}
+	for _, it := range out.Items {
+		lines[it.ID] = it
+	}
+	require.Empty(t, lines["i-1"].Warnings)
+	require.Equal(t, int64(100000), lines["i-1"].LineTotal)
+	require.Equal(t, []string{WarnPriceChanged}, lines["i-2"].Warnings)
+	require.Equal(t, []string{WarnInsufficientStock}, lines["i-3"].Warnings)
+	require.Equal(t, int64(50000), lines["i-3"].LineTotal)
+	require.Equal(t, []string{WarnInactive}, lines["i-4"].Warnings)
+	require.Zero(t, lines["i-4"].LineTotal)
+	require.Equal(t, []string{WarnPriceUnavailable}, lines["i-5"].Warnings)
+}
+
+func TestCart_RecoverLink(t *testing.T) {
+	secret := []byte(testSecret)
+	var recovered []string
+	repo := fakeRepo{
+		recoverFn: func(ctx context.Context, cartID string) (string, error) {
+			if cartID == "converted" {
+				return "", ErrNotFound
+			}
+			recovered = append(recovered, cartID)
+			return cartID, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), testSecret, testSiteURL+"/").Routes(r)
+
+	cases := []struct {
+		name, token, want string
+	}{
+		{"valid", signRecoveryToken("c-1", time.Now().Add(time.Hour), secret), testSiteURL + "/cart"},
+		{"expired", signRecoveryToken("c-1", time.Now().Add(-time.Minute), secret), testSiteURL + "/cart?recovery=failed"},
+		{"forged", signRecoveryToken("c-1", time.Now().Add(time.Hour), []byte("other")), testSiteURL + "/cart?recovery=failed"},
+		{"already checked out", signRecoveryToken("converted", time.Now().Add(time.Hour), secret), testSiteURL + "/cart?recovery=failed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/cart/recover?token="+url.QueryEscape(tc.token), nil)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusFound, rec.Code)
+			require.Equal(t, tc.want, rec.Header().Get("Location"))
+		})
+	}
+	require.Equal(t, []string{"c-1"}, recovered)
+}
+
+func TestCart_Access_ReactivatesAbandoned(t *testing.T) {
+	var reactivated []string
+	repo := fakeRepo{
+		ownerFn: func(ctx context.Context, cartID string) (string, string, error) {
+			return "", "abandoned", nil
+		},
+		reactivateFn: func(ctx context.Context, cartID string) error {
+			reactivated = append(reactivated, cartID)
+			return nil
+		},
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, Status: "active"}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), testSecret, testSiteURL).Routes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cart/c-1", nil))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Empty(t, reactivated, "no token, no revival")
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, guestRequest(http.MethodGet, "/cart/c-1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, []string{"c-1"}, reactivated)
+}
+
+func TestCart_AbandonmentReport(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	repo := fakeRepo{
+		reportFn: func(ctx context.Context, from, to time.Time) ([]AbandonmentStats, error) {
+			gotFrom, gotTo = from, to
+			return []AbandonmentStats{
+				{Currency: "IDR", Carts: 20, Converted: 8, Abandoned: 10, AbandonedValue: 1500000, Recovered: 4, RecoveredValue: 500000, ConvertedAfterAbandonment: 3},
+				{Currency: "USD", Carts: 1},
+			}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), testSecret, testSiteURL).AdminRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/carts/abandonment?from=2026-09-01T00:00:00Z&to=2026-10-01T00:00:00Z", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), gotFrom)
+	require.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), gotTo)
+
+	var body AbandonmentReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Stats, 2)
+	require.InDelta(t, 0.5, body.Stats[0].AbandonmentRate, 1e-9)
+	require.InDelta(t, 0.4, body.Stats[0].RecoveryRate, 1e-9)
+	require.Zero(t, body.Stats[1].RecoveryRate)
+
+	// defaults to the last 30 days
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/carts/abandonment", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, gotTo.AddDate(0, 0, -30), gotFrom)
+
+	for _, q := range []string{"?from=yesterday", "?from=2026-10-01T00:00:00Z&to=2026-09-01T00:00:00Z"} {
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/carts/abandonment"+q, nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestCart_AddToUserCart_AddsToExistingLine(t *testing.T) {
+	var upserted map[string]int
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, currency, userID string) (string, error) {
+			require.Equal(t, "u-1", userID)
+			return "cart-1", nil
+		},
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, Status: "active", Items: []CartItem{{VariantID: "v-1", Qty: 2}}}, nil
+		},
+		stockFn: func(ctx context.Context, variantID string) (*VariantStock, error) {
+			return &VariantStock{VariantID: variantID, Active: true, Policy: "deny", Available: 4}, nil
+		},
+		upsertFn: func(ctx context.Context, cartID, variantID string, qty int) error {
+			upserted[variantID] = qty
+			return nil
+		},
+	}
+	svc := NewService(repo)
+
+	upserted = map[string]int{}
+	id, err := svc.AddToUserCart(context.Background(), "u-1", "v-1", 1)
+	require.NoError(t, err)
+	require.Equal(t, "cart-1", id)
+	require.Equal(t, map[string]int{"v-1": 3}, upserted)
+
+	upserted = map[string]int{}
+	id, err = svc.AddToUserCart(context.Background(), "u-1", "v-2", 1)
+	require.NoError(t, err)
+	require.Equal(t, "cart-1", id)
+	require.Equal(t, map[string]int{"v-2": 1}, upserted)
+
+	_, err = svc.AddToUserCart(context.Background(), "u-1", "v-1", 3)
+	require.ErrorIs(t, err, ErrInsufficientStock)
+}
+
+func TestCart_UserPaths_ReopenAbandonedCart(t *testing.T) {
+	secret := []byte(testSecret)
+	token, err := httpx.SignJWTWithRole("u-1", "customer", secret, time.Hour)
+	require.NoError(t, err)
+
+	// u-1's only cart was marked abandoned
+	status := "abandoned"
+	var revived int
+	repo := fakeRepo{
+		activeFn: func(ctx context.Context, userID string) (string, error) {
+			if status != "active" {
+				return "", ErrNotFound
+			}
+			return "old-cart", nil
+		},
+		reviveFn: func(ctx context.Context, userID string) (string, error) {
+			require.Equal(t, "u-1", userID)
+			if status != "abandoned" {
+				return "", ErrNotFound
+			}
+			status = "active"
+			revived++
+			return "old-cart", nil
+		},
+		createFn: func(ctx context.Context, currency, userID string) (string, error) {
+			require.Equal(t, "active", status, "the old cart is back before one is opened")
+			return "old-cart", nil
+		},
+		getFn: func(ctx context.Context, cartID string) (*Cart, error) {
+			return &Cart{ID: cartID, UserID: "u-1", Status: status, Items: []CartItem{{ID: "i-1", VariantID: "v-1", Qty: 2}}}, nil
+		},
+	}
+	r := chi.NewRouter()
+	r.Use(httpx.OptionalAuth(secret))
+	h := NewHandler(NewService(repo), testSecret, testSiteURL)
+	h.Routes(r)
+	h.UserRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/me/cart", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"id":"old-cart"`)
+	require.Equal(t, 1, revived)
+
+	status = "abandoned"
+	req = httptest.NewRequest(http.MethodPost, "/cart", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Contains(t, rec.Body.String(), "old-cart")
+	require.Equal(t, 2, revived)
+
+	// login merge and wishlist moves use the same cart
+	status = "abandoned"
+	id, err := NewService(repo).MergeGuestCart(context.Background(), "", "u-1")
+	require.NoError(t, err)
+	require.Equal(t, "old-cart", id)
+	require.Equal(t, 3, revived)
+}
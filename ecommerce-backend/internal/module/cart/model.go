@@ -1,13 +1,129 @@
 package cart
 
+import (
+	"math"
+	"time"
+
+	"github.com/synchhans/ecommerce-backend/internal/module/inventory"
+)
+
 type Cart struct {
-	ID     string     `json:"id"`
-	Status string     `json:"status"`
-	Items  []CartItem `json:"items"`
+	ID       string     `json:"id"`
+	UserID   string     `json:"-"` // "" for a guest cart
+	Status   string     `json:"status"`
+	Currency string     `json:"currency"`
+	Items    []CartItem `json:"items"`
+	// sum of the line totals checkout would charge: lines that are inactive
+	// or have no price in the cart currency don't count
+	Subtotal int64 `json:"subtotal"`
 }
 
+// Line warnings tell the shopper what to fix before checkout.
+const (
+	WarnInactive          = "inactive"           // variant or product no longer sold; dropped at checkout
+	WarnPriceUnavailable  = "price_unavailable"  // not sold in the cart currency; checkout fails
+	WarnInsufficientStock = "insufficient_stock" // more than can be sold; checkout fails
+	WarnPriceChanged      = "price_changed"      // unit price differs from price_at_add
+)
+
 type CartItem struct {
 	ID        string `json:"id"`
 	VariantID string `json:"variant_id"`
 	Qty       int    `json:"qty"`
+
+	ProductName string `json:"product_name"`
+	ProductSlug string `json:"product_slug"`
+	VariantName string `json:"variant_name"`
+	SKU         string `json:"sku"`
+	ImageURL    string `json:"image_url"`
+
+	// in the cart currency; nil when the variant has no price in it
+	UnitPrice  *int64   `json:"unit_price"`
+	PriceAtAdd *int64   `json:"price_at_add,omitempty"`
+	LineTotal  int64    `json:"line_total"`
+	Warnings   []string `json:"warnings"`
+
+	// only set with ?include=availability
+	Availability *AvailabilitySummary `json:"availability,omitempty"`
+
+	Stock *VariantStock `json:"-"` // for the warnings; nil skips stock checks
+}
+
+type AvailabilitySummary struct {
+	Available int  `json:"available"`
+	InStock   bool `json:"in_stock"`
+}
+
+// VariantStock is what the cart needs to decide whether a qty can be sold.
+type VariantStock struct {
+	VariantID      string
+	Active         bool
+	Policy         string // deny/backorder/preorder
+	Available      int    // unreserved stock across active locations
+	BackorderLimit *int   // nil = unlimited
+	Backordered    int    // outstanding backordered units on open orders
+}
+
+// Sellable is the most units CanSell allows (math.MaxInt when unlimited).
+func (v VariantStock) Sellable() int {
+	switch v.Policy {
+	case inventory.PolicyBackorder, inventory.PolicyPreorder:
+		if v.BackorderLimit == nil {
+			return math.MaxInt
+		}
+		return v.Available + max(*v.BackorderLimit-v.Backordered, 0)
+	}
+	return v.Available
+}
+
+// CanSell reports whether qty units fit the variant's inventory policy.
+func (v VariantStock) CanSell(qty int) bool {
+	if qty <= v.Available {
+		return true
+	}
+	switch v.Policy {
+	case inventory.PolicyBackorder, inventory.PolicyPreorder:
+		if v.BackorderLimit == nil {
+			return true
+		}
+		return qty-v.Available <= *v.BackorderLimit-v.Backordered
+	}
+	return false
+}
+
+// AbandonedCart is the event emitted when a cart is marked abandoned.
+type AbandonedCart struct {
+	CartID         string    `json:"cart_id"`
+	UserID         string    `json:"user_id,omitempty"`
+	Email          string    `json:"email,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	Currency       string    `json:"currency"`
+	Items          int       `json:"items"`
+	Value          int64     `json:"value"` // priced total in Currency when marked
+	LastActivityAt time.Time `json:"last_activity_at"`
+	RecoveryURL    string    `json:"recovery_url,omitempty"`
+}
+
+// AbandonmentStats covers carts with items created in the report window, per
+// currency. Merged guest carts are left out, their items live on in the
+// user's cart.
+type AbandonmentStats struct {
+	Currency       string `json:"currency"`
+	Carts          int    `json:"carts"`
+	Converted      int    `json:"converted"`
+	Abandoned      int    `json:"abandoned"`
+	AbandonedValue int64  `json:"abandoned_value"`
+	Recovered      int    `json:"recovered"`
+	RecoveredValue int64  `json:"recovered_value"`
+	// abandoned carts that went on to checkout
+	ConvertedAfterAbandonment int `json:"converted_after_abandonment"`
+
+	AbandonmentRate float64 `json:"abandonment_rate"` // abandoned / carts
+	RecoveryRate    float64 `json:"recovery_rate"`    // recovered / abandoned
+}
+
+type AbandonmentReport struct {
+	From  time.Time          `json:"from"`
+	To    time.Time          `json:"to"`
+	Stats []AbandonmentStats `json:"stats"`
 }
@@ -1,12 +1,54 @@
 package cart
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Repository interface {
-	CreateCart(ctx context.Context) (string, error)
+	// CreateCart opens a cart in currency ("" = the base currency). With a
+	// userID it returns the user's active cart instead when there is one,
+	// leaving its currency as is.
+	CreateCart(ctx context.Context, currency, userID string) (string, error)
 	GetCart(ctx context.Context, cartID string) (*Cart, error)
+	// CartOwner returns the cart's user ("" for a guest cart) and status, or ErrNotFound.
+	CartOwner(ctx context.Context, cartID string) (owner, status string, err error)
+	// ActiveCartID returns the user's active cart, or ErrNotFound.
+	ActiveCartID(ctx context.Context, userID string) (string, error)
+	// ReactivateUserCart makes the user's latest abandoned cart active again
+	// and returns it. ErrNotFound when they have an active cart already or no
+	// abandoned one.
+	ReactivateUserCart(ctx context.Context, userID string) (string, error)
+	// ClaimCart gives an active guest cart to the user.
+	ClaimCart(ctx context.Context, cartID, userID string) error
+	// MergeCart sets qty (variant -> new qty) on the user's cart and marks
+	// the guest cart merged, in one transaction.
+	MergeCart(ctx context.Context, guestCartID, userCartID string, qty map[string]int) error
+	// SetCurrency fails with ErrPriceUnavailable if an item has no price in currency.
+	SetCurrency(ctx context.Context, cartID, currency string) error
+	// UnpricedVariants returns the variants that have no price in the cart's currency.
+	UnpricedVariants(ctx context.Context, cartID string, variantIDs []string) ([]string, error)
 
+	// Item writes only touch active carts; ErrCartClosed once a cart is
+	// merged or converted.
 	UpsertItem(ctx context.Context, cartID, variantID string, qty int) error
 	UpdateItemQty(ctx context.Context, cartID, itemID string, qty int) error
 	DeleteItem(ctx context.Context, cartID, itemID string) error
+
+	// AvailableQty returns unreserved stock across active locations per variant.
+	AvailableQty(ctx context.Context, variantIDs []string) (map[string]int, error)
+	GetVariantStock(ctx context.Context, variantID string) (*VariantStock, error)
+
+	// MarkAbandoned marks active carts with items and no activity since
+	// idleSince as abandoned and returns them, with the owner's email.
+	MarkAbandoned(ctx context.Context, idleSince time.Time) ([]AbandonedCart, error)
+	// ReactivateCart makes an abandoned cart active again. ErrNotFound when it
+	// isn't abandoned or its user has opened another active cart since.
+	ReactivateCart(ctx context.Context, cartID string) error
+	// RecoverCart restores an abandoned cart and returns the active cart that
+	// now holds its items: the cart itself, or the user's newer active cart
+	// it was merged into. An active cart is returned as is; ErrNotFound for
+	// one that was converted or merged.
+	RecoverCart(ctx context.Context, cartID string) (string, error)
+	AbandonmentReport(ctx context.Context, from, to time.Time) ([]AbandonmentStats, error)
 }
@@ -3,13 +3,21 @@ package cart
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var ErrNotFound = errors.New("not found")
 var ErrInvalidQty = errors.New("invalid qty")
+var ErrInsufficientStock = errors.New("insufficient stock")
+var ErrInvalidCurrency = errors.New("unknown or inactive currency")
+var ErrPriceUnavailable = errors.New("variant not sold in the cart currency")
+var ErrForbidden = errors.New("cart belongs to someone else")
+var ErrInvalidRange = errors.New("from must be before to")
+var ErrCartClosed = errors.New("cart is no longer active")
 
 type PostgresRepository struct {
 	pool *pgxpool.Pool
@@ -19,20 +27,210 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 	return &PostgresRepository{pool: pool}
 }
 
-func (r *PostgresRepository) CreateCart(ctx context.Context) (string, error) {
+// variantStockCols selects a VariantStock (minus the id) for variant v of product p.
+const variantStockCols = `v.is_active AND p.is_active,
+       v.inventory_policy,
+       v.backorder_limit,
+       (SELECT available FROM variant_available(v.id)),
+       (SELECT qty FROM variant_backordered(v.id))`
+
+// priceInCart is the current price of variant $2 in cart $1's currency.
+const priceInCart = `(SELECT vp.price FROM carts c CROSS JOIN LATERAL variant_price_in($2::uuid, c.currency) vp WHERE c.id = $1)`
+
+func (r *PostgresRepository) CreateCart(ctx context.Context, currency, userID string) (string, error) {
 	var id string
-	err := r.pool.QueryRow(ctx, `INSERT INTO carts DEFAULT VALUES RETURNING id::text;`).Scan(&id)
+	err := r.pool.QueryRow(ctx, `
+INSERT INTO carts (currency, user_id)
+SELECT code, NULLIF($2, '')::uuid FROM currencies
+WHERE is_active AND (code = $1 OR ($1 = '' AND is_base))
+ON CONFLICT (user_id) WHERE status = 'active' AND user_id IS NOT NULL
+DO UPDATE SET updated_at = now()
+RETURNING id::text;
+`, currency, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInvalidCurrency
+	}
 	return id, err
 }
 
+func (r *PostgresRepository) CartOwner(ctx context.Context, cartID string) (owner, status string, err error) {
+	err = r.pool.QueryRow(ctx, `
+SELECT COALESCE(user_id::text, ''), status FROM carts WHERE id = $1;
+`, cartID).Scan(&owner, &status)
+	if isNotFound(err) {
+		return "", "", ErrNotFound // unknown or malformed id
+	}
+	return owner, status, err
+}
+
+func isNotFound(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == "22P02")
+}
+
+func (r *PostgresRepository) ActiveCartID(ctx context.Context, userID string) (string, error) {
+	var id string
+	err := r.pool.QueryRow(ctx, `
+SELECT id::text FROM carts WHERE user_id = $1 AND status = 'active';
+`, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return id, err
+}
+
+func (r *PostgresRepository) ReactivateUserCart(ctx context.Context, userID string) (string, error) {
+	var id string
+	err := r.pool.QueryRow(ctx, `
+UPDATE carts
+SET status = 'active', recovered_at = now(), updated_at = now()
+WHERE id = (
+    SELECT id FROM carts WHERE user_id = $1 AND status = 'abandoned'
+    ORDER BY abandoned_at DESC NULLS LAST LIMIT 1
+  )
+  AND NOT EXISTS (SELECT 1 FROM carts WHERE user_id = $1 AND status = 'active')
+RETURNING id::text;
+`, userID).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == "23505") {
+		return "", ErrNotFound // nothing to restore, or a cart was opened concurrently
+	}
+	return id, err
+}
+
+func (r *PostgresRepository) ClaimCart(ctx context.Context, cartID, userID string) error {
+	ct, err := r.pool.Exec(ctx, `
+UPDATE carts SET user_id = $2, updated_at = now()
+WHERE id = $1 AND status = 'active' AND user_id IS NULL;
+`, cartID, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) MergeCart(ctx context.Context, guestCartID, userCartID string, qty map[string]int) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// a concurrent login may have merged it already
+	ct, err := tx.Exec(ctx, `
+UPDATE carts SET status = 'merged', updated_at = now()
+WHERE id = $1 AND status = 'active';
+`, guestCartID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	for variantID, n := range qty {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO cart_items (cart_id, variant_id, qty, price_at_add)
+VALUES ($1, $2, $3, `+priceInCart+`)
+ON CONFLICT (cart_id, variant_id)
+DO UPDATE SET qty = EXCLUDED.qty, updated_at = now();
+`, userCartID, variantID, n); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE carts SET updated_at = now() WHERE id = $1;`, userCartID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) SetCurrency(ctx context.Context, cartID, currency string) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var active bool
+	err = tx.QueryRow(ctx, `SELECT is_active FROM currencies WHERE code = $1;`, currency).Scan(&active)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !active) {
+		return ErrInvalidCurrency
+	}
+	if err != nil {
+		return err
+	}
+
+	ct, err := tx.Exec(ctx, `
+UPDATE carts SET currency = $2, updated_at = now()
+WHERE id = $1 AND status = 'active';
+`, cartID, currency)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE cart_items ci
+SET price_at_add = (SELECT vp.price FROM variant_price_in(ci.variant_id, $2) vp)
+WHERE ci.cart_id = $1;
+`, cartID, currency); err != nil {
+		return err
+	}
+
+	var unpriced bool
+	err = tx.QueryRow(ctx, `
+SELECT EXISTS (
+  SELECT 1 FROM cart_items ci
+  WHERE ci.cart_id = $1
+    AND NOT EXISTS (SELECT 1 FROM variant_price_in(ci.variant_id, $2))
+);
+`, cartID, currency).Scan(&unpriced)
+	if err != nil {
+		return err
+	}
+	if unpriced {
+		return ErrPriceUnavailable
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) UnpricedVariants(ctx context.Context, cartID string, variantIDs []string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT v.id::text
+FROM carts c
+CROSS JOIN unnest($2::uuid[]) AS v(id)
+WHERE c.id = $1
+  AND NOT EXISTS (SELECT 1 FROM variant_price_in(v.id, c.currency));
+`, cartID, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
 func (r *PostgresRepository) GetCart(ctx context.Context, cartID string) (*Cart, error) {
 	var c Cart
 	err := r.pool.QueryRow(ctx, `
-SELECT id::text, status
+SELECT id::text, COALESCE(user_id::text, ''), status, currency
 FROM carts
 WHERE id = $1
 LIMIT 1;
-`, cartID).Scan(&c.ID, &c.Status)
+`, cartID).Scan(&c.ID, &c.UserID, &c.Status, &c.Currency)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -40,22 +238,34 @@ LIMIT 1;
 		return nil, err
 	}
 
+	// priced like checkout does: variant_price_in the cart's currency
 	rows, err := r.pool.Query(ctx, `
-SELECT id::text, variant_id::text, qty
-FROM cart_items
-WHERE cart_id = $1
-ORDER BY created_at ASC;
-`, cartID)
+SELECT ci.id::text, ci.variant_id::text, ci.qty, ci.price_at_add,
+       p.name, p.slug, v.name, v.sku,
+       COALESCE((SELECT pi.url FROM product_images pi WHERE pi.product_id = p.id ORDER BY pi.position LIMIT 1), ''),
+       vp.price,
+       `+variantStockCols+`
+FROM cart_items ci
+JOIN product_variants v ON v.id = ci.variant_id
+JOIN products p ON p.id = v.product_id
+LEFT JOIN LATERAL variant_price_in(v.id, $2) vp ON true
+WHERE ci.cart_id = $1
+ORDER BY ci.created_at ASC;
+`, cartID, c.Currency)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var it CartItem
-		if err := rows.Scan(&it.ID, &it.VariantID, &it.Qty); err != nil {
+		it := CartItem{Stock: &VariantStock{}}
+		if err := rows.Scan(&it.ID, &it.VariantID, &it.Qty, &it.PriceAtAdd,
+			&it.ProductName, &it.ProductSlug, &it.VariantName, &it.SKU, &it.ImageURL,
+			&it.UnitPrice,
+			&it.Stock.Active, &it.Stock.Policy, &it.Stock.BackorderLimit, &it.Stock.Available, &it.Stock.Backordered); err != nil {
 			return nil, err
 		}
+		it.Stock.VariantID = it.VariantID
 		c.Items = append(c.Items, it)
 	}
 	if err := rows.Err(); err != nil {
@@ -70,14 +280,22 @@ func (r *PostgresRepository) UpsertItem(ctx context.Context, cartID, variantID s
 		return ErrInvalidQty
 	}
 
-	// Upsert based on UNIQUE(cart_id, variant_id)
-	_, err := r.pool.Exec(ctx, `
-INSERT INTO cart_items (cart_id, variant_id, qty)
-VALUES ($1, $2, $3)
+	// Upsert based on UNIQUE(cart_id, variant_id); re-adding refreshes the
+	// price the shopper saw. Merged and converted carts no longer change.
+	ct, err := r.pool.Exec(ctx, `
+INSERT INTO cart_items (cart_id, variant_id, qty, price_at_add)
+SELECT $1, $2, $3, `+priceInCart+`
+WHERE EXISTS (SELECT 1 FROM carts WHERE id = $1 AND status = 'active')
 ON CONFLICT (cart_id, variant_id)
-DO UPDATE SET qty = EXCLUDED.qty, updated_at = now();
+DO UPDATE SET qty = EXCLUDED.qty, price_at_add = EXCLUDED.price_at_add, updated_at = now();
 `, cartID, variantID, qty)
-	return err
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return r.missingOrClosed(ctx, cartID)
+	}
+	return nil
 }
 
 func (r *PostgresRepository) UpdateItemQty(ctx context.Context, cartID, itemID string, qty int) error {
@@ -87,13 +305,14 @@ func (r *PostgresRepository) UpdateItemQty(ctx context.Context, cartID, itemID s
 	ct, err := r.pool.Exec(ctx, `
 UPDATE cart_items
 SET qty = $1, updated_at = now()
-WHERE id = $2 AND cart_id = $3;
+WHERE id = $2 AND cart_id = $3
+  AND EXISTS (SELECT 1 FROM carts WHERE id = $3 AND status = 'active');
 `, qty, itemID, cartID)
 	if err != nil {
 		return err
 	}
 	if ct.RowsAffected() == 0 {
-		return ErrNotFound
+		return r.missingOrClosed(ctx, cartID)
 	}
 	return nil
 }
@@ -101,8 +320,142 @@ WHERE id = $2 AND cart_id = $3;
 func (r *PostgresRepository) DeleteItem(ctx context.Context, cartID, itemID string) error {
 	ct, err := r.pool.Exec(ctx, `
 DELETE FROM cart_items
-WHERE id = $1 AND cart_id = $2;
+WHERE id = $1 AND cart_id = $2
+  AND EXISTS (SELECT 1 FROM carts WHERE id = $2 AND status = 'active');
 `, itemID, cartID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return r.missingOrClosed(ctx, cartID)
+	}
+	return nil
+}
+
+// missingOrClosed explains an item write that touched nothing: ErrCartClosed
+// when the cart was merged, converted or abandoned meanwhile, else ErrNotFound.
+func (r *PostgresRepository) missingOrClosed(ctx context.Context, cartID string) error {
+	var active bool
+	err := r.pool.QueryRow(ctx, `SELECT status = 'active' FROM carts WHERE id = $1;`, cartID).Scan(&active)
+	if isNotFound(err) || (err == nil && active) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrCartClosed
+}
+
+func (r *PostgresRepository) AvailableQty(ctx context.Context, variantIDs []string) (map[string]int, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id::text, s.available
+FROM unnest($1::uuid[]) AS id
+CROSS JOIN LATERAL variant_available(id) s;
+`, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]int, len(variantIDs))
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) GetVariantStock(ctx context.Context, variantID string) (*VariantStock, error) {
+	v := VariantStock{VariantID: variantID}
+	err := r.pool.QueryRow(ctx, `
+SELECT `+variantStockCols+`
+FROM product_variants v
+JOIN products p ON p.id = v.product_id
+WHERE v.id = $1
+LIMIT 1;
+`, variantID).Scan(&v.Active, &v.Policy, &v.BackorderLimit, &v.Available, &v.Backordered)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ===== Abandoned carts =====
+
+// Activity is the latest change to the cart or any of its items. Empty carts
+// are left alone: there is nothing to recover and they'd swamp the report.
+func (r *PostgresRepository) MarkAbandoned(ctx context.Context, idleSince time.Time) ([]AbandonedCart, error) {
+	rows, err := r.pool.Query(ctx, `
+WITH idle AS (
+  SELECT c.id, GREATEST(c.updated_at, MAX(ci.updated_at)) AS last_activity_at
+  FROM carts c
+  JOIN cart_items ci ON ci.cart_id = c.id
+  WHERE c.status = 'active' AND c.updated_at < $1
+  GROUP BY c.id
+  HAVING MAX(ci.updated_at) < $1
+),
+priced AS (
+  SELECT idle.id, idle.last_activity_at,
+         COUNT(*)::int AS items,
+         COALESCE(SUM(ci.qty * vp.price) FILTER (WHERE v.is_active AND p.is_active), 0)::bigint AS value
+  FROM idle
+  JOIN carts c ON c.id = idle.id
+  JOIN cart_items ci ON ci.cart_id = idle.id
+  JOIN product_variants v ON v.id = ci.variant_id
+  JOIN products p ON p.id = v.product_id
+  LEFT JOIN LATERAL variant_price_in(v.id, c.currency) vp ON true
+  GROUP BY idle.id, idle.last_activity_at
+),
+marked AS (
+  UPDATE carts c
+  SET status = 'abandoned', abandoned_at = now(), abandoned_value = priced.value
+  FROM priced
+  WHERE c.id = priced.id AND c.status = 'active'
+  RETURNING c.id, c.user_id, c.currency, priced.items, priced.value, priced.last_activity_at
+)
+SELECT m.id::text, COALESCE(m.user_id::text, ''), COALESCE(u.email, ''), COALESCE(u.name, ''),
+       m.currency, m.items, m.value, m.last_activity_at
+FROM marked m
+LEFT JOIN users u ON u.id = m.user_id AND u.status = 'active'
+ORDER BY m.last_activity_at;
+`, idleSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AbandonedCart
+	for rows.Next() {
+		var a AbandonedCart
+		if err := rows.Scan(&a.CartID, &a.UserID, &a.Email, &a.Name,
+			&a.Currency, &a.Items, &a.Value, &a.LastActivityAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) ReactivateCart(ctx context.Context, cartID string) error {
+	ct, err := r.pool.Exec(ctx, `
+UPDATE carts c
+SET status = 'active', recovered_at = now(), updated_at = now()
+WHERE c.id = $1 AND c.status = 'abandoned'
+  AND (c.user_id IS NULL OR NOT EXISTS (
+    SELECT 1 FROM carts o WHERE o.user_id = c.user_id AND o.status = 'active'
+  ));
+`, cartID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrNotFound // the user opened a cart concurrently
+	}
 	if err != nil {
 		return err
 	}
@@ -111,3 +464,108 @@ WHERE id = $1 AND cart_id = $2;
 	}
 	return nil
 }
+
+func (r *PostgresRepository) RecoverCart(ctx context.Context, cartID string) (string, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var owner, status string
+	err = tx.QueryRow(ctx, `
+SELECT COALESCE(user_id::text, ''), status FROM carts WHERE id = $1 FOR UPDATE;
+`, cartID).Scan(&owner, &status)
+	if isNotFound(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case "active":
+		return cartID, nil // already back, e.g. the link clicked twice
+	case "abandoned":
+	default:
+		return "", ErrNotFound
+	}
+
+	if owner != "" {
+		var activeID string
+		err := tx.QueryRow(ctx, `
+SELECT id::text FROM carts WHERE user_id = $1 AND status = 'active' FOR UPDATE;
+`, owner).Scan(&activeID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+		if err == nil {
+			// the user started another cart since: bring the items over,
+			// keeping the larger qty where both carts have a variant
+			if _, err := tx.Exec(ctx, `
+INSERT INTO cart_items (cart_id, variant_id, qty, price_at_add)
+SELECT c.id, ci.variant_id, ci.qty, vp.price
+FROM cart_items ci
+JOIN carts c ON c.id = $2
+LEFT JOIN LATERAL variant_price_in(ci.variant_id, c.currency) vp ON true
+WHERE ci.cart_id = $1
+ON CONFLICT (cart_id, variant_id)
+DO UPDATE SET qty = GREATEST(cart_items.qty, EXCLUDED.qty), updated_at = now();
+`, cartID, activeID); err != nil {
+				return "", err
+			}
+			if _, err := tx.Exec(ctx, `
+UPDATE carts SET status = 'merged', recovered_at = now(), updated_at = now() WHERE id = $1;
+`, cartID); err != nil {
+				return "", err
+			}
+			if _, err := tx.Exec(ctx, `UPDATE carts SET updated_at = now() WHERE id = $1;`, activeID); err != nil {
+				return "", err
+			}
+			return activeID, tx.Commit(ctx)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+UPDATE carts SET status = 'active', recovered_at = now(), updated_at = now() WHERE id = $1;
+`, cartID); err != nil {
+		return "", err
+	}
+	return cartID, tx.Commit(ctx)
+}
+
+// AbandonmentReport counts carts created in [from, to). A recovered cart that
+// was merged into the user's newer cart still counts as recovered.
+func (r *PostgresRepository) AbandonmentReport(ctx context.Context, from, to time.Time) ([]AbandonmentStats, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT c.currency,
+       COUNT(*)::int,
+       COUNT(*) FILTER (WHERE c.status = 'converted')::int,
+       COUNT(*) FILTER (WHERE c.abandoned_at IS NOT NULL)::int,
+       COALESCE(SUM(c.abandoned_value) FILTER (WHERE c.abandoned_at IS NOT NULL), 0)::bigint,
+       COUNT(*) FILTER (WHERE c.recovered_at IS NOT NULL)::int,
+       COALESCE(SUM(c.abandoned_value) FILTER (WHERE c.recovered_at IS NOT NULL), 0)::bigint,
+       COUNT(*) FILTER (WHERE c.abandoned_at IS NOT NULL AND c.status = 'converted')::int
+FROM carts c
+WHERE c.created_at >= $1 AND c.created_at < $2
+  AND (c.status <> 'merged' OR c.abandoned_at IS NOT NULL)
+  AND EXISTS (SELECT 1 FROM cart_items ci WHERE ci.cart_id = c.id)
+GROUP BY c.currency
+ORDER BY c.currency;
+`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AbandonmentStats
+	for rows.Next() {
+		var st AbandonmentStats
+		if err := rows.Scan(&st.Currency, &st.Carts, &st.Converted,
+			&st.Abandoned, &st.AbandonedValue, &st.Recovered, &st.RecoveredValue,
+			&st.ConvertedAfterAbandonment); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
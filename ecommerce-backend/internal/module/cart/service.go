@@ -1,6 +1,13 @@
 package cart
 
-import "context"
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/synchhans/ecommerce-backend/internal/platform/money"
+)
 
 type Service struct {
 	repo Repository
@@ -10,22 +17,325 @@ func NewService(repo Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) CreateCart(ctx context.Context) (string, error) {
-	return s.repo.CreateCart(ctx)
+// CreateCart opens a guest cart, or for a signed-in user (userID != "")
+// returns their active cart, opening one if needed. An existing cart is
+// switched to the requested currency like SetCurrency would.
+func (s *Service) CreateCart(ctx context.Context, currency, userID string) (string, error) {
+	currency = money.Normalize(currency)
+	if currency != "" && !money.ValidCode(currency) {
+		return "", ErrInvalidCurrency
+	}
+	if userID != "" {
+		// back after the abandon window: reopen the old cart rather than
+		// starting an empty one
+		if _, err := s.repo.ReactivateUserCart(ctx, userID); err != nil && !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	id, err := s.repo.CreateCart(ctx, currency, userID)
+	if err != nil {
+		return "", err
+	}
+	if userID != "" && currency != "" {
+		if err := s.repo.SetCurrency(ctx, id, currency); err != nil {
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+// activeUserCart returns the user's active cart, reopening their latest
+// abandoned one when they have none; ErrNotFound when there is neither.
+func (s *Service) activeUserCart(ctx context.Context, userID string) (string, error) {
+	id, err := s.repo.ActiveCartID(ctx, userID)
+	if !errors.Is(err, ErrNotFound) {
+		return id, err
+	}
+	return s.repo.ReactivateUserCart(ctx, userID)
+}
+
+// Authorize checks that the caller may use the cart: its owner for a user
+// cart, the holder of its cart token (guestToken) for a guest cart. A
+// shopper coming back to an abandoned cart makes it active again, unless
+// they have opened another cart since.
+func (s *Service) Authorize(ctx context.Context, cartID, userID string, guestToken bool) error {
+	owner, status, err := s.repo.CartOwner(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	if (owner == "" && !guestToken) || (owner != "" && owner != userID) {
+		return ErrForbidden
+	}
+	if status == "abandoned" {
+		if err := s.repo.ReactivateCart(ctx, cartID); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecoverCart restores an abandoned cart from a recovery link and returns
+// the active cart holding its items.
+func (s *Service) RecoverCart(ctx context.Context, cartID string) (string, error) {
+	return s.repo.RecoverCart(ctx, cartID)
 }
 
-func (s *Service) GetCart(ctx context.Context, cartID string) (*Cart, error) {
-	return s.repo.GetCart(ctx, cartID)
+// AbandonmentReport covers carts created in [from, to).
+func (s *Service) AbandonmentReport(ctx context.Context, from, to time.Time) (*AbandonmentReport, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+	stats, err := s.repo.AbandonmentReport(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		st := &stats[i]
+		if st.Carts > 0 {
+			st.AbandonmentRate = float64(st.Abandoned) / float64(st.Carts)
+		}
+		if st.Abandoned > 0 {
+			st.RecoveryRate = float64(st.Recovered) / float64(st.Abandoned)
+		}
+	}
+	if stats == nil {
+		stats = []AbandonmentStats{}
+	}
+	return &AbandonmentReport{From: from, To: to, Stats: stats}, nil
+}
+
+// MyCart returns the user's active cart, reopening an abandoned one, or
+// ErrNotFound.
+func (s *Service) MyCart(ctx context.Context, userID string, withAvailability bool) (*Cart, error) {
+	id, err := s.activeUserCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, id, withAvailability)
+}
+
+// MergeGuestCart runs at login: the guest cart becomes the user's cart, or
+// when they already have one its items are added to it. Quantities are
+// summed and capped at what can be sold; items that can't be sold or priced
+// in the user's cart currency stay behind. A cart that is unknown, no longer
+// active or someone else's is ignored. It returns the user's active cart
+// ("" when they have none).
+func (s *Service) MergeGuestCart(ctx context.Context, guestCartID, userID string) (string, error) {
+	guest, err := s.repo.GetCart(ctx, guestCartID)
+	if errors.Is(err, ErrNotFound) {
+		guest = nil
+	} else if err != nil {
+		return "", err
+	}
+	if guest != nil && (guest.Status != "active" || (guest.UserID != "" && guest.UserID != userID)) {
+		guest = nil
+	}
+
+	userCartID, err := s.activeUserCart(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		if guest == nil {
+			return "", nil
+		}
+		if err := s.repo.ClaimCart(ctx, guest.ID, userID); err != nil {
+			return "", err
+		}
+		return guest.ID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if guest == nil || guest.ID == userCartID {
+		return userCartID, nil
+	}
+
+	mine, err := s.repo.GetCart(ctx, userCartID)
+	if err != nil {
+		return "", err
+	}
+	have := make(map[string]int, len(mine.Items))
+	for _, it := range mine.Items {
+		have[it.VariantID] = it.Qty
+	}
+	ids := make([]string, 0, len(guest.Items))
+	for _, it := range guest.Items {
+		ids = append(ids, it.VariantID)
+	}
+	unpriced, err := s.repo.UnpricedVariants(ctx, userCartID, ids)
+	if err != nil {
+		return "", err
+	}
+
+	qty := make(map[string]int, len(guest.Items))
+	for _, it := range guest.Items {
+		if slices.Contains(unpriced, it.VariantID) {
+			continue
+		}
+		v, err := s.repo.GetVariantStock(ctx, it.VariantID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !v.Active {
+			continue
+		}
+		// never lower what the user already had
+		if n := min(have[it.VariantID]+it.Qty, v.Sellable()); n > have[it.VariantID] {
+			qty[it.VariantID] = n
+		}
+	}
+
+	if err := s.repo.MergeCart(ctx, guest.ID, userCartID, qty); err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	return userCartID, nil
+}
+
+// SetCurrency switches the cart's currency; every item must be sold in it.
+func (s *Service) SetCurrency(ctx context.Context, cartID, currency string) error {
+	currency = money.Normalize(currency)
+	if !money.ValidCode(currency) {
+		return ErrInvalidCurrency
+	}
+	return s.repo.SetCurrency(ctx, cartID, currency)
+}
+
+// GetCart returns the cart priced the way checkout prices it, with line
+// totals, the subtotal and per-line warnings.
+func (s *Service) GetCart(ctx context.Context, cartID string, withAvailability bool) (*Cart, error) {
+	c, err := s.repo.GetCart(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	priceCart(c)
+	if withAvailability && len(c.Items) > 0 {
+		ids := make([]string, 0, len(c.Items))
+		for _, it := range c.Items {
+			ids = append(ids, it.VariantID)
+		}
+		avail, err := s.repo.AvailableQty(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range c.Items {
+			n := avail[c.Items[i].VariantID]
+			c.Items[i].Availability = &AvailabilitySummary{Available: n, InStock: n >= c.Items[i].Qty}
+		}
+	}
+	return c, nil
+}
+
+// priceCart fills in line totals, the subtotal and warnings.
+func priceCart(c *Cart) {
+	c.Subtotal = 0
+	for i := range c.Items {
+		it := &c.Items[i]
+		it.LineTotal = 0
+		it.Warnings = []string{}
+		if it.Stock != nil && !it.Stock.Active {
+			it.Warnings = append(it.Warnings, WarnInactive)
+			continue
+		}
+		if it.UnitPrice == nil {
+			it.Warnings = append(it.Warnings, WarnPriceUnavailable)
+			continue
+		}
+		it.LineTotal = *it.UnitPrice * int64(it.Qty)
+		c.Subtotal += it.LineTotal
+		if it.Stock != nil && !it.Stock.CanSell(it.Qty) {
+			it.Warnings = append(it.Warnings, WarnInsufficientStock)
+		}
+		if it.PriceAtAdd != nil && *it.PriceAtAdd != *it.UnitPrice {
+			it.Warnings = append(it.Warnings, WarnPriceChanged)
+		}
+	}
 }
 
 func (s *Service) AddOrReplaceItem(ctx context.Context, cartID, variantID string, qty int) error {
+	if qty <= 0 {
+		return ErrInvalidQty
+	}
+	if err := s.checkStock(ctx, variantID, qty); err != nil {
+		return err
+	}
+	unpriced, err := s.repo.UnpricedVariants(ctx, cartID, []string{variantID})
+	if err != nil {
+		return err
+	}
+	if len(unpriced) > 0 {
+		return ErrPriceUnavailable
+	}
 	return s.repo.UpsertItem(ctx, cartID, variantID, qty)
 }
 
+// AddToUserCart adds qty units of a variant on top of what the user's active
+// cart already holds, opening a cart in the base currency if needed, and
+// returns the cart.
+func (s *Service) AddToUserCart(ctx context.Context, userID, variantID string, qty int) (string, error) {
+	if qty <= 0 {
+		return "", ErrInvalidQty
+	}
+	cartID, err := s.CreateCart(ctx, "", userID)
+	if err != nil {
+		return "", err
+	}
+	c, err := s.repo.GetCart(ctx, cartID)
+	if err != nil {
+		return "", err
+	}
+	for _, it := range c.Items {
+		if it.VariantID == variantID {
+			qty += it.Qty
+			break
+		}
+	}
+	if err := s.AddOrReplaceItem(ctx, cartID, variantID, qty); err != nil {
+		return "", err
+	}
+	return cartID, nil
+}
+
 func (s *Service) UpdateItemQty(ctx context.Context, cartID, itemID string, qty int) error {
+	if qty <= 0 {
+		return ErrInvalidQty
+	}
+	c, err := s.repo.GetCart(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	variantID := ""
+	for _, it := range c.Items {
+		if it.ID == itemID {
+			variantID = it.VariantID
+			break
+		}
+	}
+	if variantID == "" {
+		return ErrNotFound
+	}
+	if err := s.checkStock(ctx, variantID, qty); err != nil {
+		return err
+	}
 	return s.repo.UpdateItemQty(ctx, cartID, itemID, qty)
 }
 
+// checkStock applies the variant's inventory policy (deny/backorder/preorder).
+// Checkout re-checks against the allocated location, this is the early gate.
+func (s *Service) checkStock(ctx context.Context, variantID string, qty int) error {
+	v, err := s.repo.GetVariantStock(ctx, variantID)
+	if err != nil {
+		return err
+	}
+	if !v.Active {
+		return ErrNotFound
+	}
+	if !v.CanSell(qty) {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
 func (s *Service) RemoveItem(ctx context.Context, cartID, itemID string) error {
 	return s.repo.DeleteItem(ctx, cartID, itemID)
 }
@@ -1,49 +1,146 @@
 package catalog
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
 )
 
 type Handler struct {
 	svc *Service
+	// storefront origin, e.g. https://shop.example.com; canonical and
+	// sitemap URLs point at its /product/{slug} and /catalog?cat={slug} pages
+	siteURL string
 }
 
-func NewHandler(svc *Service) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc *Service, siteURL string) *Handler {
+	return &Handler{svc: svc, siteURL: strings.TrimRight(siteURL, "/")}
 }
 
 func (h *Handler) Routes(r chi.Router) {
 	r.Get("/products", h.listProducts)
 	r.Get("/products/{slug}", h.getProductBySlug)
+	r.Get("/products/{slug}/variant", h.findVariant)
+	r.Get("/products/{slug}/related", h.relatedProducts)
+	r.Get("/products/{slug}/frequently-bought-together", h.frequentlyBoughtTogether)
+	r.Get("/search", h.search)
+	r.Get("/categories", h.listCategories)
+	r.Get("/categories/{slug}/products", h.listCategoryProducts)
+	r.Get("/sitemap.xml", h.sitemap)
 }
 
 func (h *Handler) listProducts(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	limit := parseInt(q.Get("limit"), 20)
-	offset := parseInt(q.Get("offset"), 0)
-	search := q.Get("search")
+	p, ok := parseListParams(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_query"})
+		return
+	}
 
-	items, err := h.svc.ListProducts(r.Context(), limit, offset, search)
+	page, facets, err := h.svc.ListProducts(r.Context(), p)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		pagination.Page[ProductListItem]
+		Facets *Facets `json:"facets"`
+	}{page, facets})
+}
+
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	p, ok := parseListParams(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_query"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
-		"items":  items,
-		"limit":  limit,
-		"offset": offset,
-	})
+	p.Search = r.URL.Query().Get("q")
+
+	page, facets, err := h.svc.Search(r.Context(), p)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Query string `json:"query"`
+		pagination.Page[SearchResult]
+		Facets *Facets `json:"facets"`
+	}{p.Search, page, facets})
+}
+
+// parseListParams reads ?search, category, min_price, max_price, in_stock,
+// on_sale, min_rating, sort, limit, cursor and include=total.
+func parseListParams(r *http.Request) (ListParams, bool) {
+	q := r.URL.Query()
+	p := ListParams{
+		Limit:     parseInt(q.Get("limit"), pagination.DefaultLimit),
+		Cursor:    q.Get("cursor"),
+		Search:    q.Get("search"),
+		Category:  q.Get("category"),
+		InStock:   q.Get("in_stock") == "true",
+		OnSale:    q.Get("on_sale") == "true",
+		Sort:      q.Get("sort"),
+		WithTotal: httpx.Includes(r, "total"),
+	}
+	for key, dst := range map[string]**int64{"min_price": &p.MinPrice, "max_price": &p.MaxPrice} {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return p, false
+		}
+		*dst = &n
+	}
+	if v := q.Get("min_rating"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return p, false
+		}
+		p.MinRating = &n
+	}
+	return p, true
+}
+
+func writeListError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+	case errors.Is(err, ErrInvalidPayload):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_query"})
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_cursor"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+	}
 }
 
 func (h *Handler) getProductBySlug(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
-	p, err := h.svc.GetProductDetail(r.Context(), slug)
+	p, err := h.svc.GetProductDetail(r.Context(), slug, httpx.Includes(r, "availability"), r.URL.Query().Get("currency"))
 	if err != nil {
+		if redirectMoved(w, r, err) {
+			return
+		}
+		if errors.Is(err, ErrInvalidPayload) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_currency"})
+			return
+		}
 		if errors.Is(err, ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
 			return
@@ -52,9 +149,590 @@ func (h *Handler) getProductBySlug(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
 		return
 	}
+	if p.SEO.CanonicalURL == "" {
+		p.SEO.CanonicalURL = h.productURL(p.Slug)
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// redirectMoved answers 301 to the same endpoint under the product's current
+// slug, keeping the query string. It reports whether err was a *MovedError.
+func redirectMoved(w http.ResponseWriter, r *http.Request, err error) bool {
+	var moved *MovedError
+	if !errors.As(err, &moved) {
+		return false
+	}
+	loc := *r.URL
+	loc.Path = strings.Replace(r.URL.Path, "/products/"+chi.URLParam(r, "slug"), "/products/"+moved.Slug, 1)
+	loc.RawPath = ""
+	w.Header().Set("Location", loc.RequestURI())
+	writeJSON(w, http.StatusMovedPermanently, map[string]any{"error": "moved", "slug": moved.Slug})
+	return true
+}
+
+func (h *Handler) productURL(slug string) string {
+	return h.siteURL + "/product/" + url.PathEscape(slug)
+}
+
+func (h *Handler) categoryURL(slug string) string {
+	return h.siteURL + "/catalog?cat=" + url.QueryEscape(slug)
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+func (h *Handler) sitemap(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.svc.Sitemap(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		return
+	}
+
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: make([]sitemapURL, 0, len(entries))}
+	for _, e := range entries {
+		u := sitemapURL{LastMod: e.UpdatedAt.UTC().Format("2006-01-02")}
+		switch {
+		case e.Kind == "category":
+			u.Loc = h.categoryURL(e.Slug)
+		case e.CanonicalURL != "":
+			u.Loc = e.CanonicalURL
+		default:
+			u.Loc = h.productURL(e.Slug)
+		}
+		set.URLs = append(set.URLs, u)
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(set)
+}
+
+func (h *Handler) relatedProducts(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.RelatedProducts(r.Context(), chi.URLParam(r, "slug"), parseInt(r.URL.Query().Get("limit"), 0))
+	if err != nil {
+		if redirectMoved(w, r, err) {
+			return
+		}
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) frequentlyBoughtTogether(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.FrequentlyBoughtTogether(r.Context(), chi.URLParam(r, "slug"), parseInt(r.URL.Query().Get("limit"), 0))
+	if err != nil {
+		if redirectMoved(w, r, err) {
+			return
+		}
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+// AdminRoutes must be mounted behind AuthMiddleware + RequireRole("admin").
+func (h *Handler) AdminRoutes(r chi.Router) {
+	r.Post("/admin/products", h.createProduct)
+	r.Patch("/admin/products/{id}", h.updateProduct)
+	r.Post("/admin/products/{id}/archive", h.archiveProduct)
+
+	r.Post("/admin/products/{id}/variants", h.createVariant)
+	r.Patch("/admin/variants/{id}", h.updateVariant)
+	r.Post("/admin/variants/{id}/deactivate", h.deactivateVariant)
+	r.Put("/admin/variants/{id}/options", h.setVariantOptions)
+	r.Post("/admin/variants/{id}/price-schedules", h.schedulePrice)
+	r.Get("/admin/variants/{id}/price-schedules", h.listPriceSchedules)
+	r.Delete("/admin/price-schedules/{id}", h.cancelPriceSchedule)
+	r.Get("/admin/variants/{id}/price-history", h.priceHistory)
+
+	r.Post("/admin/products/{id}/options", h.createOption)
+	r.Post("/admin/options/{id}/values", h.addOptionValue)
+
+	r.Post("/admin/products/{id}/images", h.addImage)
+	r.Put("/admin/products/{id}/images/order", h.reorderImages)
+	r.Delete("/admin/products/{id}/images/{imageID}", h.removeImage)
+	r.Put("/admin/products/{id}/categories", h.setProductCategories)
+
+	r.Post("/admin/categories", h.createCategory)
+	r.Patch("/admin/categories/{id}", h.updateCategory)
+	r.Delete("/admin/categories/{id}", h.deleteCategory)
+
+	r.Post("/admin/catalog/import", h.importCatalog)
+	r.Get("/admin/catalog/export", h.exportCatalog)
+}
+
+func (h *Handler) createProduct(w http.ResponseWriter, r *http.Request) {
+	var in ProductInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	p, err := h.svc.CreateProduct(r.Context(), in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+func (h *Handler) updateProduct(w http.ResponseWriter, r *http.Request) {
+	var in ProductInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	p, err := h.svc.UpdateProduct(r.Context(), chi.URLParam(r, "id"), in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
 	writeJSON(w, http.StatusOK, p)
 }
 
+func (h *Handler) archiveProduct(w http.ResponseWriter, r *http.Request) {
+	p, err := h.svc.ArchiveProduct(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) createVariant(w http.ResponseWriter, r *http.Request) {
+	var in VariantInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	v, err := h.svc.CreateVariant(r.Context(), chi.URLParam(r, "id"), in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, v)
+}
+
+func (h *Handler) updateVariant(w http.ResponseWriter, r *http.Request) {
+	var in VariantInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	v, err := h.svc.UpdateVariant(r.Context(), chi.URLParam(r, "id"), in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *Handler) deactivateVariant(w http.ResponseWriter, r *http.Request) {
+	v, err := h.svc.DeactivateVariant(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+type addImageReq struct {
+	URL      string `json:"url"`
+	Position *int   `json:"position"`
+}
+
+func (h *Handler) addImage(w http.ResponseWriter, r *http.Request) {
+	var req addImageReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	im, err := h.svc.AddImage(r.Context(), chi.URLParam(r, "id"), req.URL, req.Position)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, im)
+}
+
+type reorderImagesReq struct {
+	ImageIDs []string `json:"image_ids"`
+}
+
+func (h *Handler) reorderImages(w http.ResponseWriter, r *http.Request) {
+	var req reorderImagesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	items, err := h.svc.ReorderImages(r.Context(), chi.URLParam(r, "id"), req.ImageIDs)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) removeImage(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.RemoveImage(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "imageID")); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPayload):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+	case errors.Is(err, ErrInvalidParent):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_parent"})
+	case errors.Is(err, ErrInvalidOptions):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_options"})
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+	case errors.Is(err, ErrSlugTaken):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "slug_taken"})
+	case errors.Is(err, ErrSKUTaken):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "sku_taken"})
+	case errors.Is(err, ErrCombinationTaken):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "combination_taken"})
+	case errors.Is(err, ErrOptionTaken):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "option_taken"})
+	case errors.Is(err, ErrScheduleConflict):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "schedule_conflict"})
+	case errors.Is(err, ErrScheduleNotPending):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "schedule_not_pending"})
+	case errors.Is(err, ErrInvalidFormat):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_format"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+	}
+}
+
+func (h *Handler) listCategories(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.CategoryTree(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) listCategoryProducts(w http.ResponseWriter, r *http.Request) {
+	p, ok := parseListParams(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_query"})
+		return
+	}
+
+	c, page, facets, err := h.svc.CategoryProducts(r.Context(), chi.URLParam(r, "slug"), p)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Category *Category `json:"category"`
+		pagination.Page[ProductListItem]
+		Facets *Facets `json:"facets"`
+	}{c, page, facets})
+}
+
+type productCategoriesReq struct {
+	CategoryIDs []string `json:"category_ids"`
+}
+
+func (h *Handler) setProductCategories(w http.ResponseWriter, r *http.Request) {
+	var req productCategoriesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	items, err := h.svc.SetProductCategories(r.Context(), chi.URLParam(r, "id"), req.CategoryIDs)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) createCategory(w http.ResponseWriter, r *http.Request) {
+	var in CategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	c, err := h.svc.CreateCategory(r.Context(), in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (h *Handler) updateCategory(w http.ResponseWriter, r *http.Request) {
+	var in CategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	c, err := h.svc.UpdateCategory(r.Context(), chi.URLParam(r, "id"), in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Handler) deleteCategory(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteCategory(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findVariant resolves ?Size=M&Color=Black (option names as keys) to one variant.
+func (h *Handler) findVariant(w http.ResponseWriter, r *http.Request) {
+	selected := map[string]string{}
+	for k, vs := range r.URL.Query() {
+		if len(vs) > 0 {
+			selected[k] = vs[0]
+		}
+	}
+
+	v, err := h.svc.FindVariant(r.Context(), chi.URLParam(r, "slug"), selected)
+	if err != nil {
+		if redirectMoved(w, r, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, ErrInvalidOptions):
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_options"})
+		case errors.Is(err, ErrNotFound):
+			// missing product or no matching variant
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "variant_not_found"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *Handler) createOption(w http.ResponseWriter, r *http.Request) {
+	var in OptionInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	o, err := h.svc.CreateOption(r.Context(), chi.URLParam(r, "id"), in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, o)
+}
+
+type optionValueReq struct {
+	Value string `json:"value"`
+}
+
+func (h *Handler) addOptionValue(w http.ResponseWriter, r *http.Request) {
+	var req optionValueReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	ov, err := h.svc.AddOptionValue(r.Context(), chi.URLParam(r, "id"), req.Value)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ov)
+}
+
+type variantOptionsReq struct {
+	Options map[string]string `json:"options"`
+}
+
+func (h *Handler) setVariantOptions(w http.ResponseWriter, r *http.Request) {
+	var req variantOptionsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	out, err := h.svc.SetVariantOptions(r.Context(), chi.URLParam(r, "id"), req.Options)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"options": out})
+}
+
+func (h *Handler) schedulePrice(w http.ResponseWriter, r *http.Request) {
+	actor, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+	var in PriceScheduleInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	ps, err := h.svc.SchedulePrice(r.Context(), chi.URLParam(r, "id"), actor, in)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ps)
+}
+
+func (h *Handler) listPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.PriceSchedules(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) cancelPriceSchedule(w http.ResponseWriter, r *http.Request) {
+	ps, err := h.svc.CancelPriceSchedule(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ps)
+}
+
+// priceHistory lists changes newest first, or with ?at=<RFC3339> returns the
+// price in effect at that moment (e.g. to check an order_items.unit_price).
+func (h *Handler) priceHistory(w http.ResponseWriter, r *http.Request) {
+	variantID := chi.URLParam(r, "id")
+	q := r.URL.Query()
+
+	if raw := q.Get("at"); raw != "" {
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_query"})
+			return
+		}
+		pp, err := h.svc.PriceAt(r.Context(), variantID, at)
+		if err != nil {
+			writeListError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, pp)
+		return
+	}
+
+	page, err := h.svc.PriceHistory(r.Context(), variantID, parseInt(q.Get("limit"), 0), q.Get("cursor"))
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// maxImportBytes caps an import upload.
+const maxImportBytes = 32 << 20
+
+// importCatalog takes the file as the raw body or as multipart field "file".
+// The format comes from ?format=, else the content type or file extension.
+// Any row error answers 422 and nothing is written; ?dry_run=true only
+// validates.
+func (h *Handler) importCatalog(w http.ResponseWriter, r *http.Request) {
+	actor, _ := httpx.UserIDFromContext(r.Context())
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var body io.Reader = r.Body
+	format := r.URL.Query().Get("format")
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if ct == "multipart/form-data" {
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			var tooBig *http.MaxBytesError
+			if errors.As(err, &tooBig) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"error": "file_too_large"})
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+			return
+		}
+		defer f.Close()
+		body = f
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fh.Filename)), ".")
+		}
+	} else if format == "" {
+		format = transferFormat(ct)
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	rep, err := h.svc.Import(r.Context(), body, format, dryRun, actor)
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"error": "file_too_large"})
+			return
+		}
+		writeAdminError(w, err)
+		return
+	}
+	if len(rep.Errors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, rep)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+func transferFormat(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return FormatCSV
+	case "application/json":
+		return FormatJSON
+	}
+	return ""
+}
+
+func (h *Handler) exportCatalog(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatCSV
+	}
+	if format != FormatCSV && format != FormatJSON {
+		writeAdminError(w, ErrInvalidFormat)
+		return
+	}
+
+	// buffered so a failed query can still answer with an error status
+	var buf bytes.Buffer
+	if err := h.svc.Export(r.Context(), &buf, format); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if format == FormatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catalog-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
 func parseInt(s string, def int) int {
 	if s == "" {
 		return def
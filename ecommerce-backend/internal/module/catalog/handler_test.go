@@ -3,29 +3,213 @@ package catalog
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
 
 type fakeRepo struct {
-	listFn func(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error)
-	getFn  func(ctx context.Context, slug string) (*ProductDetail, error)
+	listFn   func(ctx context.Context, p ListParams) ([]ProductListItem, error)
+	facetsFn func(ctx context.Context, p ListParams) (*Facets, error)
+	searchFn func(ctx context.Context, p ListParams) ([]SearchResult, error)
+	countFn  func(ctx context.Context, p ListParams) (int, error)
+	getFn    func(ctx context.Context, slug string) (*ProductDetail, error)
+	availFn  func(ctx context.Context, variantIDs []string) (map[string]int, error)
+	pricesFn func(ctx context.Context, variantIDs []string, currency string) (map[string]CurrencyPrice, error)
+
+	createProductFn func(ctx context.Context, in ProductInput) (*Product, error)
+	updateProductFn func(ctx context.Context, productID string, in ProductInput) (*Product, error)
+	createVariantFn func(ctx context.Context, productID string, in VariantInput) (*Variant, error)
+	updateVariantFn func(ctx context.Context, variantID string, in VariantInput) (*Variant, error)
+	createOptionFn  func(ctx context.Context, productID string, in OptionInput) (*ProductOption, error)
+	addValueFn      func(ctx context.Context, optionID, value string) (*OptionValue, error)
+	setVarOptsFn    func(ctx context.Context, variantID string, options map[string]string) (map[string]string, error)
+	addImageFn      func(ctx context.Context, productID, url string, position *int) (*ProductImage, error)
+	reorderFn       func(ctx context.Context, productID string, imageIDs []string) ([]ProductImage, error)
+	removeImageFn   func(ctx context.Context, productID, imageID string) error
+
+	listCatFn     func(ctx context.Context) ([]Category, error)
+	getCatFn      func(ctx context.Context, slug string) (*Category, error)
+	createCatFn   func(ctx context.Context, in CategoryInput) (*Category, error)
+	updateCatFn   func(ctx context.Context, categoryID string, in CategoryInput) (*Category, error)
+	deleteCatFn   func(ctx context.Context, categoryID string) error
+	setProdCatsFn func(ctx context.Context, productID string, categoryIDs []string) ([]Category, error)
+
+	createSchedFn func(ctx context.Context, ps PriceSchedule) (*PriceSchedule, error)
+	cancelSchedFn func(ctx context.Context, scheduleID string) (*PriceSchedule, error)
+	dueFn         func(ctx context.Context, now time.Time) ([]PriceSchedule, error)
+	applyFn       func(ctx context.Context, scheduleID string, now time.Time) (bool, error)
+	revertFn      func(ctx context.Context, scheduleID string, now time.Time) (bool, error)
+	historyFn     func(ctx context.Context, variantID string, limit int, after *PriceHistoryCursor) ([]PricePoint, error)
+	priceAtFn     func(ctx context.Context, variantID string, at time.Time) (*PricePoint, error)
+
+	importFn func(ctx context.Context, rows []TransferRow, actor string, dryRun bool) (*ImportReport, error)
+	exportFn func(ctx context.Context) ([]TransferRow, error)
+
+	currentSlugFn func(ctx context.Context, oldSlug string) (string, error)
+	sitemapFn     func(ctx context.Context, limit int) ([]SitemapEntry, error)
+
+	relatedFn     func(ctx context.Context, slug string, limit int) ([]ProductListItem, error)
+	coPurchasedFn func(ctx context.Context, slug string, limit int) ([]CoPurchase, error)
+	rebuildFn     func(ctx context.Context, since time.Time, perProduct int) (int, error)
 }
 
-func (f fakeRepo) ListProducts(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error) {
-	return f.listFn(ctx, limit, offset, search)
+func (f fakeRepo) ListProducts(ctx context.Context, p ListParams) ([]ProductListItem, error) {
+	return f.listFn(ctx, p)
+}
+func (f fakeRepo) ProductFacets(ctx context.Context, p ListParams) (*Facets, error) {
+	if f.facetsFn == nil {
+		return &Facets{Categories: []CategoryFacet{}}, nil
+	}
+	return f.facetsFn(ctx, p)
+}
+func (f fakeRepo) CountProducts(ctx context.Context, p ListParams) (int, error) {
+	return f.countFn(ctx, p)
+}
+func (f fakeRepo) SearchProducts(ctx context.Context, p ListParams) ([]SearchResult, error) {
+	return f.searchFn(ctx, p)
 }
 func (f fakeRepo) GetProductBySlug(ctx context.Context, slug string) (*ProductDetail, error) {
 	return f.getFn(ctx, slug)
 }
+func (f fakeRepo) AvailableQty(ctx context.Context, variantIDs []string) (map[string]int, error) {
+	return f.availFn(ctx, variantIDs)
+}
+func (f fakeRepo) PricesIn(ctx context.Context, variantIDs []string, currency string) (map[string]CurrencyPrice, error) {
+	return f.pricesFn(ctx, variantIDs, currency)
+}
+func (f fakeRepo) CreateProduct(ctx context.Context, in ProductInput) (*Product, error) {
+	return f.createProductFn(ctx, in)
+}
+func (f fakeRepo) UpdateProduct(ctx context.Context, productID string, in ProductInput) (*Product, error) {
+	return f.updateProductFn(ctx, productID, in)
+}
+func (f fakeRepo) CreateVariant(ctx context.Context, productID string, in VariantInput) (*Variant, error) {
+	return f.createVariantFn(ctx, productID, in)
+}
+func (f fakeRepo) UpdateVariant(ctx context.Context, variantID string, in VariantInput) (*Variant, error) {
+	return f.updateVariantFn(ctx, variantID, in)
+}
+func (f fakeRepo) CreateOption(ctx context.Context, productID string, in OptionInput) (*ProductOption, error) {
+	return f.createOptionFn(ctx, productID, in)
+}
+func (f fakeRepo) AddOptionValue(ctx context.Context, optionID, value string) (*OptionValue, error) {
+	return f.addValueFn(ctx, optionID, value)
+}
+func (f fakeRepo) SetVariantOptions(ctx context.Context, variantID string, options map[string]string) (map[string]string, error) {
+	return f.setVarOptsFn(ctx, variantID, options)
+}
+func (f fakeRepo) AddImage(ctx context.Context, productID, url string, position *int) (*ProductImage, error) {
+	return f.addImageFn(ctx, productID, url, position)
+}
+func (f fakeRepo) ReorderImages(ctx context.Context, productID string, imageIDs []string) ([]ProductImage, error) {
+	return f.reorderFn(ctx, productID, imageIDs)
+}
+func (f fakeRepo) RemoveImage(ctx context.Context, productID, imageID string) error {
+	return f.removeImageFn(ctx, productID, imageID)
+}
+func (f fakeRepo) CurrentSlug(ctx context.Context, oldSlug string) (string, error) {
+	if f.currentSlugFn == nil {
+		return "", ErrNotFound
+	}
+	return f.currentSlugFn(ctx, oldSlug)
+}
+func (f fakeRepo) SitemapEntries(ctx context.Context, limit int) ([]SitemapEntry, error) {
+	return f.sitemapFn(ctx, limit)
+}
+func (f fakeRepo) RelatedProducts(ctx context.Context, slug string, limit int) ([]ProductListItem, error) {
+	return f.relatedFn(ctx, slug, limit)
+}
+func (f fakeRepo) CoPurchased(ctx context.Context, slug string, limit int) ([]CoPurchase, error) {
+	return f.coPurchasedFn(ctx, slug, limit)
+}
+func (f fakeRepo) RebuildCoPurchases(ctx context.Context, since time.Time, perProduct int) (int, error) {
+	return f.rebuildFn(ctx, since, perProduct)
+}
+func (f fakeRepo) ListCategories(ctx context.Context) ([]Category, error) {
+	return f.listCatFn(ctx)
+}
+func (f fakeRepo) GetCategoryBySlug(ctx context.Context, slug string) (*Category, error) {
+	return f.getCatFn(ctx, slug)
+}
+func (f fakeRepo) CreateCategory(ctx context.Context, in CategoryInput) (*Category, error) {
+	return f.createCatFn(ctx, in)
+}
+func (f fakeRepo) UpdateCategory(ctx context.Context, categoryID string, in CategoryInput) (*Category, error) {
+	return f.updateCatFn(ctx, categoryID, in)
+}
+func (f fakeRepo) DeleteCategory(ctx context.Context, categoryID string) error {
+	return f.deleteCatFn(ctx, categoryID)
+}
+func (f fakeRepo) SetProductCategories(ctx context.Context, productID string, categoryIDs []string) ([]Category, error) {
+	return f.setProdCatsFn(ctx, productID, categoryIDs)
+}
+
+func (f fakeRepo) CreatePriceSchedule(ctx context.Context, ps PriceSchedule) (*PriceSchedule, error) {
+	return f.createSchedFn(ctx, ps)
+}
+func (f fakeRepo) ListPriceSchedules(ctx context.Context, variantID string) ([]PriceSchedule, error) {
+	return nil, nil
+}
+func (f fakeRepo) CancelPriceSchedule(ctx context.Context, scheduleID string) (*PriceSchedule, error) {
+	return f.cancelSchedFn(ctx, scheduleID)
+}
+func (f fakeRepo) DuePriceSchedules(ctx context.Context, now time.Time) ([]PriceSchedule, error) {
+	return f.dueFn(ctx, now)
+}
+func (f fakeRepo) ApplyPriceSchedule(ctx context.Context, scheduleID string, now time.Time) (bool, error) {
+	return f.applyFn(ctx, scheduleID, now)
+}
+func (f fakeRepo) RevertPriceSchedule(ctx context.Context, scheduleID string, now time.Time) (bool, error) {
+	return f.revertFn(ctx, scheduleID, now)
+}
+func (f fakeRepo) ListPriceHistory(ctx context.Context, variantID string, limit int, after *PriceHistoryCursor) ([]PricePoint, error) {
+	return f.historyFn(ctx, variantID, limit, after)
+}
+func (f fakeRepo) PriceAt(ctx context.Context, variantID string, at time.Time) (*PricePoint, error) {
+	return f.priceAtFn(ctx, variantID, at)
+}
+
+func (f fakeRepo) ImportRows(ctx context.Context, rows []TransferRow, actor string, dryRun bool) (*ImportReport, error) {
+	return f.importFn(ctx, rows, actor, dryRun)
+}
+
+func (f fakeRepo) ExportRows(ctx context.Context) ([]TransferRow, error) {
+	return f.exportFn(ctx)
+}
+
+func adminRouter(t *testing.T, h *Handler) (chi.Router, string) {
+	t.Helper()
+	secret := []byte("secret")
+	token, err := httpx.SignJWTWithRole("u-admin", "admin", secret, time.Hour)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(httpx.AuthMiddleware(secret))
+	r.Use(httpx.RequireRole("admin"))
+	h.AdminRoutes(r)
+	return r, token
+}
+
+func adminDo(r chi.Router, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
 
 func TestCatalog_ListProducts_OK(t *testing.T) {
 	repo := fakeRepo{
-		listFn: func(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error) {
+		listFn: func(ctx context.Context, p ListParams) ([]ProductListItem, error) {
 			return []ProductListItem{
 				{ID: "1", Slug: "abc", Name: "ABC", MinPrice: 1000, MaxPrice: 2000, ImageURL: "x"},
 			}, nil
@@ -36,7 +220,7 @@ func TestCatalog_ListProducts_OK(t *testing.T) {
 	}
 
 	svc := NewService(repo)
-	h := NewHandler(svc)
+	h := NewHandler(svc, "")
 
 	r := chi.NewRouter()
 	h.Routes(r)
@@ -54,7 +238,7 @@ func TestCatalog_ListProducts_OK(t *testing.T) {
 
 func TestCatalog_GetProduct_404(t *testing.T) {
 	repo := fakeRepo{
-		listFn: func(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error) {
+		listFn: func(ctx context.Context, p ListParams) ([]ProductListItem, error) {
 			return nil, nil
 		},
 		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
@@ -63,7 +247,7 @@ func TestCatalog_GetProduct_404(t *testing.T) {
 	}
 
 	svc := NewService(repo)
-	h := NewHandler(svc)
+	h := NewHandler(svc, "")
 
 	r := chi.NewRouter()
 	h.Routes(r)
@@ -74,3 +258,797 @@ func TestCatalog_GetProduct_404(t *testing.T) {
 
 	require.Equal(t, http.StatusNotFound, rec.Code)
 }
+
+func TestCatalog_GetProduct_IncludeAvailability(t *testing.T) {
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
+			return &ProductDetail{ID: "p-1", Slug: slug, Variants: []ProductVariant{
+				{ID: "v-1", SKU: "A"},
+				{ID: "v-2", SKU: "B"},
+			}}, nil
+		},
+		availFn: func(ctx context.Context, variantIDs []string) (map[string]int, error) {
+			return map[string]int{"v-1": 4}, nil
+		},
+	}
+
+	svc := NewService(repo)
+	h := NewHandler(svc, "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/products/abc?include=availability", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out ProductDetail
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Len(t, out.Variants, 2)
+	require.Equal(t, 4, out.Variants[0].Availability.Available)
+	require.False(t, out.Variants[1].Availability.InStock)
+}
+
+func TestCatalog_GetProduct_Currency(t *testing.T) {
+	compare := int64(2500)
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
+			return &ProductDetail{ID: "p-1", Slug: slug, Variants: []ProductVariant{
+				{ID: "v-1", SKU: "A", Price: 300000},
+				{ID: "v-2", SKU: "B", Price: 350000},
+			}}, nil
+		},
+		pricesFn: func(ctx context.Context, variantIDs []string, currency string) (map[string]CurrencyPrice, error) {
+			require.Equal(t, "USD", currency)
+			return map[string]CurrencyPrice{"v-1": {Price: 1999, CompareAtPrice: &compare}}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), "").Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/products/abc?currency=usd", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out ProductDetail
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, "USD", out.Currency)
+	require.Equal(t, int64(1999), out.Variants[0].Price)
+	require.True(t, out.Variants[0].OnSale)
+	require.Equal(t, 20, out.Variants[0].DiscountPercent)
+	require.True(t, out.Variants[1].PriceUnavailable)
+	require.Zero(t, out.Variants[1].Price)
+
+	req = httptest.NewRequest(http.MethodGet, "/products/abc?currency=rupiah", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdmin_CreateProduct_DerivesSlug(t *testing.T) {
+	repo := fakeRepo{
+		createProductFn: func(ctx context.Context, in ProductInput) (*Product, error) {
+			require.Equal(t, "kaos-polos-hitam", *in.Slug)
+			return &Product{ID: "p-1", Slug: *in.Slug, Name: *in.Name, IsActive: true}, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodPost, "/admin/products", `{"name":"  Kaos Polos (Hitam) "}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestAdmin_CreateProduct_403_NotAdmin(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}), "")
+	secret := []byte("secret")
+	token, err := httpx.SignJWT("u-1", secret, time.Hour)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(httpx.AuthMiddleware(secret))
+	r.Use(httpx.RequireRole("admin"))
+	h.AdminRoutes(r)
+
+	rec := adminDo(r, token, http.MethodPost, "/admin/products", `{"name":"X"}`)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAdmin_CreateVariant_409_SKUTaken(t *testing.T) {
+	repo := fakeRepo{
+		createVariantFn: func(ctx context.Context, productID string, in VariantInput) (*Variant, error) {
+			require.Equal(t, "p-1", productID)
+			return nil, ErrSKUTaken
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodPost, "/admin/products/p-1/variants", `{"sku":"TS-BLK-M","name":"M","price":99000}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "sku_taken")
+}
+
+func TestAdmin_DeactivateVariant(t *testing.T) {
+	repo := fakeRepo{
+		updateVariantFn: func(ctx context.Context, variantID string, in VariantInput) (*Variant, error) {
+			require.False(t, *in.IsActive)
+			require.Nil(t, in.Price)
+			return &Variant{ID: variantID}, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodPost, "/admin/variants/v-1/deactivate", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdmin_ReorderImages_400_Duplicate(t *testing.T) {
+	r, token := adminRouter(t, NewHandler(NewService(fakeRepo{}), ""))
+
+	rec := adminDo(r, token, http.MethodPut, "/admin/products/p-1/images/order", `{"image_ids":["a","b","a"]}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdmin_RemoveImage_204(t *testing.T) {
+	repo := fakeRepo{
+		removeImageFn: func(ctx context.Context, productID, imageID string) error {
+			require.Equal(t, "p-1", productID)
+			require.Equal(t, "img-1", imageID)
+			return nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodDelete, "/admin/products/p-1/images/img-1", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestCatalog_ListCategories_Tree(t *testing.T) {
+	root := "c-1"
+	repo := fakeRepo{
+		listCatFn: func(ctx context.Context) ([]Category, error) {
+			return []Category{
+				{ID: "c-1", Slug: "fashion", Name: "Fashion"},
+				{ID: "c-2", ParentID: &root, Slug: "kaos", Name: "Kaos"},
+			}, nil
+		},
+	}
+	h := NewHandler(NewService(repo), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/categories", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Items []Category `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Items, 1)
+	require.Len(t, body.Items[0].Children, 1)
+	require.Equal(t, "kaos", body.Items[0].Children[0].Slug)
+}
+
+func TestCatalog_CategoryProducts_404(t *testing.T) {
+	repo := fakeRepo{
+		getCatFn: func(ctx context.Context, slug string) (*Category, error) {
+			return nil, ErrNotFound
+		},
+	}
+	h := NewHandler(NewService(repo), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/categories/nope/products", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAdmin_UpdateCategory_400_SelfParent(t *testing.T) {
+	r, token := adminRouter(t, NewHandler(NewService(fakeRepo{}), ""))
+
+	rec := adminDo(r, token, http.MethodPatch, "/admin/categories/c-1", `{"parent_id":"c-1"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "invalid_parent")
+}
+
+func TestAdmin_SetProductCategories_Dedupes(t *testing.T) {
+	repo := fakeRepo{
+		setProdCatsFn: func(ctx context.Context, productID string, categoryIDs []string) ([]Category, error) {
+			require.Equal(t, []string{"c-1", "c-2"}, categoryIDs)
+			return []Category{}, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodPut, "/admin/products/p-1/categories", `{"category_ids":["c-1","c-2","c-1"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCatalog_ListProducts_FiltersAndFacets(t *testing.T) {
+	repo := fakeRepo{
+		listFn: func(ctx context.Context, p ListParams) ([]ProductListItem, error) {
+			require.Equal(t, int64(10000), *p.MinPrice)
+			require.Nil(t, p.MaxPrice)
+			require.Equal(t, "kaos", p.Category)
+			require.True(t, p.InStock)
+			require.False(t, p.OnSale)
+			require.Equal(t, SortPriceAsc, p.Sort)
+			return []ProductListItem{}, nil
+		},
+		facetsFn: func(ctx context.Context, p ListParams) (*Facets, error) {
+			return &Facets{Price: PriceFacet{Min: 10000, Max: 50000}, InStock: 3, Categories: []CategoryFacet{}}, nil
+		},
+	}
+	h := NewHandler(NewService(repo), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/products?min_price=10000&category=kaos&in_stock=true&sort=price_asc", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Facets Facets `json:"facets"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, int64(50000), body.Facets.Price.Max)
+	require.Equal(t, 3, body.Facets.InStock)
+}
+
+func TestCatalog_ListProducts_400_BadSort(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	for _, q := range []string{"sort=popular", "min_price=abc", "min_price=500&max_price=100"} {
+		req := httptest.NewRequest(http.MethodGet, "/products?"+q, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestCatalog_Search_OK(t *testing.T) {
+	repo := fakeRepo{
+		searchFn: func(ctx context.Context, p ListParams) ([]SearchResult, error) {
+			require.Equal(t, "kaos hitam", p.Search)
+			require.Equal(t, SortRelevance, p.Sort)
+			require.True(t, p.OnSale)
+			return []SearchResult{{
+				ProductListItem: ProductListItem{ID: "p-1", Slug: "kaos-polos", Name: "Kaos Polos"},
+				Score:           1.2,
+				Match:           MatchFullText,
+				Snippet:         "<mark>Kaos</mark> Polos",
+			}}, nil
+		},
+	}
+	h := NewHandler(NewService(repo), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=+kaos+hitam+&on_sale=true&sort=name", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Items []map[string]any `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Items, 1)
+	require.Equal(t, "kaos-polos", body.Items[0]["slug"])
+	require.Equal(t, "fulltext", body.Items[0]["match"])
+}
+
+func TestCatalog_Search_400_EmptyQuery(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=%20", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCatalog_ListProducts_CursorPaging(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 600000, time.UTC)
+	repo := fakeRepo{
+		listFn: func(ctx context.Context, p ListParams) ([]ProductListItem, error) {
+			require.Equal(t, 3, p.Limit) // limit+1 probe row
+			if p.After == nil {
+				return []ProductListItem{
+					{ID: "p-3", CreatedAt: created.Add(2 * time.Second)},
+					{ID: "p-2", CreatedAt: created},
+					{ID: "p-1", CreatedAt: created.Add(-time.Second)},
+				}, nil
+			}
+			require.Equal(t, "p-2", p.After.ID)
+			require.True(t, created.Equal(*p.After.CreatedAt))
+			return []ProductListItem{{ID: "p-1", CreatedAt: created.Add(-time.Second)}}, nil
+		},
+		countFn: func(ctx context.Context, p ListParams) (int, error) {
+			require.Nil(t, p.After)
+			return 3, nil
+		},
+	}
+	h := NewHandler(NewService(repo), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	type page struct {
+		Items      []ProductListItem `json:"items"`
+		HasMore    bool              `json:"has_more"`
+		NextCursor string            `json:"next_cursor"`
+		Total      *int              `json:"total"`
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/products?limit=2&include=total", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var first page
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &first))
+	require.Len(t, first.Items, 2)
+	require.True(t, first.HasMore)
+	require.NotEmpty(t, first.NextCursor)
+	require.Equal(t, 3, *first.Total)
+
+	req = httptest.NewRequest(http.MethodGet, "/products?limit=2&cursor="+first.NextCursor, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var second page
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &second))
+	require.Len(t, second.Items, 1)
+	require.False(t, second.HasMore)
+	require.Empty(t, second.NextCursor)
+	require.Nil(t, second.Total)
+
+	// a cursor minted for one sort order is rejected under another
+	req = httptest.NewRequest(http.MethodGet, "/products?sort=name&cursor="+first.NextCursor, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "invalid_cursor")
+}
+
+func TestCatalog_FindVariant(t *testing.T) {
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
+			return &ProductDetail{
+				ID:   "p-1",
+				Slug: slug,
+				Options: []ProductOption{
+					{Name: "Size", Values: []OptionValue{{Value: "M"}, {Value: "L"}}},
+					{Name: "Color", Values: []OptionValue{{Value: "Black"}}},
+				},
+				Variants: []ProductVariant{
+					{ID: "v-m", Active: true, Options: map[string]string{"Size": "M", "Color": "Black"}},
+					{ID: "v-l", Active: false, Options: map[string]string{"Size": "L", "Color": "Black"}},
+				},
+			}, nil
+		},
+	}
+	h := NewHandler(NewService(repo), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	cases := []struct {
+		query string
+		code  int
+	}{
+		{"size=m&color=black", http.StatusOK},
+		{"size=m&color=black&utm_source=newsletter", http.StatusOK},
+		{"Size=L&Color=Black", http.StatusNotFound}, // inactive
+		{"Size=M", http.StatusBadRequest},
+		{"Size=M&Material=Cotton", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/products/kaos/variant?"+tc.query, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, tc.code, rec.Code, tc.query)
+	}
+}
+
+func TestCatalog_FindVariant_500_RepoError(t *testing.T) {
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
+			return nil, errors.New("connection reset")
+		},
+	}
+	h := NewHandler(NewService(repo), "")
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/products/kaos/variant?size=m", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Contains(t, rec.Body.String(), "internal_error")
+}
+
+func TestAdmin_SetVariantOptions_409_Combination(t *testing.T) {
+	repo := fakeRepo{
+		setVarOptsFn: func(ctx context.Context, variantID string, options map[string]string) (map[string]string, error) {
+			require.Equal(t, map[string]string{"Size": "M"}, options)
+			return nil, ErrCombinationTaken
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodPut, "/admin/variants/v-1/options", `{"options":{"Size":"M"}}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "combination_taken")
+}
+
+func TestAdmin_CreateOption_400_DuplicateValues(t *testing.T) {
+	r, token := adminRouter(t, NewHandler(NewService(fakeRepo{}), ""))
+
+	rec := adminDo(r, token, http.MethodPost, "/admin/products/p-1/options", `{"name":"Size","values":["M","m"]}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCatalog_Admin_SchedulePrice(t *testing.T) {
+	var got PriceSchedule
+	repo := fakeRepo{
+		createSchedFn: func(ctx context.Context, ps PriceSchedule) (*PriceSchedule, error) {
+			got = ps
+			ps.ID, ps.Status = "ps1", SchedulePending
+			return &ps, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodPost, "/admin/variants/v1/price-schedules",
+		`{"price":80000,"compare_at_price":100000,"starts_at":"2026-11-27T07:00:00+07:00","ends_at":"2026-11-30T07:00:00+07:00"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "v1", got.VariantID)
+	require.Equal(t, "u-admin", got.CreatedBy)
+	require.Equal(t, int64(80000), got.Price)
+	// both ends of the window are stored in UTC
+	require.Equal(t, time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC), got.StartsAt)
+	require.NotNil(t, got.EndsAt)
+	require.Equal(t, time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC), *got.EndsAt)
+
+	// window must end after it starts
+	rec = adminDo(r, token, http.MethodPost, "/admin/variants/v1/price-schedules",
+		`{"price":80000,"starts_at":"2026-11-30T00:00:00Z","ends_at":"2026-11-27T00:00:00Z"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	repo.createSchedFn = func(ctx context.Context, ps PriceSchedule) (*PriceSchedule, error) {
+		return nil, ErrScheduleConflict
+	}
+	r, token = adminRouter(t, NewHandler(NewService(repo), ""))
+	rec = adminDo(r, token, http.MethodPost, "/admin/variants/v1/price-schedules",
+		`{"price":80000,"starts_at":"2026-11-28T00:00:00Z"}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "schedule_conflict")
+}
+
+func TestCatalog_Admin_CancelPriceSchedule_NotPending(t *testing.T) {
+	repo := fakeRepo{
+		cancelSchedFn: func(ctx context.Context, scheduleID string) (*PriceSchedule, error) {
+			return nil, ErrScheduleNotPending
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodDelete, "/admin/price-schedules/ps1", "")
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestCatalog_Admin_PriceHistory(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	var gotAt time.Time
+	repo := fakeRepo{
+		historyFn: func(ctx context.Context, variantID string, limit int, after *PriceHistoryCursor) ([]PricePoint, error) {
+			require.Equal(t, 3, limit) // limit+1
+			return []PricePoint{
+				{ID: 3, VariantID: variantID, Price: 80000, Source: "schedule", ChangedAt: t0.Add(48 * time.Hour)},
+				{ID: 2, VariantID: variantID, Price: 95000, Source: "manual", ChangedAt: t0.Add(24 * time.Hour)},
+				{ID: 1, VariantID: variantID, Price: 100000, Source: "backfill", ChangedAt: t0},
+			}, nil
+		},
+		priceAtFn: func(ctx context.Context, variantID string, at time.Time) (*PricePoint, error) {
+			gotAt = at
+			return &PricePoint{ID: 2, VariantID: variantID, Price: 95000, Source: "manual", ChangedAt: t0.Add(24 * time.Hour)}, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodGet, "/admin/variants/v1/price-history?limit=2", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page struct {
+		Items      []PricePoint `json:"items"`
+		HasMore    bool         `json:"has_more"`
+		NextCursor string       `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Items, 2)
+	require.True(t, page.HasMore)
+	require.NotEmpty(t, page.NextCursor)
+
+	rec = adminDo(r, token, http.MethodGet, "/admin/variants/v1/price-history?at=2026-10-02T12:00:00Z", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, t0.Add(36*time.Hour), gotAt)
+	require.Contains(t, rec.Body.String(), `"price":95000`)
+
+	rec = adminDo(r, token, http.MethodGet, "/admin/variants/v1/price-history?at=yesterday", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCatalog_Admin_Import(t *testing.T) {
+	var got []TransferRow
+	var gotDryRun bool
+	repo := fakeRepo{
+		importFn: func(ctx context.Context, rows []TransferRow, actor string, dryRun bool) (*ImportReport, error) {
+			require.Equal(t, "u-admin", actor)
+			got, gotDryRun = rows, dryRun
+			return &ImportReport{DryRun: dryRun, Rows: len(rows), ProductsCreated: 1, VariantsCreated: len(rows), Errors: []RowError{}}, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	csvBody := "product_slug,sku,product_name,variant_name,price,options,stock\n" +
+		",TEE-M,Basic Tee,M,99000,Size=M,MAIN=5\n" +
+		"basic-tee,TEE-L,,L,99000,Size=L,MAIN=3\n"
+	rec := adminDo(r, token, http.MethodPost, "/admin/catalog/import?format=csv&dry_run=true", csvBody)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, gotDryRun)
+	require.Len(t, got, 2)
+	require.Equal(t, "basic-tee", got[1].ProductSlug)
+	require.Equal(t, "Basic Tee", got[1].ProductName)
+	require.Equal(t, map[string]int{"MAIN": 3}, got[1].Stock)
+
+	// validation errors never reach the repository
+	got = nil
+	rec = adminDo(r, token, http.MethodPost, "/admin/catalog/import?format=csv",
+		"sku,product_name,variant_name,price\nTEE-M,Basic Tee,M,abc\nTEE-M,Basic Tee,M,1\n")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Nil(t, got)
+	var rep ImportReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rep))
+	require.False(t, rep.Applied)
+	require.Len(t, rep.Errors, 2)
+	require.Equal(t, "price", rep.Errors[0].Field)
+	require.Equal(t, 2, rep.Errors[1].Row)
+
+	rec = adminDo(r, token, http.MethodPost, "/admin/catalog/import?format=xml", "<x/>")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "invalid_format")
+}
+
+func TestCatalog_Admin_Import_413_TooLarge(t *testing.T) {
+	repo := fakeRepo{
+		importFn: func(ctx context.Context, rows []TransferRow, actor string, dryRun bool) (*ImportReport, error) {
+			t.Fatal("an oversized file must not reach the repository")
+			return nil, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	body := "[" + strings.Repeat(" ", maxImportBytes) + "]"
+	rec := adminDo(r, token, http.MethodPost, "/admin/catalog/import?format=json", body)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	require.Contains(t, rec.Body.String(), "file_too_large")
+}
+
+func TestCatalog_Admin_Export(t *testing.T) {
+	price, active := int64(99000), true
+	repo := fakeRepo{
+		exportFn: func(ctx context.Context) ([]TransferRow, error) {
+			return []TransferRow{{
+				ProductSlug: "basic-tee", ProductName: "Basic Tee", ProductActive: &active,
+				Categories: []string{"apparel/tops"}, Images: []string{},
+				SKU: "TEE-M", VariantName: "M", Price: &price, VariantActive: &active,
+				Options: map[string]string{"Size": "M"}, Stock: map[string]int{"MAIN": 5},
+			}}, nil
+		},
+	}
+	r, token := adminRouter(t, NewHandler(NewService(repo), ""))
+
+	rec := adminDo(r, token, http.MethodGet, "/admin/catalog/export", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Disposition"), ".csv")
+	require.Contains(t, rec.Body.String(), "basic-tee,Basic Tee,,true,apparel/tops,,TEE-M,M,99000,,,true,Size=M,MAIN=5")
+}
+
+func TestCatalog_ListProducts_RatingSort(t *testing.T) {
+	repo := fakeRepo{
+		listFn: func(ctx context.Context, p ListParams) ([]ProductListItem, error) {
+			require.Equal(t, SortRating, p.Sort)
+			require.Equal(t, 4.0, *p.MinRating)
+			if p.After == nil {
+				return []ProductListItem{
+					{ID: "p-1", RatingAvg: 4.8, RatingCount: 12},
+					{ID: "p-2", RatingAvg: 4.5, RatingCount: 3},
+				}, nil
+			}
+			require.Equal(t, "p-1", p.After.ID)
+			require.Equal(t, 4.8, *p.After.Rating)
+			require.Equal(t, 12, *p.After.Reviews)
+			return nil, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), "").Routes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products?sort=rating&min_rating=4&limit=1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"rating_avg":4.8`)
+	var first struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &first))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products?sort=rating&min_rating=4&limit=1&cursor="+first.NextCursor, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products?min_rating=6", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCatalog_RelatedProducts(t *testing.T) {
+	repo := fakeRepo{
+		relatedFn: func(ctx context.Context, slug string, limit int) ([]ProductListItem, error) {
+			if slug == "gone" {
+				return nil, ErrNotFound
+			}
+			require.Equal(t, "kaos", slug)
+			require.Equal(t, 24, limit) // capped
+			return []ProductListItem{{ID: "p-2", Slug: "kemeja"}}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), "").Routes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/kaos/related?limit=100", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"slug":"kemeja"`)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/gone/related", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCatalog_FrequentlyBoughtTogether(t *testing.T) {
+	repo := fakeRepo{
+		coPurchasedFn: func(ctx context.Context, slug string, limit int) ([]CoPurchase, error) {
+			require.Equal(t, "kaos", slug)
+			require.Equal(t, 8, limit)
+			return []CoPurchase{{ProductListItem: ProductListItem{ID: "p-3", Slug: "topi"}, Orders: 7}}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), "").Routes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/kaos/frequently-bought-together", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out struct {
+		Items []CoPurchase `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Len(t, out.Items, 1)
+	require.Equal(t, "topi", out.Items[0].Slug)
+	require.Equal(t, 7, out.Items[0].Orders)
+}
+
+func TestCatalog_GetProduct_SEODefaults(t *testing.T) {
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
+			return &ProductDetail{ID: "p-1", Slug: slug, Name: "Kaos Polos", Description: "Kaos\nkatun combed."}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), "https://shop.example.com/").Routes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/kaos-polos", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out ProductDetail
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, SEO{
+		Title:        "Kaos Polos",
+		Description:  "Kaos katun combed.",
+		CanonicalURL: "https://shop.example.com/product/kaos-polos",
+	}, out.SEO)
+}
+
+func TestCatalog_GetProduct_301_RenamedSlug(t *testing.T) {
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
+			return nil, ErrNotFound
+		},
+		currentSlugFn: func(ctx context.Context, oldSlug string) (string, error) {
+			if oldSlug == "kaos-lama" {
+				return "kaos-baru", nil
+			}
+			return "", ErrNotFound
+		},
+		relatedFn: func(ctx context.Context, slug string, limit int) ([]ProductListItem, error) {
+			return nil, ErrNotFound
+		},
+	}
+	r := chi.NewRouter()
+	r.Route("/v1", func(v1 chi.Router) { NewHandler(NewService(repo), "").Routes(v1) })
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/products/kaos-lama?include=availability", nil))
+	require.Equal(t, http.StatusMovedPermanently, rec.Code)
+	require.Equal(t, "/v1/products/kaos-baru?include=availability", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/products/kaos-lama/related", nil))
+	require.Equal(t, http.StatusMovedPermanently, rec.Code)
+	require.Equal(t, "/v1/products/kaos-baru/related", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/products/never-existed", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCatalog_Sitemap(t *testing.T) {
+	updated := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	repo := fakeRepo{
+		sitemapFn: func(ctx context.Context, limit int) ([]SitemapEntry, error) {
+			return []SitemapEntry{
+				{Kind: "product", Slug: "kaos-polos", UpdatedAt: updated},
+				{Kind: "product", Slug: "kemeja", CanonicalURL: "https://brand.example.com/kemeja", UpdatedAt: updated},
+				{Kind: "category", Slug: "fashion", UpdatedAt: updated},
+			}, nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo), "https://shop.example.com").Routes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	require.Contains(t, body, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	require.Contains(t, body, "<url><loc>https://shop.example.com/catalog?cat=fashion</loc><lastmod>2026-10-01</lastmod></url>")
+	require.Contains(t, body, "<loc>https://shop.example.com/product/kaos-polos</loc>")
+	require.Contains(t, body, "<loc>https://brand.example.com/kemeja</loc>")
+	require.Less(t, strings.Index(body, "fashion"), strings.Index(body, "kaos-polos"))
+}
+
+func TestAdmin_UpdateProduct_400_RelativeCanonical(t *testing.T) {
+	r, token := adminRouter(t, NewHandler(NewService(fakeRepo{}), ""))
+
+	rec := adminDo(r, token, http.MethodPatch, "/admin/products/p-1", `{"canonical_url":"/product/kaos"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -1,25 +1,138 @@
 package catalog
 
+import "time"
+
 type ProductListItem struct {
-	ID          string `json:"id"`
-	Slug        string `json:"slug"`
-	Name        string `json:"name"`
-	MinPrice    int64  `json:"min_price"`
-	MaxPrice    int64  `json:"max_price"`
-	ImageURL    string `json:"image_url,omitempty"`
-	IsActive    bool   `json:"-"`
+	ID       string `json:"id"`
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+	MinPrice int64  `json:"min_price"`
+	MaxPrice int64  `json:"max_price"`
+	// compare-at of the cheapest variant; OnSale/DiscountPercent cover any variant
+	CompareAtPrice  *int64 `json:"compare_at_price,omitempty"`
+	OnSale          bool   `json:"on_sale"`
+	DiscountPercent int    `json:"discount_percent"`
+	ImageURL        string `json:"image_url,omitempty"`
+	IsActive        bool   `json:"-"`
+	// approved reviews only; 0/0 when unreviewed
+	RatingAvg   float64 `json:"rating_avg"`
+	RatingCount int     `json:"rating_count"`
+
+	CreatedAt time.Time `json:"-"` // keyset for SortNewest
+}
+
+// Sort options for product listings.
+const (
+	SortNewest    = "newest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortName      = "name"
+	SortRating    = "rating" // best rated first, ties by review count
+
+	// SortRelevance is implied by /search and not accepted on listings.
+	SortRelevance = "relevance"
+)
+
+// ListParams filters a product listing. Zero values mean "no filter".
+type ListParams struct {
+	Limit     int
+	Cursor    string // opaque next_cursor from the previous page
+	Search    string
+	Category  string // slug; includes descendant categories
+	MinPrice  *int64 // any active variant priced within [MinPrice, MaxPrice]
+	MaxPrice  *int64
+	InStock   bool
+	OnSale    bool // any active variant with compare_at_price > price
+	MinRating *float64
+	Sort      string
+
+	WithTotal bool
+	After     *ProductCursor // decoded Cursor, set by the service
+}
+
+// ProductCursor carries the last row's sort key; only the field matching Sort is set.
+type ProductCursor struct {
+	Sort      string     `json:"s"`
+	CreatedAt *time.Time `json:"t,omitempty"`
+	Price     *int64     `json:"p,omitempty"`
+	Name      *string    `json:"n,omitempty"`
+	Score     *float64   `json:"r,omitempty"`
+	Rating    *float64   `json:"ra,omitempty"`
+	Reviews   *int       `json:"rc,omitempty"`
+	ID        string     `json:"id"`
+}
+
+// How a search result matched.
+const (
+	MatchFullText = "fulltext"
+	MatchFuzzy    = "fuzzy"
+)
+
+type SearchResult struct {
+	ProductListItem
+	Score   float64 `json:"score"`
+	Match   string  `json:"match"`
+	Snippet string  `json:"snippet"` // name + description with <mark> highlights
+}
+
+// Facets are computed over the filtered set, each ignoring its own filter so the
+// sidebar can show what toggling it would yield.
+type Facets struct {
+	Price      PriceFacet      `json:"price"`
+	InStock    int             `json:"in_stock"`
+	OnSale     int             `json:"on_sale"`
+	Categories []CategoryFacet `json:"categories"`
+}
+
+type PriceFacet struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+type CategoryFacet struct {
+	ID    string `json:"id"`
+	Slug  string `json:"slug"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
 }
 
 type ProductDetail struct {
-	ID          string          `json:"id"`
-	Slug        string          `json:"slug"`
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Images      []ProductImage  `json:"images"`
+	ID          string           `json:"id"`
+	Slug        string           `json:"slug"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Images      []ProductImage   `json:"images"`
+	Options     []ProductOption  `json:"options"`
 	Variants    []ProductVariant `json:"variants"`
+	// root→leaf path of the product's deepest category; empty when uncategorised
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs"`
+	RatingAvg   float64      `json:"rating_avg"`
+	RatingCount int          `json:"rating_count"`
+	SEO         SEO          `json:"seo"`
+
+	// only set with ?currency=; variant prices are then in that currency
+	Currency string `json:"currency,omitempty"`
+}
+
+// SEO is what a storefront puts in <head>. Title and Description fall back
+// to the product's name and description when no override is stored;
+// CanonicalURL falls back to the storefront product page.
+type SEO struct {
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	CanonicalURL string `json:"canonical_url"`
+}
+
+// SitemapEntry is a storefront page listed in sitemap.xml.
+type SitemapEntry struct {
+	Kind         string // "product" or "category"
+	Slug         string
+	CanonicalURL string // product override, if any
+	UpdatedAt    time.Time
 }
 
 type ProductImage struct {
+	ID       string `json:"id"`
 	URL      string `json:"url"`
 	Position int    `json:"position"`
 }
@@ -30,4 +143,195 @@ type ProductVariant struct {
 	Name   string `json:"name"`
 	Price  int64  `json:"price"`
 	Active bool   `json:"active"`
+
+	CompareAtPrice  *int64 `json:"compare_at_price,omitempty"`
+	DiscountPercent int    `json:"discount_percent"`
+	OnSale          bool   `json:"on_sale"`
+	WeightGrams     *int   `json:"weight_grams,omitempty"`
+
+	// option name -> value, e.g. {"Size":"M","Color":"Black"}
+	Options map[string]string `json:"options,omitempty"`
+
+	InventoryPolicy string     `json:"inventory_policy"` // deny/backorder/preorder
+	PreorderShipsAt *time.Time `json:"preorder_ships_at,omitempty"`
+
+	// only set with ?include=availability
+	Availability *AvailabilitySummary `json:"availability,omitempty"`
+
+	// with ?currency=: the variant has no price in that currency and can't be bought
+	PriceUnavailable bool `json:"price_unavailable,omitempty"`
+}
+
+// CurrencyPrice is a variant price resolved from a currency's price list.
+type CurrencyPrice struct {
+	Price          int64
+	CompareAtPrice *int64
+}
+
+type Category struct {
+	ID       string     `json:"id"`
+	ParentID *string    `json:"parent_id"`
+	Slug     string     `json:"slug"`
+	Name     string     `json:"name"`
+	Children []Category `json:"children,omitempty"`
+}
+
+// CategoryInput is partial on update. ParentID "" moves the category to the root.
+type CategoryInput struct {
+	ParentID *string `json:"parent_id"`
+	Slug     *string `json:"slug"`
+	Name     *string `json:"name"`
+}
+
+type Breadcrumb struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type ProductOption struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Position int           `json:"position"`
+	Values   []OptionValue `json:"values"`
+}
+
+type OptionValue struct {
+	ID       string `json:"id"`
+	Value    string `json:"value"`
+	Position int    `json:"position"`
+}
+
+type AvailabilitySummary struct {
+	Available int  `json:"available"`
+	InStock   bool `json:"in_stock"`
+}
+
+// Admin views/inputs. Update inputs are partial: nil fields are left unchanged.
+
+type Product struct {
+	ID          string    `json:"id"`
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// SEO overrides; empty means derived (see SEO)
+	MetaTitle       string `json:"meta_title"`
+	MetaDescription string `json:"meta_description"`
+	CanonicalURL    string `json:"canonical_url"`
+}
+
+// ProductInput is partial on update. Renaming the slug keeps the old one
+// redirecting to the product.
+type ProductInput struct {
+	Slug        *string `json:"slug"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+
+	MetaTitle       *string `json:"meta_title"`
+	MetaDescription *string `json:"meta_description"`
+	CanonicalURL    *string `json:"canonical_url"` // absolute http(s) URL, "" clears
+}
+
+type Variant struct {
+	ID             string    `json:"id"`
+	ProductID      string    `json:"product_id"`
+	SKU            string    `json:"sku"`
+	Name           string    `json:"name"`
+	Price          int64     `json:"price"`
+	CompareAtPrice *int64    `json:"compare_at_price,omitempty"`
+	WeightGrams    *int      `json:"weight_grams,omitempty"`
+	IsActive       bool      `json:"is_active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type VariantInput struct {
+	SKU            *string `json:"sku"`
+	Name           *string `json:"name"`
+	Price          *int64  `json:"price"`
+	CompareAtPrice *int64  `json:"compare_at_price"`
+	WeightGrams    *int    `json:"weight_grams"`
+	IsActive       *bool   `json:"is_active"`
+
+	// Options must name exactly one value for every option of the product.
+	Options map[string]string `json:"options"`
+}
+
+type OptionInput struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Price schedule statuses (price_schedules.status).
+const (
+	SchedulePending   = "pending"
+	ScheduleActive    = "active"
+	ScheduleCompleted = "completed"
+	ScheduleCancelled = "cancelled"
+	ScheduleExpired   = "expired"
+)
+
+// PriceSchedule queues a price change. Without EndsAt it is permanent; with
+// EndsAt the previous price is restored when the window closes.
+type PriceSchedule struct {
+	ID             string     `json:"id"`
+	VariantID      string     `json:"variant_id"`
+	Price          int64      `json:"price"`
+	CompareAtPrice *int64     `json:"compare_at_price,omitempty"`
+	StartsAt       time.Time  `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	Status         string     `json:"status"`
+	CreatedBy      string     `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AppliedAt      *time.Time `json:"applied_at,omitempty"`
+	RevertedAt     *time.Time `json:"reverted_at,omitempty"`
+}
+
+type PriceScheduleInput struct {
+	Price          *int64     `json:"price"`
+	CompareAtPrice *int64     `json:"compare_at_price"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+}
+
+// PricePoint is one row of a variant's price history.
+type PricePoint struct {
+	ID             int64     `json:"-"`
+	VariantID      string    `json:"variant_id"`
+	Price          int64     `json:"price"`
+	CompareAtPrice *int64    `json:"compare_at_price,omitempty"`
+	Source         string    `json:"source"` // manual/schedule/revert/import/backfill
+	ScheduleID     *string   `json:"schedule_id,omitempty"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+type PriceHistoryCursor struct {
+	ChangedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+}
+
+// CoPurchase is a product bought in the same paid orders as another.
+type CoPurchase struct {
+	ProductListItem
+	Orders int `json:"orders"` // paid orders containing both
+}
+
+// OnSale reports whether compareAt marks price down.
+func OnSale(price int64, compareAt *int64) bool {
+	return compareAt != nil && *compareAt > price
+}
+
+// DiscountPercent is the markdown from compareAt to price, rounded down.
+func DiscountPercent(price int64, compareAt *int64) int {
+	if !OnSale(price, compareAt) {
+		return 0
+	}
+	return int((*compareAt - price) * 100 / *compareAt)
 }
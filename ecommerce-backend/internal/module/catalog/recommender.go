@@ -0,0 +1,53 @@
+package catalog
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// CoPurchaseBuilder periodically recomputes the "frequently bought together"
+// pairs from paid orders in the lookback window. A rebuild replaces the whole
+// table in one transaction, so readers never see a partial result.
+type CoPurchaseBuilder struct {
+	repo       Repository
+	interval   time.Duration
+	lookback   time.Duration
+	perProduct int
+	now        func() time.Time
+}
+
+func NewCoPurchaseBuilder(repo Repository, interval time.Duration) *CoPurchaseBuilder {
+	if interval <= 0 {
+		interval = 6 * time.Hour
+	}
+	return &CoPurchaseBuilder{
+		repo:       repo,
+		interval:   interval,
+		lookback:   180 * 24 * time.Hour,
+		perProduct: 20,
+		now:        time.Now,
+	}
+}
+
+// Run rebuilds immediately and then on every tick until ctx is done.
+func (b *CoPurchaseBuilder) Run(ctx context.Context) {
+	t := time.NewTicker(b.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := b.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("co-purchase rebuild failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce returns how many product pairs were stored.
+func (b *CoPurchaseBuilder) RunOnce(ctx context.Context) (int, error) {
+	return b.repo.RebuildCoPurchases(ctx, b.now().Add(-b.lookback), b.perProduct)
+}
@@ -1,8 +1,85 @@
 package catalog
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Repository interface {
-	ListProducts(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error)
+	// ListProducts returns up to p.Limit rows after p.After in p.Sort order.
+	ListProducts(ctx context.Context, p ListParams) ([]ProductListItem, error)
+	CountProducts(ctx context.Context, p ListParams) (int, error)
+	ProductFacets(ctx context.Context, p ListParams) (*Facets, error)
+	// SearchProducts orders by relevance; p.Search must be non-empty and p.Sort is ignored.
+	SearchProducts(ctx context.Context, p ListParams) ([]SearchResult, error)
 	GetProductBySlug(ctx context.Context, slug string) (*ProductDetail, error)
+	// CurrentSlug returns the live slug of the active product that used
+	// oldSlug before a rename, or ErrNotFound.
+	CurrentSlug(ctx context.Context, oldSlug string) (string, error)
+	// SitemapEntries lists active products (newest changes first) and
+	// categories, at most limit of each.
+	SitemapEntries(ctx context.Context, limit int) ([]SitemapEntry, error)
+
+	// RelatedProducts shares a category with the product and starts within
+	// [minPrice/2, minPrice*2] of its cheapest variant, closest price first.
+	RelatedProducts(ctx context.Context, slug string, limit int) ([]ProductListItem, error)
+	// CoPurchased reads the precomputed pairs, most shared orders first.
+	CoPurchased(ctx context.Context, slug string, limit int) ([]CoPurchase, error)
+	// RebuildCoPurchases recomputes the pairs from paid orders since since,
+	// keeping perProduct per product. Returns the number of pairs stored.
+	RebuildCoPurchases(ctx context.Context, since time.Time, perProduct int) (int, error)
+
+	// Categories are returned flat, ordered by name; the service builds the tree.
+	ListCategories(ctx context.Context) ([]Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*Category, error)
+
+	// AvailableQty returns unreserved stock across active locations per variant.
+	AvailableQty(ctx context.Context, variantIDs []string) (map[string]int, error)
+	// PricesIn resolves variant prices in currency (explicit price list, then FX
+	// fallback). Variants missing from the map can't be sold in it; an unknown
+	// or inactive currency is ErrInvalidPayload.
+	PricesIn(ctx context.Context, variantIDs []string, currency string) (map[string]CurrencyPrice, error)
+
+	// Admin
+	CreateProduct(ctx context.Context, in ProductInput) (*Product, error)
+	UpdateProduct(ctx context.Context, productID string, in ProductInput) (*Product, error)
+
+	CreateVariant(ctx context.Context, productID string, in VariantInput) (*Variant, error)
+	UpdateVariant(ctx context.Context, variantID string, in VariantInput) (*Variant, error)
+
+	CreateOption(ctx context.Context, productID string, in OptionInput) (*ProductOption, error)
+	// CreatePriceSchedule fails with ErrScheduleConflict when it overlaps an open schedule.
+	CreatePriceSchedule(ctx context.Context, ps PriceSchedule) (*PriceSchedule, error)
+	ListPriceSchedules(ctx context.Context, variantID string) ([]PriceSchedule, error)
+	CancelPriceSchedule(ctx context.Context, scheduleID string) (*PriceSchedule, error)
+	// DuePriceSchedules returns pending schedules whose start and active ones whose end is <= now.
+	DuePriceSchedules(ctx context.Context, now time.Time) ([]PriceSchedule, error)
+	// ApplyPriceSchedule / RevertPriceSchedule report false when the schedule was
+	// no longer in the expected status (another worker got there first).
+	ApplyPriceSchedule(ctx context.Context, scheduleID string, now time.Time) (bool, error)
+	RevertPriceSchedule(ctx context.Context, scheduleID string, now time.Time) (bool, error)
+
+	ListPriceHistory(ctx context.Context, variantID string, limit int, after *PriceHistoryCursor) ([]PricePoint, error)
+	PriceAt(ctx context.Context, variantID string, at time.Time) (*PricePoint, error)
+
+	AddOptionValue(ctx context.Context, optionID, value string) (*OptionValue, error)
+	// SetVariantOptions replaces the variant's option values (keyed by option name).
+	SetVariantOptions(ctx context.Context, variantID string, options map[string]string) (map[string]string, error)
+
+	// AddImage inserts at position (shifting later images) or appends when position is nil.
+	AddImage(ctx context.Context, productID, url string, position *int) (*ProductImage, error)
+	// ReorderImages assigns positions 0..n-1 in the given order; imageIDs must be exactly the product's images.
+	ReorderImages(ctx context.Context, productID string, imageIDs []string) ([]ProductImage, error)
+	RemoveImage(ctx context.Context, productID, imageID string) error
+
+	CreateCategory(ctx context.Context, in CategoryInput) (*Category, error)
+	UpdateCategory(ctx context.Context, categoryID string, in CategoryInput) (*Category, error)
+	// DeleteCategory re-parents children onto the deleted category's parent.
+	DeleteCategory(ctx context.Context, categoryID string) error
+	SetProductCategories(ctx context.Context, productID string, categoryIDs []string) ([]Category, error)
+
+	// ImportRows upserts prepared rows in one transaction that is rolled back
+	// on dry run or when any row fails. Row failures go into the report.
+	ImportRows(ctx context.Context, rows []TransferRow, actor string, dryRun bool) (*ImportReport, error)
+	ExportRows(ctx context.Context) ([]TransferRow, error)
 }
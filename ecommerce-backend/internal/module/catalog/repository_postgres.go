@@ -2,10 +2,18 @@ package catalog
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/synchhans/ecommerce-backend/internal/module/inventory"
 )
 
 type PostgresRepository struct {
@@ -16,46 +24,174 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 	return &PostgresRepository{pool: pool}
 }
 
-func (r *PostgresRepository) ListProducts(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error) {
-	if limit <= 0 || limit > 100 {
-		limit = 20
-	}
-	if offset < 0 {
-		offset = 0
+// Facet keys for productFilter's skip argument.
+const (
+	facetPrice    = "price"
+	facetCategory = "category"
+	facetInStock  = "in_stock"
+	facetOnSale   = "on_sale"
+)
+
+const inStockCond = `EXISTS (
+  SELECT 1 FROM product_variants sv
+  JOIN inventory_items ii ON ii.variant_id = sv.id
+  JOIN locations l ON l.id = ii.location_id AND l.is_active = true
+  WHERE sv.product_id = p.id AND sv.is_active = true AND stock_available(ii.stock_on_hand, ii.reserved) > 0
+)`
+
+const onSaleCond = `EXISTS (
+  SELECT 1 FROM product_variants sv
+  WHERE sv.product_id = p.id AND sv.is_active = true
+    AND sv.compare_at_price IS NOT NULL AND sv.compare_at_price > sv.price
+)`
+
+// productFilter renders ListParams into a WITH prefix, a WHERE clause over
+// products p, and its args. The filter named by skip is left out (for facets).
+func productFilter(p ListParams, skip string) (with, where string, args []any) {
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
 
-	// simple search: match name/slug
-	where := "p.is_active = true"
-	args := []any{}
-	argn := 1
-	if strings.TrimSpace(search) != "" {
-		where += fmt.Sprintf(" AND (p.name ILIKE $%d OR p.slug ILIKE $%d)", argn, argn)
-		args = append(args, "%"+strings.TrimSpace(search)+"%")
-		argn++
+	conds := []string{"p.is_active = true"}
+	if s := strings.TrimSpace(p.Search); s != "" {
+		conds = append(conds, searchCond(arg(s)))
+	}
+	if p.Category != "" && skip != facetCategory {
+		with = fmt.Sprintf(`
+WITH RECURSIVE cat_tree AS (
+  SELECT id FROM categories WHERE slug = %s
+  UNION
+  SELECT c.id FROM categories c JOIN cat_tree t ON c.parent_id = t.id
+)`, arg(p.Category))
+		conds = append(conds, `EXISTS (
+  SELECT 1 FROM product_categories fpc
+  WHERE fpc.product_id = p.id AND fpc.category_id IN (SELECT id FROM cat_tree)
+)`)
+	}
+	if (p.MinPrice != nil || p.MaxPrice != nil) && skip != facetPrice {
+		cond := "SELECT 1 FROM product_variants pv WHERE pv.product_id = p.id AND pv.is_active = true"
+		if p.MinPrice != nil {
+			cond += " AND pv.price >= " + arg(*p.MinPrice)
+		}
+		if p.MaxPrice != nil {
+			cond += " AND pv.price <= " + arg(*p.MaxPrice)
+		}
+		conds = append(conds, "EXISTS ("+cond+")")
+	}
+	if p.InStock && skip != facetInStock {
+		conds = append(conds, inStockCond)
+	}
+	if p.OnSale && skip != facetOnSale {
+		conds = append(conds, onSaleCond)
 	}
+	if p.MinRating != nil {
+		conds = append(conds, "p.rating_avg >= "+arg(*p.MinRating))
+	}
+	return with, strings.Join(conds, " AND "), args
+}
 
-	q := fmt.Sprintf(`
-SELECT
-  p.id::text,
+// searchCond matches the full-text document, or falls back to trigram word
+// similarity on name/SKU so misspelled queries still find something.
+func searchCond(q string) string {
+	return fmt.Sprintf(`(
+  p.search_vector @@ websearch_to_tsquery('simple', %[1]s)
+  OR %[1]s <%% p.name
+  OR EXISTS (
+    SELECT 1 FROM product_variants sv
+    WHERE sv.product_id = p.id AND sv.is_active = true AND %[1]s <%% sv.sku
+  )
+)`, q)
+}
+
+// productCardCols is the ProductListItem select list (see scanCard); it expects
+// products p LEFT JOIN active product_variants v, grouped by p.id.
+const productCardCols = `p.id::text,
   p.slug,
   p.name,
   COALESCE(MIN(v.price), 0) AS min_price,
   COALESCE(MAX(v.price), 0) AS max_price,
+  (array_agg(v.compare_at_price ORDER BY v.price ASC, v.id ASC) FILTER (WHERE v.id IS NOT NULL))[1] AS compare_at_price,
+  COALESCE(bool_or(v.compare_at_price > v.price), false) AS on_sale,
+  COALESCE(MAX(CASE WHEN v.compare_at_price > v.price
+    THEN (v.compare_at_price - v.price) * 100 / v.compare_at_price END), 0)::int AS discount_percent,
   COALESCE((
      SELECT url FROM product_images pi
      WHERE pi.product_id = p.id
      ORDER BY pi.position ASC
      LIMIT 1
-  ), '') AS image_url
+  ), '') AS image_url,
+  p.rating_avg::float8,
+  p.rating_count,
+  p.created_at`
+
+func cardDest(it *ProductListItem) []any {
+	return []any{&it.ID, &it.Slug, &it.Name, &it.MinPrice, &it.MaxPrice,
+		&it.CompareAtPrice, &it.OnSale, &it.DiscountPercent, &it.ImageURL, &it.RatingAvg, &it.RatingCount, &it.CreatedAt}
+}
+
+// productOrder must stay in step with productKeyset: every sort ends on p.id
+// in the same direction as its key so row comparisons are exact.
+func productOrder(sort string) string {
+	switch sort {
+	case SortPriceAsc:
+		return "min_price ASC, p.id ASC"
+	case SortPriceDesc:
+		return "min_price DESC, p.id DESC"
+	case SortName:
+		return "p.name ASC, p.id ASC"
+	case SortRating:
+		return "p.rating_avg DESC, p.rating_count DESC, p.id DESC"
+	case SortRelevance:
+		return "score DESC, p.id DESC"
+	default:
+		return "p.created_at DESC, p.id DESC"
+	}
+}
+
+// productKeyset renders the HAVING condition selecting rows after c.
+func productKeyset(sort string, c *ProductCursor, arg func(any) string) string {
+	switch sort {
+	case SortPriceAsc:
+		return fmt.Sprintf("(COALESCE(MIN(v.price), 0), p.id) > (%s::bigint, %s::uuid)", arg(*c.Price), arg(c.ID))
+	case SortPriceDesc:
+		return fmt.Sprintf("(COALESCE(MIN(v.price), 0), p.id) < (%s::bigint, %s::uuid)", arg(*c.Price), arg(c.ID))
+	case SortName:
+		return fmt.Sprintf("(p.name, p.id) > (%s::text, %s::uuid)", arg(*c.Name), arg(c.ID))
+	case SortRating:
+		return fmt.Sprintf("(p.rating_avg::float8, p.rating_count, p.id) < (%s::float8, %s::int, %s::uuid)",
+			arg(*c.Rating), arg(*c.Reviews), arg(c.ID))
+	default:
+		return fmt.Sprintf("(p.created_at, p.id) < (%s::timestamptz, %s::uuid)", arg(*c.CreatedAt), arg(c.ID))
+	}
+}
+
+func (r *PostgresRepository) ListProducts(ctx context.Context, p ListParams) ([]ProductListItem, error) {
+	if p.Limit <= 0 {
+		p.Limit = 20
+	}
+
+	with, where, args := productFilter(p, "")
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	having := "TRUE"
+	if p.After != nil {
+		having = productKeyset(p.Sort, p.After, arg)
+	}
+
+	q := fmt.Sprintf(`%s
+SELECT
+  `+productCardCols+`
 FROM products p
 LEFT JOIN product_variants v ON v.product_id = p.id AND v.is_active = true
 WHERE %s
 GROUP BY p.id, p.slug, p.name
-ORDER BY p.created_at DESC
-LIMIT $%d OFFSET $%d;
-`, where, argn, argn+1)
-
-	args = append(args, limit, offset)
+HAVING %s
+ORDER BY %s
+LIMIT %s;
+`, with, where, having, productOrder(p.Sort), arg(p.Limit))
 
 	rows, err := r.pool.Query(ctx, q, args...)
 	if err != nil {
@@ -63,33 +199,165 @@ LIMIT $%d OFFSET $%d;
 	}
 	defer rows.Close()
 
-	out := make([]ProductListItem, 0, limit)
+	out := make([]ProductListItem, 0, p.Limit)
 	for rows.Next() {
 		var it ProductListItem
-		if err := rows.Scan(&it.ID, &it.Slug, &it.Name, &it.MinPrice, &it.MaxPrice, &it.ImageURL); err != nil {
+		if err := rows.Scan(cardDest(&it)...); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) CountProducts(ctx context.Context, p ListParams) (int, error) {
+	with, where, args := productFilter(p, "")
+	var n int
+	err := r.pool.QueryRow(ctx, fmt.Sprintf(`%s
+SELECT COUNT(*) FROM products p WHERE %s;
+`, with, where), args...).Scan(&n)
+	return n, err
+}
+
+func (r *PostgresRepository) SearchProducts(ctx context.Context, p ListParams) ([]SearchResult, error) {
+	if p.Limit <= 0 {
+		p.Limit = 20
+	}
+
+	with, where, args := productFilter(p, "")
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	qa := arg(strings.TrimSpace(p.Search))
+	tsq := fmt.Sprintf("websearch_to_tsquery('simple', %s)", qa)
+
+	// Full-text hits always outrank fuzzy ones: score = 1 + ts_rank vs. similarity in [0,1].
+	score := fmt.Sprintf(`(CASE WHEN p.search_vector @@ %[1]s
+    THEN 1 + ts_rank_cd(p.search_vector, %[1]s)
+    ELSE word_similarity(%[2]s, p.name)
+  END)::float8`, tsq, qa)
+
+	having := "TRUE"
+	if p.After != nil {
+		having = fmt.Sprintf("(%s, p.id) < (%s::float8, %s::uuid)", score, arg(*p.After.Score), arg(p.After.ID))
+	}
+
+	q := fmt.Sprintf(`%s
+SELECT
+  `+productCardCols+`,
+  (p.search_vector @@ %s) AS fulltext,
+  %s AS score,
+  ts_headline('simple', p.name || '. ' || p.description, %s,
+    'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2') AS snippet
+FROM products p
+LEFT JOIN product_variants v ON v.product_id = p.id AND v.is_active = true
+WHERE %s
+GROUP BY p.id, p.slug, p.name
+HAVING %s
+ORDER BY %s
+LIMIT %s;
+`, with, tsq, score, tsq, where, having, productOrder(SortRelevance), arg(p.Limit))
+
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]SearchResult, 0, p.Limit)
+	for rows.Next() {
+		var it SearchResult
+		var fulltext bool
+		if err := rows.Scan(append(cardDest(&it.ProductListItem), &fulltext, &it.Score, &it.Snippet)...); err != nil {
 			return nil, err
 		}
+		it.Match = MatchFuzzy
+		if fulltext {
+			it.Match = MatchFullText
+		}
 		out = append(out, it)
 	}
 	return out, rows.Err()
 }
 
+func (r *PostgresRepository) ProductFacets(ctx context.Context, p ListParams) (*Facets, error) {
+	f := Facets{Categories: []CategoryFacet{}}
+
+	with, where, args := productFilter(p, facetPrice)
+	err := r.pool.QueryRow(ctx, fmt.Sprintf(`%s
+SELECT COALESCE(MIN(v.price), 0), COALESCE(MAX(v.price), 0)
+FROM products p
+JOIN product_variants v ON v.product_id = p.id AND v.is_active = true
+WHERE %s;
+`, with, where), args...).Scan(&f.Price.Min, &f.Price.Max)
+	if err != nil {
+		return nil, err
+	}
+
+	with, where, args = productFilter(p, facetInStock)
+	if err := r.pool.QueryRow(ctx, fmt.Sprintf(`%s
+SELECT COUNT(*) FROM products p WHERE %s AND %s;
+`, with, where, inStockCond), args...).Scan(&f.InStock); err != nil {
+		return nil, err
+	}
+
+	with, where, args = productFilter(p, facetOnSale)
+	if err := r.pool.QueryRow(ctx, fmt.Sprintf(`%s
+SELECT COUNT(*) FROM products p WHERE %s AND %s;
+`, with, where, onSaleCond), args...).Scan(&f.OnSale); err != nil {
+		return nil, err
+	}
+
+	with, where, args = productFilter(p, facetCategory)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`%s
+SELECT c.id::text, c.slug, c.name, COUNT(DISTINCT p.id)::int
+FROM products p
+JOIN product_categories pc ON pc.product_id = p.id
+JOIN categories c ON c.id = pc.category_id
+WHERE %s
+GROUP BY c.id, c.slug, c.name
+ORDER BY COUNT(DISTINCT p.id) DESC, c.name ASC;
+`, with, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cf CategoryFacet
+		if err := rows.Scan(&cf.ID, &cf.Slug, &cf.Name, &cf.Count); err != nil {
+			return nil, err
+		}
+		f.Categories = append(f.Categories, cf)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
 func (r *PostgresRepository) GetProductBySlug(ctx context.Context, slug string) (*ProductDetail, error) {
 	// Product
 	var p ProductDetail
 	err := r.pool.QueryRow(ctx, `
-SELECT id::text, slug, name, description
+SELECT id::text, slug, name, description, rating_avg::float8, rating_count,
+       meta_title, meta_description, canonical_url
 FROM products
 WHERE slug = $1 AND is_active = true
 LIMIT 1;
-`, slug).Scan(&p.ID, &p.Slug, &p.Name, &p.Description)
+`, slug).Scan(&p.ID, &p.Slug, &p.Name, &p.Description, &p.RatingAvg, &p.RatingCount,
+		&p.SEO.Title, &p.SEO.Description, &p.SEO.CanonicalURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Images
 	imgRows, err := r.pool.Query(ctx, `
-SELECT url, position
+SELECT id::text, url, position
 FROM product_images
 WHERE product_id = $1
 ORDER BY position ASC;
@@ -101,7 +369,7 @@ ORDER BY position ASC;
 
 	for imgRows.Next() {
 		var im ProductImage
-		if err := imgRows.Scan(&im.URL, &im.Position); err != nil {
+		if err := imgRows.Scan(&im.ID, &im.URL, &im.Position); err != nil {
 			return nil, err
 		}
 		p.Images = append(p.Images, im)
@@ -112,7 +380,7 @@ ORDER BY position ASC;
 
 	// Variants
 	varRows, err := r.pool.Query(ctx, `
-SELECT id::text, sku, name, price, is_active
+SELECT id::text, sku, name, price, compare_at_price, weight_grams, is_active, inventory_policy, preorder_ships_at
 FROM product_variants
 WHERE product_id = $1
 ORDER BY created_at ASC;
@@ -124,14 +392,1688 @@ ORDER BY created_at ASC;
 
 	for varRows.Next() {
 		var v ProductVariant
-		if err := varRows.Scan(&v.ID, &v.SKU, &v.Name, &v.Price, &v.Active); err != nil {
+		if err := varRows.Scan(&v.ID, &v.SKU, &v.Name, &v.Price, &v.CompareAtPrice, &v.WeightGrams, &v.Active, &v.InventoryPolicy, &v.PreorderShipsAt); err != nil {
 			return nil, err
 		}
+		v.DiscountPercent = DiscountPercent(v.Price, v.CompareAtPrice)
+		v.OnSale = OnSale(v.Price, v.CompareAtPrice)
 		p.Variants = append(p.Variants, v)
 	}
 	if err := varRows.Err(); err != nil {
 		return nil, err
 	}
 
+	// Options
+	p.Options = []ProductOption{}
+	optRows, err := r.pool.Query(ctx, `
+SELECT o.id::text, o.name, o.position, ov.id::text, ov.value, ov.position
+FROM product_options o
+JOIN product_option_values ov ON ov.option_id = o.id
+WHERE o.product_id = $1
+ORDER BY o.position ASC, o.name ASC, ov.position ASC, ov.value ASC;
+`, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer optRows.Close()
+
+	for optRows.Next() {
+		var o ProductOption
+		var ov OptionValue
+		if err := optRows.Scan(&o.ID, &o.Name, &o.Position, &ov.ID, &ov.Value, &ov.Position); err != nil {
+			return nil, err
+		}
+		if n := len(p.Options); n == 0 || p.Options[n-1].ID != o.ID {
+			p.Options = append(p.Options, o)
+		}
+		last := &p.Options[len(p.Options)-1]
+		last.Values = append(last.Values, ov)
+	}
+	if err := optRows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(p.Options) > 0 {
+		linkRows, err := r.pool.Query(ctx, `
+SELECT pvo.variant_id::text, o.name, ov.value
+FROM product_variant_option_values pvo
+JOIN product_variants v ON v.id = pvo.variant_id
+JOIN product_options o ON o.id = pvo.option_id
+JOIN product_option_values ov ON ov.id = pvo.value_id
+WHERE v.product_id = $1;
+`, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		defer linkRows.Close()
+
+		idx := make(map[string]int, len(p.Variants))
+		for i, v := range p.Variants {
+			idx[v.ID] = i
+		}
+		for linkRows.Next() {
+			var vid, name, val string
+			if err := linkRows.Scan(&vid, &name, &val); err != nil {
+				return nil, err
+			}
+			i, ok := idx[vid]
+			if !ok {
+				continue
+			}
+			if p.Variants[i].Options == nil {
+				p.Variants[i].Options = map[string]string{}
+			}
+			p.Variants[i].Options[name] = val
+		}
+		if err := linkRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Breadcrumbs: walk up from every assigned category, keep the deepest path
+	crumbRows, err := r.pool.Query(ctx, `
+WITH RECURSIVE path AS (
+  SELECT pc.category_id AS leaf, c.id, c.parent_id, c.slug, c.name, 0 AS depth
+  FROM product_categories pc
+  JOIN categories c ON c.id = pc.category_id
+  WHERE pc.product_id = $1
+  UNION ALL
+  SELECT p.leaf, c.id, c.parent_id, c.slug, c.name, p.depth + 1
+  FROM path p
+  JOIN categories c ON c.id = p.parent_id
+), deepest AS (
+  SELECT leaf FROM path GROUP BY leaf ORDER BY MAX(depth) DESC, leaf LIMIT 1
+)
+SELECT id::text, slug, name
+FROM path
+WHERE leaf = (SELECT leaf FROM deepest)
+ORDER BY depth DESC;
+`, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer crumbRows.Close()
+
+	p.Breadcrumbs = []Breadcrumb{}
+	for crumbRows.Next() {
+		var b Breadcrumb
+		if err := crumbRows.Scan(&b.ID, &b.Slug, &b.Name); err != nil {
+			return nil, err
+		}
+		p.Breadcrumbs = append(p.Breadcrumbs, b)
+	}
+	if err := crumbRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *PostgresRepository) PricesIn(ctx context.Context, variantIDs []string, currency string) (map[string]CurrencyPrice, error) {
+	var ok bool
+	if err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM currencies WHERE code = $1 AND is_active);`, currency).Scan(&ok); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidPayload
+	}
+
+	rows, err := r.pool.Query(ctx, `
+SELECT v.id::text, vp.price, vp.compare_at_price
+FROM unnest($1::uuid[]) AS v(id)
+CROSS JOIN LATERAL variant_price_in(v.id, $2) vp;
+`, variantIDs, currency)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]CurrencyPrice, len(variantIDs))
+	for rows.Next() {
+		var id string
+		var cp CurrencyPrice
+		if err := rows.Scan(&id, &cp.Price, &cp.CompareAtPrice); err != nil {
+			return nil, err
+		}
+		out[id] = cp
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) AvailableQty(ctx context.Context, variantIDs []string) (map[string]int, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id::text, s.available
+FROM unnest($1::uuid[]) AS id
+CROSS JOIN LATERAL variant_available(id) s;
+`, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]int, len(variantIDs))
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
+
+// ===== Admin =====
+
+const productCols = `id::text, slug, name, description, is_active, created_at, updated_at, meta_title, meta_description, canonical_url`
+
+const variantCols = `id::text, product_id::text, sku, name, price, compare_at_price, weight_grams, is_active, created_at, updated_at`
+
+func scanProduct(row pgx.Row) (*Product, error) {
+	var p Product
+	if err := row.Scan(&p.ID, &p.Slug, &p.Name, &p.Description, &p.IsActive, &p.CreatedAt, &p.UpdatedAt, &p.MetaTitle, &p.MetaDescription, &p.CanonicalURL); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
 	return &p, nil
 }
+
+func scanVariant(row pgx.Row) (*Variant, error) {
+	var v Variant
+	if err := row.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Name, &v.Price, &v.CompareAtPrice, &v.WeightGrams, &v.IsActive, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+	return &v, nil
+}
+
+func (r *PostgresRepository) CreateProduct(ctx context.Context, in ProductInput) (*Product, error) {
+	return scanProduct(r.pool.QueryRow(ctx, `
+INSERT INTO products (slug, name, description, is_active, meta_title, meta_description, canonical_url)
+VALUES ($1, $2, COALESCE($3, ''), COALESCE($4, true), COALESCE($5, ''), COALESCE($6, ''), COALESCE($7, ''))
+RETURNING `+productCols+`;
+`, in.Slug, in.Name, in.Description, in.IsActive, in.MetaTitle, in.MetaDescription, in.CanonicalURL))
+}
+
+func (r *PostgresRepository) UpdateProduct(ctx context.Context, productID string, in ProductInput) (*Product, error) {
+	return scanProduct(r.pool.QueryRow(ctx, `
+UPDATE products
+SET slug = COALESCE($2, slug),
+    name = COALESCE($3, name),
+    description = COALESCE($4, description),
+    is_active = COALESCE($5, is_active),
+    meta_title = COALESCE($6, meta_title),
+    meta_description = COALESCE($7, meta_description),
+    canonical_url = COALESCE($8, canonical_url),
+    updated_at = now()
+WHERE id = $1
+RETURNING `+productCols+`;
+`, productID, in.Slug, in.Name, in.Description, in.IsActive, in.MetaTitle, in.MetaDescription, in.CanonicalURL))
+}
+
+func (r *PostgresRepository) CreateVariant(ctx context.Context, productID string, in VariantInput) (*Variant, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	v, err := scanVariant(tx.QueryRow(ctx, `
+INSERT INTO product_variants (product_id, sku, name, price, compare_at_price, weight_grams, is_active)
+VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, true))
+RETURNING `+variantCols+`;
+`, productID, in.SKU, in.Name, in.Price, in.CompareAtPrice, in.WeightGrams, in.IsActive))
+	if err != nil {
+		return nil, err
+	}
+	if in.Options != nil {
+		if v.Options, err = setVariantOptions(ctx, tx, v.ID, in.Options); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *PostgresRepository) UpdateVariant(ctx context.Context, variantID string, in VariantInput) (*Variant, error) {
+	return scanVariant(r.pool.QueryRow(ctx, `
+UPDATE product_variants
+SET sku = COALESCE($2, sku),
+    name = COALESCE($3, name),
+    price = COALESCE($4, price),
+    compare_at_price = COALESCE($5, compare_at_price),
+    weight_grams = COALESCE($6, weight_grams),
+    is_active = COALESCE($7, is_active),
+    updated_at = now()
+WHERE id = $1
+RETURNING `+variantCols+`;
+`, variantID, in.SKU, in.Name, in.Price, in.CompareAtPrice, in.WeightGrams, in.IsActive))
+}
+
+func (r *PostgresRepository) CreateOption(ctx context.Context, productID string, in OptionInput) (*ProductOption, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	o := ProductOption{Name: in.Name, Values: []OptionValue{}}
+	err = tx.QueryRow(ctx, `
+INSERT INTO product_options (product_id, name, position)
+VALUES ($1, $2, (SELECT COUNT(*) FROM product_options WHERE product_id = $1))
+RETURNING id::text, position;
+`, productID, in.Name).Scan(&o.ID, &o.Position)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+
+	for i, val := range in.Values {
+		ov := OptionValue{Value: val, Position: i}
+		if err := tx.QueryRow(ctx, `
+INSERT INTO product_option_values (option_id, value, position)
+VALUES ($1, $2, $3)
+RETURNING id::text;
+`, o.ID, val, i).Scan(&ov.ID); err != nil {
+			return nil, mapPgError(err)
+		}
+		o.Values = append(o.Values, ov)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *PostgresRepository) AddOptionValue(ctx context.Context, optionID, value string) (*OptionValue, error) {
+	ov := OptionValue{Value: value}
+	err := r.pool.QueryRow(ctx, `
+INSERT INTO product_option_values (option_id, value, position)
+VALUES ($1, $2, (SELECT COUNT(*) FROM product_option_values WHERE option_id = $1))
+RETURNING id::text, position;
+`, optionID, value).Scan(&ov.ID, &ov.Position)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return &ov, nil
+}
+
+func (r *PostgresRepository) SetVariantOptions(ctx context.Context, variantID string, options map[string]string) (map[string]string, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	out, err := setVariantOptions(ctx, tx, variantID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// setVariantOptions resolves option/value names (case-insensitively) against the
+// variant's product, requires one value per product option, and rewrites the
+// links plus option_signature. The unique signature index rejects duplicates.
+func setVariantOptions(ctx context.Context, tx pgx.Tx, variantID string, options map[string]string) (map[string]string, error) {
+	rows, err := tx.Query(ctx, `
+SELECT o.id::text, o.name, ov.id::text, ov.value
+FROM product_variants pv
+JOIN product_options o ON o.product_id = pv.product_id
+JOIN product_option_values ov ON ov.option_id = o.id
+WHERE pv.id = $1;
+`, variantID)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	type optionDef struct {
+		id, name string
+		values   map[string][2]string // lower(value) -> {value id, value}
+	}
+	defs := map[string]*optionDef{} // lower(name)
+	for rows.Next() {
+		var oid, oname, vid, val string
+		if err := rows.Scan(&oid, &oname, &vid, &val); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key := strings.ToLower(oname)
+		d, ok := defs[key]
+		if !ok {
+			d = &optionDef{id: oid, name: oname, values: map[string][2]string{}}
+			defs[key] = d
+		}
+		d.values[strings.ToLower(val)] = [2]string{vid, val}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(options) != len(defs) {
+		return nil, ErrInvalidOptions
+	}
+	out := make(map[string]string, len(options))
+	optionIDs := make([]string, 0, len(options))
+	valueIDs := make([]string, 0, len(options))
+	for name, val := range options {
+		d, ok := defs[strings.ToLower(name)]
+		if !ok {
+			return nil, ErrInvalidOptions
+		}
+		v, ok := d.values[strings.ToLower(val)]
+		if !ok {
+			return nil, ErrInvalidOptions
+		}
+		optionIDs = append(optionIDs, d.id)
+		valueIDs = append(valueIDs, v[0])
+		out[d.name] = v[1]
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_variant_option_values WHERE variant_id = $1;`, variantID); err != nil {
+		return nil, err
+	}
+	if len(valueIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO product_variant_option_values (variant_id, option_id, value_id)
+SELECT $1, o, v FROM unnest($2::uuid[], $3::uuid[]) AS x(o, v);
+`, variantID, optionIDs, valueIDs); err != nil {
+			return nil, mapPgError(err)
+		}
+	}
+
+	var signature *string
+	if len(valueIDs) > 0 {
+		sorted := slices.Clone(valueIDs)
+		slices.Sort(sorted)
+		sig := strings.Join(sorted, ",")
+		signature = &sig
+	}
+	tag, err := tx.Exec(ctx, `
+UPDATE product_variants SET option_signature = $2, updated_at = now() WHERE id = $1;
+`, variantID, signature)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+// ===== Price schedules =====
+
+const scheduleCols = `id::text, variant_id::text, price, compare_at_price, starts_at, ends_at, status,
+  created_by, created_at, applied_at, reverted_at`
+
+func scanSchedule(row pgx.Row) (*PriceSchedule, error) {
+	var ps PriceSchedule
+	if err := row.Scan(&ps.ID, &ps.VariantID, &ps.Price, &ps.CompareAtPrice, &ps.StartsAt, &ps.EndsAt, &ps.Status,
+		&ps.CreatedBy, &ps.CreatedAt, &ps.AppliedAt, &ps.RevertedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+	return &ps, nil
+}
+
+func (r *PostgresRepository) CreatePriceSchedule(ctx context.Context, ps PriceSchedule) (*PriceSchedule, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// serialize schedule edits per variant
+	if err := tx.QueryRow(ctx, `SELECT id::text FROM product_variants WHERE id = $1 FOR UPDATE;`, ps.VariantID).Scan(new(string)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+
+	// a permanent change is the point [starts_at, starts_at]; windows are [starts_at, ends_at)
+	var conflict bool
+	err = tx.QueryRow(ctx, `
+SELECT EXISTS (
+  SELECT 1 FROM price_schedules
+  WHERE variant_id = $1 AND status IN ('pending', 'active')
+    AND CASE WHEN ends_at IS NULL THEN tstzrange(starts_at, starts_at, '[]') ELSE tstzrange(starts_at, ends_at) END
+     && CASE WHEN $3::timestamptz IS NULL THEN tstzrange($2, $2, '[]') ELSE tstzrange($2, $3) END
+);
+`, ps.VariantID, ps.StartsAt, ps.EndsAt).Scan(&conflict)
+	if err != nil {
+		return nil, err
+	}
+	if conflict {
+		return nil, ErrScheduleConflict
+	}
+
+	out, err := scanSchedule(tx.QueryRow(ctx, `
+INSERT INTO price_schedules (variant_id, price, compare_at_price, starts_at, ends_at, created_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING `+scheduleCols+`;
+`, ps.VariantID, ps.Price, ps.CompareAtPrice, ps.StartsAt, ps.EndsAt, ps.CreatedBy))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *PostgresRepository) ListPriceSchedules(ctx context.Context, variantID string) ([]PriceSchedule, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT `+scheduleCols+`
+FROM price_schedules
+WHERE variant_id = $1
+ORDER BY starts_at DESC, created_at DESC;
+`, variantID)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	defer rows.Close()
+	return scanSchedules(rows)
+}
+
+func scanSchedules(rows pgx.Rows) ([]PriceSchedule, error) {
+	out := []PriceSchedule{}
+	for rows.Next() {
+		ps, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *ps)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) CancelPriceSchedule(ctx context.Context, scheduleID string) (*PriceSchedule, error) {
+	ps, err := scanSchedule(r.pool.QueryRow(ctx, `
+UPDATE price_schedules SET status = 'cancelled'
+WHERE id = $1 AND status = 'pending'
+RETURNING `+scheduleCols+`;
+`, scheduleID))
+	if errors.Is(err, ErrNotFound) {
+		// distinguish unknown id from one that already ran
+		var status string
+		if e := r.pool.QueryRow(ctx, `SELECT status FROM price_schedules WHERE id = $1;`, scheduleID).Scan(&status); e == nil {
+			return nil, ErrScheduleNotPending
+		}
+	}
+	return ps, err
+}
+
+func (r *PostgresRepository) DuePriceSchedules(ctx context.Context, now time.Time) ([]PriceSchedule, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT `+scheduleCols+`
+FROM price_schedules
+WHERE (status = 'pending' AND starts_at <= $1)
+   OR (status = 'active' AND ends_at <= $1)
+ORDER BY COALESCE(CASE WHEN status = 'active' THEN ends_at END, starts_at) ASC
+LIMIT 500;
+`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSchedules(rows)
+}
+
+func (r *PostgresRepository) ApplyPriceSchedule(ctx context.Context, scheduleID string, now time.Time) (bool, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ps, err := scanSchedule(tx.QueryRow(ctx, `
+SELECT `+scheduleCols+` FROM price_schedules WHERE id = $1 AND status = 'pending' FOR UPDATE;
+`, scheduleID))
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if ps.EndsAt != nil && !ps.EndsAt.After(now) {
+		// the whole window passed while nobody was running; don't flash the sale price
+		if _, err := tx.Exec(ctx, `UPDATE price_schedules SET status = 'expired' WHERE id = $1;`, ps.ID); err != nil {
+			return false, err
+		}
+		return true, tx.Commit(ctx)
+	}
+
+	if _, err := tx.Exec(ctx, `
+SELECT set_config('app.price_change_source', 'schedule', true), set_config('app.price_schedule_id', $1, true);
+`, ps.ID); err != nil {
+		return false, err
+	}
+
+	var oldPrice int64
+	var oldCompare *int64
+	err = tx.QueryRow(ctx, `
+WITH old AS (
+  SELECT id, price, compare_at_price FROM product_variants WHERE id = $1 FOR UPDATE
+)
+UPDATE product_variants v
+SET price = $2, compare_at_price = $3, updated_at = now()
+FROM old
+WHERE v.id = old.id
+RETURNING old.price, old.compare_at_price;
+`, ps.VariantID, ps.Price, ps.CompareAtPrice).Scan(&oldPrice, &oldCompare)
+	if err != nil {
+		return false, mapPgError(err)
+	}
+
+	status := ScheduleCompleted
+	if ps.EndsAt != nil {
+		status = ScheduleActive
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE price_schedules
+SET status = $2, applied_at = $3, revert_price = $4, revert_compare_at_price = $5
+WHERE id = $1;
+`, ps.ID, status, now, oldPrice, oldCompare); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) RevertPriceSchedule(ctx context.Context, scheduleID string, now time.Time) (bool, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var variantID string
+	err = tx.QueryRow(ctx, `
+SELECT variant_id::text FROM price_schedules WHERE id = $1 AND status = 'active' FOR UPDATE;
+`, scheduleID).Scan(&variantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+SELECT set_config('app.price_change_source', 'revert', true), set_config('app.price_schedule_id', $1, true);
+`, scheduleID); err != nil {
+		return false, err
+	}
+
+	// only restore if nobody re-priced the variant during the window
+	if _, err := tx.Exec(ctx, `
+UPDATE product_variants v
+SET price = s.revert_price, compare_at_price = s.revert_compare_at_price, updated_at = now()
+FROM price_schedules s
+WHERE s.id = $1 AND v.id = s.variant_id
+  AND v.price = s.price AND v.compare_at_price IS NOT DISTINCT FROM s.compare_at_price;
+`, scheduleID); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+UPDATE price_schedules SET status = 'completed', reverted_at = $2 WHERE id = $1;
+`, scheduleID, now); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) ListPriceHistory(ctx context.Context, variantID string, limit int, after *PriceHistoryCursor) ([]PricePoint, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var afterAt *time.Time
+	var afterID *int64
+	if after != nil {
+		afterAt, afterID = &after.ChangedAt, &after.ID
+	}
+
+	rows, err := r.pool.Query(ctx, `
+SELECT id, variant_id::text, price, compare_at_price, source, schedule_id::text, changed_at
+FROM variant_price_history
+WHERE variant_id = $1
+  AND ($3::timestamptz IS NULL OR (changed_at, id) < ($3::timestamptz, $4::bigint))
+ORDER BY changed_at DESC, id DESC
+LIMIT $2;
+`, variantID, limit, afterAt, afterID)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	defer rows.Close()
+
+	out := make([]PricePoint, 0, limit)
+	for rows.Next() {
+		var pp PricePoint
+		if err := rows.Scan(&pp.ID, &pp.VariantID, &pp.Price, &pp.CompareAtPrice, &pp.Source, &pp.ScheduleID, &pp.ChangedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, pp)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) PriceAt(ctx context.Context, variantID string, at time.Time) (*PricePoint, error) {
+	var pp PricePoint
+	err := r.pool.QueryRow(ctx, `
+SELECT id, variant_id::text, price, compare_at_price, source, schedule_id::text, changed_at
+FROM variant_price_history
+WHERE variant_id = $1 AND changed_at <= $2
+ORDER BY changed_at DESC, id DESC
+LIMIT 1;
+`, variantID, at).Scan(&pp.ID, &pp.VariantID, &pp.Price, &pp.CompareAtPrice, &pp.Source, &pp.ScheduleID, &pp.ChangedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+	return &pp, nil
+}
+
+func (r *PostgresRepository) AddImage(ctx context.Context, productID, url string, position *int) (*ProductImage, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// lock the product so concurrent image edits don't interleave positions
+	if err := tx.QueryRow(ctx, `SELECT id::text FROM products WHERE id = $1 FOR UPDATE;`, productID).Scan(new(string)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+
+	var next int
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1;`, productID).Scan(&next); err != nil {
+		return nil, err
+	}
+	pos := next
+	if position != nil && *position < next {
+		pos = *position
+		if _, err := tx.Exec(ctx, `
+UPDATE product_images SET position = position + 1
+WHERE product_id = $1 AND position >= $2;
+`, productID, pos); err != nil {
+			return nil, err
+		}
+	}
+
+	im := ProductImage{URL: url, Position: pos}
+	if err := tx.QueryRow(ctx, `
+INSERT INTO product_images (product_id, url, position)
+VALUES ($1, $2, $3)
+RETURNING id::text;
+`, productID, url, pos).Scan(&im.ID); err != nil {
+		return nil, mapPgError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &im, nil
+}
+
+func (r *PostgresRepository) ReorderImages(ctx context.Context, productID string, imageIDs []string) ([]ProductImage, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := tx.QueryRow(ctx, `SELECT id::text FROM products WHERE id = $1 FOR UPDATE;`, productID).Scan(new(string)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+
+	var total int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM product_images WHERE product_id = $1;`, productID).Scan(&total); err != nil {
+		return nil, err
+	}
+	if total != len(imageIDs) {
+		return nil, ErrInvalidPayload
+	}
+
+	tag, err := tx.Exec(ctx, `
+UPDATE product_images pi
+SET position = x.ord - 1
+FROM unnest($2::uuid[]) WITH ORDINALITY AS x(id, ord)
+WHERE pi.id = x.id AND pi.product_id = $1;
+`, productID, imageIDs)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	if int(tag.RowsAffected()) != total {
+		// an id belonged to another product
+		return nil, ErrInvalidPayload
+	}
+
+	rows, err := tx.Query(ctx, `
+SELECT id::text, url, position
+FROM product_images
+WHERE product_id = $1
+ORDER BY position ASC;
+`, productID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProductImage, 0, total)
+	for rows.Next() {
+		var im ProductImage
+		if err := rows.Scan(&im.ID, &im.URL, &im.Position); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, im)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *PostgresRepository) RemoveImage(ctx context.Context, productID, imageID string) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var pos int
+	err = tx.QueryRow(ctx, `
+DELETE FROM product_images
+WHERE id = $1 AND product_id = $2
+RETURNING position;
+`, imageID, productID).Scan(&pos)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return mapPgError(err)
+	}
+
+	// close the gap so positions stay contiguous
+	if _, err := tx.Exec(ctx, `
+UPDATE product_images SET position = position - 1
+WHERE product_id = $1 AND position > $2;
+`, productID, pos); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ===== Categories =====
+
+const categoryCols = `id::text, parent_id::text, slug, name`
+
+func scanCategory(row pgx.Row) (*Category, error) {
+	var c Category
+	if err := row.Scan(&c.ID, &c.ParentID, &c.Slug, &c.Name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+	return &c, nil
+}
+
+func (r *PostgresRepository) ListCategories(ctx context.Context) ([]Category, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+categoryCols+` FROM categories ORDER BY name ASC, slug ASC;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Category{}
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Slug, &c.Name); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) GetCategoryBySlug(ctx context.Context, slug string) (*Category, error) {
+	return scanCategory(r.pool.QueryRow(ctx, `SELECT `+categoryCols+` FROM categories WHERE slug = $1;`, slug))
+}
+
+func (r *PostgresRepository) CreateCategory(ctx context.Context, in CategoryInput) (*Category, error) {
+	return scanCategory(r.pool.QueryRow(ctx, `
+INSERT INTO categories (parent_id, slug, name)
+VALUES (NULLIF($1::text, '')::uuid, $2, $3)
+RETURNING `+categoryCols+`;
+`, in.ParentID, in.Slug, in.Name))
+}
+
+func (r *PostgresRepository) UpdateCategory(ctx context.Context, categoryID string, in CategoryInput) (*Category, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if in.ParentID != nil && *in.ParentID != "" {
+		// the new parent must not be the category itself or one of its descendants
+		var cycle bool
+		err := tx.QueryRow(ctx, `
+WITH RECURSIVE anc AS (
+  SELECT id, parent_id FROM categories WHERE id = $1
+  UNION
+  SELECT c.id, c.parent_id FROM categories c JOIN anc a ON c.id = a.parent_id
+)
+SELECT EXISTS (SELECT 1 FROM anc WHERE id = $2);
+`, *in.ParentID, categoryID).Scan(&cycle)
+		if err != nil {
+			return nil, mapPgError(err)
+		}
+		if cycle {
+			return nil, ErrInvalidParent
+		}
+	}
+
+	c, err := scanCategory(tx.QueryRow(ctx, `
+UPDATE categories
+SET parent_id = CASE WHEN $2::text IS NULL THEN parent_id ELSE NULLIF($2::text, '')::uuid END,
+    slug = COALESCE($3, slug),
+    name = COALESCE($4, name),
+    updated_at = now()
+WHERE id = $1
+RETURNING `+categoryCols+`;
+`, categoryID, in.ParentID, in.Slug, in.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *PostgresRepository) DeleteCategory(ctx context.Context, categoryID string) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// hang the children off the grandparent before the FK's SET NULL would orphan them to the root
+	if _, err := tx.Exec(ctx, `
+UPDATE categories
+SET parent_id = (SELECT parent_id FROM categories WHERE id = $1), updated_at = now()
+WHERE parent_id = $1;
+`, categoryID); err != nil {
+		return mapPgError(err)
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM categories WHERE id = $1;`, categoryID)
+	if err != nil {
+		return mapPgError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) SetProductCategories(ctx context.Context, productID string, categoryIDs []string) ([]Category, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := tx.QueryRow(ctx, `SELECT id::text FROM products WHERE id = $1 FOR UPDATE;`, productID).Scan(new(string)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_categories WHERE product_id = $1;`, productID); err != nil {
+		return nil, err
+	}
+	if len(categoryIDs) > 0 {
+		tag, err := tx.Exec(ctx, `
+INSERT INTO product_categories (product_id, category_id)
+SELECT $1, c.id FROM categories c WHERE c.id = ANY($2::uuid[]);
+`, productID, categoryIDs)
+		if err != nil {
+			return nil, mapPgError(err)
+		}
+		if int(tag.RowsAffected()) != len(categoryIDs) {
+			return nil, ErrNotFound
+		}
+	}
+
+	rows, err := tx.Query(ctx, `
+SELECT c.id::text, c.parent_id::text, c.slug, c.name
+FROM product_categories pc
+JOIN categories c ON c.id = pc.category_id
+WHERE pc.product_id = $1
+ORDER BY c.name ASC;
+`, productID)
+	if err != nil {
+		return nil, err
+	}
+	out := []Category{}
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Slug, &c.Name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CurrentSlug follows the slug history to the renamed product's slug.
+func (r *PostgresRepository) CurrentSlug(ctx context.Context, oldSlug string) (string, error) {
+	var slug string
+	err := r.pool.QueryRow(ctx, `
+SELECT p.slug
+FROM product_slug_history h
+JOIN products p ON p.id = h.product_id AND p.is_active = true
+WHERE h.slug = $1;
+`, oldSlug).Scan(&slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return slug, err
+}
+
+func (r *PostgresRepository) SitemapEntries(ctx context.Context, limit int) ([]SitemapEntry, error) {
+	rows, err := r.pool.Query(ctx, `
+(
+  SELECT 'product', p.slug, p.canonical_url, p.updated_at
+  FROM products p
+  WHERE p.is_active = true
+    AND EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.is_active = true)
+  ORDER BY p.updated_at DESC, p.id
+  LIMIT $1
+)
+UNION ALL
+(
+  SELECT 'category', c.slug, '', c.updated_at
+  FROM categories c
+  ORDER BY c.slug
+  LIMIT $1
+);
+`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SitemapEntry
+	for rows.Next() {
+		var e SitemapEntry
+		if err := rows.Scan(&e.Kind, &e.Slug, &e.CanonicalURL, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ===== Recommendations =====
+
+// activeProduct resolves a listed product's id and cheapest active price
+// (nil when it has no active variants).
+func (r *PostgresRepository) activeProduct(ctx context.Context, slug string) (string, *int64, error) {
+	var id string
+	var price *int64
+	err := r.pool.QueryRow(ctx, `
+SELECT p.id::text, MIN(v.price)
+FROM products p
+LEFT JOIN product_variants v ON v.product_id = p.id AND v.is_active = true
+WHERE p.slug = $1 AND p.is_active = true
+GROUP BY p.id;
+`, slug).Scan(&id, &price)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, ErrNotFound
+	}
+	return id, price, err
+}
+
+func (r *PostgresRepository) RelatedProducts(ctx context.Context, slug string, limit int) ([]ProductListItem, error) {
+	id, price, err := r.activeProduct(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	out := []ProductListItem{}
+	if price == nil {
+		return out, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+SELECT
+  `+productCardCols+`
+FROM products p
+JOIN product_variants v ON v.product_id = p.id AND v.is_active = true
+WHERE p.is_active = true AND p.id <> $1
+  AND EXISTS (
+    SELECT 1 FROM product_categories pc
+    JOIN product_categories bc ON bc.category_id = pc.category_id AND bc.product_id = $1
+    WHERE pc.product_id = p.id
+  )
+GROUP BY p.id
+HAVING MIN(v.price) BETWEEN $2::bigint / 2 AND $2::bigint * 2
+ORDER BY abs(MIN(v.price) - $2::bigint) ASC, p.rating_count DESC, p.id ASC
+LIMIT $3;
+`, id, *price, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var it ProductListItem
+		if err := rows.Scan(cardDest(&it)...); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) CoPurchased(ctx context.Context, slug string, limit int) ([]CoPurchase, error) {
+	id, _, err := r.activeProduct(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+SELECT
+  `+productCardCols+`,
+  cp.orders_count
+FROM product_co_purchases cp
+JOIN products p ON p.id = cp.related_product_id AND p.is_active = true
+JOIN product_variants v ON v.product_id = p.id AND v.is_active = true
+WHERE cp.product_id = $1
+GROUP BY p.id, cp.orders_count
+ORDER BY cp.orders_count DESC, p.id ASC
+LIMIT $2;
+`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CoPurchase{}
+	for rows.Next() {
+		var it CoPurchase
+		if err := rows.Scan(append(cardDest(&it.ProductListItem), &it.Orders)...); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) RebuildCoPurchases(ctx context.Context, since time.Time, perProduct int) (int, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// one rebuild at a time across instances; readers keep the old pairs until commit
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('product_co_purchases'));`); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM product_co_purchases;`); err != nil {
+		return 0, err
+	}
+	ct, err := tx.Exec(ctx, `
+WITH op AS (
+  SELECT DISTINCT oi.order_id, v.product_id
+  FROM order_items oi
+  JOIN orders o ON o.id = oi.order_id AND o.status = 'paid' AND o.created_at >= $1
+  JOIN product_variants v ON v.id = oi.variant_id
+), pairs AS (
+  SELECT a.product_id, b.product_id AS related_product_id, COUNT(*)::int AS orders_count,
+         row_number() OVER (PARTITION BY a.product_id ORDER BY COUNT(*) DESC, b.product_id) AS rank
+  FROM op a
+  JOIN op b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+  GROUP BY a.product_id, b.product_id
+)
+INSERT INTO product_co_purchases (product_id, related_product_id, orders_count)
+SELECT product_id, related_product_id, orders_count
+FROM pairs
+WHERE rank <= $2;
+`, since, perProduct)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return int(ct.RowsAffected()), nil
+}
+
+// ===== Bulk import/export =====
+
+// importRowErr is a per-row import failure raised by the importer itself.
+type importRowErr struct {
+	field, msg string
+}
+
+func (e *importRowErr) Error() string { return e.msg }
+
+// importRowError classifies err: row problems (bad data, constraint
+// violations) are reported against the row, anything else aborts the import.
+func importRowError(err error) (field, msg string, ok bool) {
+	var re *importRowErr
+	if errors.As(err, &re) {
+		return re.field, re.msg, true
+	}
+	err = mapPgError(err)
+	for _, e := range []error{ErrSlugTaken, ErrSKUTaken, ErrCombinationTaken, ErrOptionTaken, ErrInvalidOptions, ErrInvalidPayload} {
+		if errors.Is(err, e) {
+			return "", e.Error(), true
+		}
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")) {
+		return "", pgErr.Message, true
+	}
+	return "", "", false
+}
+
+func (r *PostgresRepository) ImportRows(ctx context.Context, rows []TransferRow, actor string, dryRun bool) (*ImportReport, error) {
+	rep := &ImportReport{DryRun: dryRun, Rows: len(rows), Errors: []RowError{}}
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// price history records these changes as imports
+	if _, err := tx.Exec(ctx, `SELECT set_config('app.price_change_source', 'import', true);`); err != nil {
+		return nil, err
+	}
+
+	locations := map[string]string{}
+	locRows, err := tx.Query(ctx, `SELECT code, id::text FROM locations;`)
+	if err != nil {
+		return nil, err
+	}
+	for locRows.Next() {
+		var code, id string
+		if err := locRows.Scan(&code, &id); err != nil {
+			locRows.Close()
+			return nil, err
+		}
+		locations[code] = id
+	}
+	locRows.Close()
+	if err := locRows.Err(); err != nil {
+		return nil, err
+	}
+
+	products := map[string]string{} // slug -> id, once the product part is in
+	failed := map[string]int{}      // slug -> row where the product part failed
+
+	// each part runs in a savepoint so one bad row doesn't abort the others
+	inSavepoint := func(n int, sku string, fn func(pgx.Tx) error) (bool, error) {
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return false, err
+		}
+		if err := fn(sp); err != nil {
+			_ = sp.Rollback(ctx)
+			field, msg, ok := importRowError(err)
+			if !ok {
+				return false, err
+			}
+			rep.Errors = append(rep.Errors, RowError{Row: n, SKU: sku, Field: field, Message: msg})
+			return false, nil
+		}
+		return true, sp.Commit(ctx)
+	}
+
+	for i, row := range rows {
+		n := i + 1
+		if prev, bad := failed[row.ProductSlug]; bad {
+			rep.Errors = append(rep.Errors, RowError{Row: n, SKU: row.SKU, Field: "product_slug", Message: fmt.Sprintf("product failed on row %d", prev)})
+			continue
+		}
+
+		productID, seen := products[row.ProductSlug]
+		if !seen {
+			var created bool
+			ok, err := inSavepoint(n, row.SKU, func(sp pgx.Tx) (err error) {
+				productID, created, err = importProduct(ctx, sp, row)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				failed[row.ProductSlug] = n
+				continue
+			}
+			products[row.ProductSlug] = productID
+			if created {
+				rep.ProductsCreated++
+			} else {
+				rep.ProductsUpdated++
+			}
+		}
+
+		if row.productOnly() {
+			continue
+		}
+		var created bool
+		ok, err := inSavepoint(n, row.SKU, func(sp pgx.Tx) (err error) {
+			created, err = importVariant(ctx, sp, productID, row, locations, actor)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok && created {
+			rep.VariantsCreated++
+		} else if ok {
+			rep.VariantsUpdated++
+		}
+	}
+
+	if dryRun || len(rep.Errors) > 0 {
+		return rep, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	rep.Applied = true
+	return rep, nil
+}
+
+// importProduct upserts the product by slug with its categories and images.
+func importProduct(ctx context.Context, tx pgx.Tx, row TransferRow) (string, bool, error) {
+	var id string
+	var created bool
+	err := tx.QueryRow(ctx, `
+INSERT INTO products (slug, name, description, is_active)
+VALUES ($1, $2, $3, COALESCE($4, true))
+ON CONFLICT (slug) DO UPDATE
+SET name = EXCLUDED.name,
+    description = EXCLUDED.description,
+    is_active = COALESCE($4, products.is_active),
+    updated_at = now()
+RETURNING id::text, (xmax = 0);
+`, row.ProductSlug, row.ProductName, row.ProductDescription, row.ProductActive).Scan(&id, &created)
+	if err != nil {
+		return "", false, err
+	}
+
+	if row.Categories != nil {
+		ids := make([]string, 0, len(row.Categories))
+		for _, path := range row.Categories {
+			cid, err := importCategoryPath(ctx, tx, path)
+			if err != nil {
+				return "", false, err
+			}
+			ids = append(ids, cid)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM product_categories WHERE product_id = $1;`, id); err != nil {
+			return "", false, err
+		}
+		if _, err := tx.Exec(ctx, `
+INSERT INTO product_categories (product_id, category_id)
+SELECT $1, c FROM unnest($2::uuid[]) AS c
+ON CONFLICT DO NOTHING;
+`, id, ids); err != nil {
+			return "", false, err
+		}
+	}
+
+	if row.Images != nil {
+		var current []string
+		rows, err := tx.Query(ctx, `SELECT url FROM product_images WHERE product_id = $1 ORDER BY position ASC, created_at ASC;`, id)
+		if err != nil {
+			return "", false, err
+		}
+		for rows.Next() {
+			var u string
+			if err := rows.Scan(&u); err != nil {
+				rows.Close()
+				return "", false, err
+			}
+			current = append(current, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return "", false, err
+		}
+		// keep image ids stable when nothing changed
+		if !slices.Equal(current, row.Images) {
+			if _, err := tx.Exec(ctx, `DELETE FROM product_images WHERE product_id = $1;`, id); err != nil {
+				return "", false, err
+			}
+			if _, err := tx.Exec(ctx, `
+INSERT INTO product_images (product_id, url, position)
+SELECT $1, u, (n - 1)::int FROM unnest($2::text[]) WITH ORDINALITY AS x(u, n);
+`, id, row.Images); err != nil {
+				return "", false, err
+			}
+		}
+	}
+	return id, created, nil
+}
+
+// importCategoryPath returns the leaf of a slug path like "apparel/tops",
+// creating missing categories under their parent. Existing ones are matched
+// by slug alone and never moved.
+func importCategoryPath(ctx context.Context, tx pgx.Tx, path string) (string, error) {
+	var parent *string
+	for _, slug := range strings.Split(path, "/") {
+		var id string
+		err := tx.QueryRow(ctx, `SELECT id::text FROM categories WHERE slug = $1;`, slug).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			name := strings.ReplaceAll(slug, "-", " ")
+			name = strings.ToUpper(name[:1]) + name[1:]
+			err = tx.QueryRow(ctx, `
+INSERT INTO categories (slug, name, parent_id) VALUES ($1, $2, $3) RETURNING id::text;
+`, slug, name, parent).Scan(&id)
+		}
+		if err != nil {
+			return "", err
+		}
+		parent = &id
+	}
+	return *parent, nil
+}
+
+// importVariant upserts the variant by SKU, then its options and stock.
+func importVariant(ctx context.Context, tx pgx.Tx, productID string, row TransferRow, locations map[string]string, actor string) (bool, error) {
+	var id string
+	var created bool
+	err := tx.QueryRow(ctx, `
+INSERT INTO product_variants (product_id, sku, name, price, compare_at_price, weight_grams, is_active)
+VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, true))
+ON CONFLICT (sku) DO UPDATE
+SET name = EXCLUDED.name,
+    price = EXCLUDED.price,
+    compare_at_price = EXCLUDED.compare_at_price,
+    weight_grams = EXCLUDED.weight_grams,
+    is_active = COALESCE($7, product_variants.is_active),
+    updated_at = now()
+WHERE product_variants.product_id = EXCLUDED.product_id
+RETURNING id::text, (xmax = 0);
+`, productID, row.SKU, row.VariantName, row.Price, row.CompareAtPrice, row.WeightGrams, row.VariantActive).Scan(&id, &created)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, &importRowErr{field: "sku", msg: "sku belongs to another product"}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if len(row.Options) > 0 {
+		for _, name := range slices.Sorted(maps.Keys(row.Options)) {
+			if err := importOptionValue(ctx, tx, productID, name, row.Options[name]); err != nil {
+				return false, err
+			}
+		}
+		if _, err := setVariantOptions(ctx, tx, id, row.Options); err != nil {
+			return false, err
+		}
+	}
+
+	for _, code := range slices.Sorted(maps.Keys(row.Stock)) {
+		locationID, ok := locations[code]
+		if !ok {
+			return false, &importRowErr{field: "stock", msg: "unknown location " + code}
+		}
+		if err := importStock(ctx, tx, id, locationID, row.Stock[code], actor); err != nil {
+			return false, err
+		}
+	}
+	return created, nil
+}
+
+// importOptionValue makes sure the product has option name with value
+// (both matched case-insensitively, like setVariantOptions).
+func importOptionValue(ctx context.Context, tx pgx.Tx, productID, name, value string) error {
+	var optionID string
+	err := tx.QueryRow(ctx, `
+SELECT id::text FROM product_options WHERE product_id = $1 AND lower(name) = lower($2);
+`, productID, name).Scan(&optionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = tx.QueryRow(ctx, `
+INSERT INTO product_options (product_id, name, position)
+VALUES ($1, $2, (SELECT COUNT(*) FROM product_options WHERE product_id = $1))
+RETURNING id::text;
+`, productID, name).Scan(&optionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+INSERT INTO product_option_values (option_id, value, position)
+SELECT $1, $2, (SELECT COUNT(*) FROM product_option_values WHERE option_id = $1)
+WHERE NOT EXISTS (
+  SELECT 1 FROM product_option_values WHERE option_id = $1 AND lower(value) = lower($2)
+);
+`, optionID, value)
+	return err
+}
+
+// importStock sets the on-hand count at a location through the inventory
+// ledger, so the change is an 'import' adjustment like any other movement.
+func importStock(ctx context.Context, tx pgx.Tx, variantID, locationID string, qty int, actor string) error {
+	_, err := inventory.SetOnHand(ctx, tx, variantID, locationID, qty, actor)
+	if errors.Is(err, inventory.ErrBelowReserved) {
+		return &importRowErr{field: "stock", msg: "below the units reserved at this location"}
+	}
+	return err
+}
+
+func (r *PostgresRepository) ExportRows(ctx context.Context) ([]TransferRow, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// products without variants get one row with the variant columns blank
+	rows, err := tx.Query(ctx, `
+SELECT p.id::text, v.id::text, p.slug, p.name, p.description, p.is_active,
+       COALESCE(v.sku, ''), COALESCE(v.name, ''), v.price, v.compare_at_price, v.weight_grams, v.is_active
+FROM products p
+LEFT JOIN product_variants v ON v.product_id = p.id
+ORDER BY p.created_at ASC, p.id ASC, v.created_at ASC, v.id ASC;
+`)
+	if err != nil {
+		return nil, err
+	}
+	var out []TransferRow
+	productOf := []string{}
+	byVariant := map[string]int{}
+	for rows.Next() {
+		var productID string
+		var variantID *string
+		var pActive bool
+		row := TransferRow{Categories: []string{}, Images: []string{}}
+		if err := rows.Scan(&productID, &variantID, &row.ProductSlug, &row.ProductName, &row.ProductDescription, &pActive,
+			&row.SKU, &row.VariantName, &row.Price, &row.CompareAtPrice, &row.WeightGrams, &row.VariantActive); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		row.ProductActive = &pActive
+		if variantID != nil {
+			byVariant[*variantID] = len(out)
+		}
+		productOf = append(productOf, productID)
+		out = append(out, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// product-level lists, fanned out to every row of the product
+	perProduct := func(sql string, add func(row *TransferRow, v string)) error {
+		rows, err := tx.Query(ctx, sql)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		vals := map[string][]string{}
+		for rows.Next() {
+			var pid, v string
+			if err := rows.Scan(&pid, &v); err != nil {
+				return err
+			}
+			vals[pid] = append(vals[pid], v)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for i := range out {
+			for _, v := range vals[productOf[i]] {
+				add(&out[i], v)
+			}
+		}
+		return nil
+	}
+	if err := perProduct(`
+WITH RECURSIVE up AS (
+  SELECT pc.product_id, c.parent_id, c.slug::text AS path
+  FROM product_categories pc
+  JOIN categories c ON c.id = pc.category_id
+  UNION ALL
+  SELECT up.product_id, c.parent_id, c.slug || '/' || up.path
+  FROM up
+  JOIN categories c ON c.id = up.parent_id
+)
+SELECT product_id::text, path FROM up WHERE parent_id IS NULL ORDER BY product_id, path;
+`, func(row *TransferRow, v string) { row.Categories = append(row.Categories, v) }); err != nil {
+		return nil, err
+	}
+	if err := perProduct(`
+SELECT product_id::text, url FROM product_images ORDER BY product_id, position ASC, created_at ASC;
+`, func(row *TransferRow, v string) { row.Images = append(row.Images, v) }); err != nil {
+		return nil, err
+	}
+
+	optRows, err := tx.Query(ctx, `
+SELECT pvov.variant_id::text, o.name, ov.value
+FROM product_variant_option_values pvov
+JOIN product_options o ON o.id = pvov.option_id
+JOIN product_option_values ov ON ov.id = pvov.value_id;
+`)
+	if err != nil {
+		return nil, err
+	}
+	for optRows.Next() {
+		var vid, name, value string
+		if err := optRows.Scan(&vid, &name, &value); err != nil {
+			optRows.Close()
+			return nil, err
+		}
+		if i, ok := byVariant[vid]; ok {
+			if out[i].Options == nil {
+				out[i].Options = map[string]string{}
+			}
+			out[i].Options[name] = value
+		}
+	}
+	optRows.Close()
+	if err := optRows.Err(); err != nil {
+		return nil, err
+	}
+
+	stockRows, err := tx.Query(ctx, `
+SELECT ii.variant_id::text, l.code, ii.stock_on_hand
+FROM inventory_items ii
+JOIN locations l ON l.id = ii.location_id;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer stockRows.Close()
+	for stockRows.Next() {
+		var vid, code string
+		var qty int
+		if err := stockRows.Scan(&vid, &code, &qty); err != nil {
+			return nil, err
+		}
+		if i, ok := byVariant[vid]; ok {
+			if out[i].Stock == nil {
+				out[i].Stock = map[string]int{}
+			}
+			out[i].Stock[code] = qty
+		}
+	}
+	return out, stockRows.Err()
+}
+
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			switch pgErr.ConstraintName {
+			case "products_slug_key":
+				return ErrSlugTaken
+			case "product_variants_sku_key":
+				return ErrSKUTaken
+			case "categories_slug_key":
+				return ErrSlugTaken
+			case "uq_variants_option_signature":
+				return ErrCombinationTaken
+			case "uq_product_options_name", "uq_product_option_values_value":
+				return ErrOptionTaken
+			}
+		case "23503", "22P02": // foreign_key_violation, invalid_text_representation (bad uuid)
+			return ErrNotFound
+		case "23514": // check_violation
+			return ErrInvalidPayload
+		}
+	}
+	return err
+}
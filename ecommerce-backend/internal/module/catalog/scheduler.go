@@ -0,0 +1,69 @@
+package catalog
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// PriceScheduler applies pending price schedules once they start and restores
+// the previous price when a sale window ends. Each transition runs in its own
+// transaction and re-checks the status, so running several API instances is safe.
+type PriceScheduler struct {
+	repo     Repository
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewPriceScheduler(repo Repository, interval time.Duration) *PriceScheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &PriceScheduler{repo: repo, interval: interval, now: time.Now}
+}
+
+// Run processes due schedules immediately and then on every tick until ctx is done.
+func (s *PriceScheduler) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("price schedule run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce returns how many schedules changed state during this pass.
+func (s *PriceScheduler) RunOnce(ctx context.Context) (int, error) {
+	now := s.now()
+	due, err := s.repo.DuePriceSchedules(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, ps := range due {
+		var changed bool
+		switch ps.Status {
+		case SchedulePending:
+			changed, err = s.repo.ApplyPriceSchedule(ctx, ps.ID, now)
+		case ScheduleActive:
+			changed, err = s.repo.RevertPriceSchedule(ctx, ps.ID, now)
+		default:
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if changed {
+			n++
+		}
+	}
+	return n, nil
+}
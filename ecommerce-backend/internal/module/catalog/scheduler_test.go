@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceScheduler_AppliesAndReverts(t *testing.T) {
+	now := time.Date(2026, 11, 27, 0, 0, 30, 0, time.UTC)
+	var calls []string
+	repo := fakeRepo{
+		dueFn: func(ctx context.Context, at time.Time) ([]PriceSchedule, error) {
+			require.Equal(t, now, at)
+			return []PriceSchedule{
+				{ID: "start", Status: SchedulePending},
+				{ID: "end", Status: ScheduleActive},
+				{ID: "raced", Status: SchedulePending}, // another instance got it first
+			}, nil
+		},
+		applyFn: func(ctx context.Context, id string, at time.Time) (bool, error) {
+			calls = append(calls, "apply:"+id)
+			return id != "raced", nil
+		},
+		revertFn: func(ctx context.Context, id string, at time.Time) (bool, error) {
+			calls = append(calls, "revert:"+id)
+			return true, nil
+		},
+	}
+	s := NewPriceScheduler(repo, 0)
+	s.now = func() time.Time { return now }
+
+	n, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{"apply:start", "revert:end", "apply:raced"}, calls)
+}
+
+func TestCoPurchaseBuilder_RebuildsLookbackWindow(t *testing.T) {
+	now := time.Date(2026, 10, 1, 6, 0, 0, 0, time.UTC)
+	repo := fakeRepo{
+		rebuildFn: func(ctx context.Context, since time.Time, perProduct int) (int, error) {
+			require.Equal(t, now.AddDate(0, 0, -180), since)
+			require.Equal(t, 20, perProduct)
+			return 42, nil
+		},
+	}
+	b := NewCoPurchaseBuilder(repo, 0)
+	b.now = func() time.Time { return now }
+
+	n, err := b.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 42, n)
+}
@@ -3,9 +3,37 @@ package catalog
 import (
 	"context"
 	"errors"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/synchhans/ecommerce-backend/internal/platform/money"
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
+)
+
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrInvalidPayload = errors.New("invalid payload")
+	ErrSlugTaken      = errors.New("slug already taken")
+	ErrSKUTaken       = errors.New("sku already taken")
+	ErrInvalidParent  = errors.New("invalid parent category")
+
+	ErrInvalidOptions   = errors.New("options must name one existing value per product option")
+	ErrCombinationTaken = errors.New("another variant already has this option combination")
+	ErrOptionTaken      = errors.New("option or value already exists")
+
+	ErrScheduleConflict   = errors.New("price schedule overlaps an existing one")
+	ErrScheduleNotPending = errors.New("price schedule already started")
 )
 
-var ErrNotFound = errors.New("not found")
+// MovedError reports a product slug that was renamed; Slug is the current one.
+type MovedError struct {
+	Slug string
+}
+
+func (e *MovedError) Error() string { return "product moved to " + e.Slug }
 
 type Service struct {
 	repo Repository
@@ -15,18 +43,755 @@ func NewService(repo Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) ListProducts(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error) {
-	return s.repo.ListProducts(ctx, limit, offset, search)
+func (s *Service) ListProducts(ctx context.Context, p ListParams) (pagination.Page[ProductListItem], *Facets, error) {
+	var page pagination.Page[ProductListItem]
+	switch p.Sort {
+	case "":
+		p.Sort = SortNewest
+	case SortNewest, SortPriceAsc, SortPriceDesc, SortName, SortRating:
+	default:
+		return page, nil, ErrInvalidPayload
+	}
+	limit, err := preparePage(&p)
+	if err != nil {
+		return page, nil, err
+	}
+
+	rows, err := s.repo.ListProducts(ctx, p)
+	if err != nil {
+		return page, nil, err
+	}
+	page = pagination.New(rows, limit, func(last ProductListItem) any {
+		c := ProductCursor{Sort: p.Sort, ID: last.ID}
+		switch p.Sort {
+		case SortPriceAsc, SortPriceDesc:
+			c.Price = &last.MinPrice
+		case SortName:
+			c.Name = &last.Name
+		case SortRating:
+			c.Rating, c.Reviews = &last.RatingAvg, &last.RatingCount
+		default:
+			c.CreatedAt = &last.CreatedAt
+		}
+		return c
+	})
+	return finishPage(ctx, s, p, page)
 }
 
-func (s *Service) GetProductDetail(ctx context.Context, slug string) (*ProductDetail, error) {
+// FindVariant picks the variant of product slug matching selected (option name ->
+// value, case-insensitive). Every product option must be selected; keys that
+// aren't option names (tracking params etc) are ignored.
+func (s *Service) FindVariant(ctx context.Context, slug string, selected map[string]string) (*ProductVariant, error) {
+	p, err := s.GetProductDetail(ctx, slug, false, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Options) == 0 {
+		return nil, ErrInvalidOptions
+	}
+	given := make(map[string]string, len(selected))
+	for k, v := range selected {
+		given[strings.ToLower(strings.TrimSpace(k))] = strings.ToLower(strings.TrimSpace(v))
+	}
+	want := make(map[string]string, len(p.Options))
+	for _, o := range p.Options {
+		name := strings.ToLower(o.Name)
+		v, ok := given[name]
+		if !ok {
+			return nil, ErrInvalidOptions
+		}
+		want[name] = v
+	}
+
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		if !v.Active || len(v.Options) != len(want) {
+			continue
+		}
+		match := true
+		for name, val := range v.Options {
+			if want[strings.ToLower(name)] != strings.ToLower(val) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return v, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// MaxSearchQueryLen bounds /search?q to keep tsquery/trigram work predictable.
+const MaxSearchQueryLen = 200
+
+func (s *Service) Search(ctx context.Context, p ListParams) (pagination.Page[SearchResult], *Facets, error) {
+	var page pagination.Page[SearchResult]
+	p.Search = strings.TrimSpace(p.Search)
+	if p.Search == "" || len(p.Search) > MaxSearchQueryLen {
+		return page, nil, ErrInvalidPayload
+	}
+	p.Sort = SortRelevance
+	limit, err := preparePage(&p)
+	if err != nil {
+		return page, nil, err
+	}
+
+	rows, err := s.repo.SearchProducts(ctx, p)
+	if err != nil {
+		return page, nil, err
+	}
+	page = pagination.New(rows, limit, func(last SearchResult) any {
+		return ProductCursor{Sort: SortRelevance, Score: &last.Score, ID: last.ID}
+	})
+	return finishPage(ctx, s, p, page)
+}
+
+// preparePage validates the shared filters, decodes p.Cursor into p.After and
+// bumps p.Limit by one so the repository can report whether more rows exist.
+// It returns the page size the caller asked for.
+func preparePage(p *ListParams) (int, error) {
+	if (p.MinPrice != nil && *p.MinPrice < 0) ||
+		(p.MinPrice != nil && p.MaxPrice != nil && *p.MinPrice > *p.MaxPrice) ||
+		(p.MinRating != nil && !(*p.MinRating >= 0 && *p.MinRating <= 5)) { // negated so NaN fails too
+		return 0, ErrInvalidPayload
+	}
+	limit := pagination.Limit(p.Limit, pagination.DefaultLimit)
+	p.Limit = limit + 1
+
+	p.After = nil
+	if p.Cursor == "" {
+		return limit, nil
+	}
+	var c ProductCursor
+	if err := pagination.Decode(p.Cursor, &c); err != nil {
+		return 0, err
+	}
+	ok := c.ID != "" && c.Sort == p.Sort
+	switch p.Sort {
+	case SortPriceAsc, SortPriceDesc:
+		ok = ok && c.Price != nil
+	case SortName:
+		ok = ok && c.Name != nil
+	case SortRating:
+		ok = ok && c.Rating != nil && c.Reviews != nil
+	case SortRelevance:
+		ok = ok && c.Score != nil
+	default:
+		ok = ok && c.CreatedAt != nil
+	}
+	if !ok {
+		return 0, pagination.ErrInvalidCursor
+	}
+	p.After = &c
+	return limit, nil
+}
+
+// finishPage adds the optional total and the facets, which ignore the cursor.
+func finishPage[T any](ctx context.Context, s *Service, p ListParams, page pagination.Page[T]) (pagination.Page[T], *Facets, error) {
+	p.After = nil
+	if p.WithTotal {
+		n, err := s.repo.CountProducts(ctx, p)
+		if err != nil {
+			return page, nil, err
+		}
+		page.Total = &n
+	}
+	facets, err := s.repo.ProductFacets(ctx, p)
+	if err != nil {
+		return page, nil, err
+	}
+	return page, facets, nil
+}
+
+// GetProductDetail loads a product by slug. currency ("" = base prices)
+// re-prices the variants from that currency's price list.
+func (s *Service) GetProductDetail(ctx context.Context, slug string, withAvailability bool, currency string) (*ProductDetail, error) {
 	if slug == "" {
 		return nil, ErrNotFound
 	}
 	p, err := s.repo.GetProductBySlug(ctx, slug)
 	if err != nil {
-		// repository bisa return pgx.ErrNoRows; handler akan map ke 404 via ErrNotFound
-		return nil, err
+		return nil, s.movedOr(ctx, slug, err)
+	}
+	if p.SEO.Title == "" {
+		p.SEO.Title = p.Name
+	}
+	if p.SEO.Description == "" {
+		p.SEO.Description = excerpt(p.Description, metaDescriptionLen)
+	}
+	if withAvailability && len(p.Variants) > 0 {
+		ids := make([]string, 0, len(p.Variants))
+		for _, v := range p.Variants {
+			ids = append(ids, v.ID)
+		}
+		avail, err := s.repo.AvailableQty(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range p.Variants {
+			n := avail[p.Variants[i].ID]
+			p.Variants[i].Availability = &AvailabilitySummary{Available: n, InStock: n > 0}
+		}
+	}
+	if currency != "" {
+		if err := s.applyCurrency(ctx, p, currency); err != nil {
+			return nil, err
+		}
 	}
 	return p, nil
 }
+
+func (s *Service) applyCurrency(ctx context.Context, p *ProductDetail, currency string) error {
+	currency = money.Normalize(currency)
+	if !money.ValidCode(currency) {
+		return ErrInvalidPayload
+	}
+	ids := make([]string, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		ids = append(ids, v.ID)
+	}
+	prices, err := s.repo.PricesIn(ctx, ids, currency)
+	if err != nil {
+		return err
+	}
+	p.Currency = currency
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		cp, ok := prices[v.ID]
+		if !ok {
+			v.Price, v.CompareAtPrice = 0, nil
+			v.PriceUnavailable = true
+		} else {
+			v.Price, v.CompareAtPrice = cp.Price, cp.CompareAtPrice
+		}
+		v.OnSale = OnSale(v.Price, v.CompareAtPrice)
+		v.DiscountPercent = DiscountPercent(v.Price, v.CompareAtPrice)
+	}
+	return nil
+}
+
+// Recommendation lists are short rails, not pages.
+const (
+	defaultRecommendations = 8
+	maxRecommendations     = 24
+)
+
+// RelatedProducts lists listed products sharing a category with slug whose
+// cheapest price is within half to double of its own, closest price first.
+func (s *Service) RelatedProducts(ctx context.Context, slug string, limit int) ([]ProductListItem, error) {
+	if slug == "" {
+		return nil, ErrNotFound
+	}
+	items, err := s.repo.RelatedProducts(ctx, slug, min(pagination.Limit(limit, defaultRecommendations), maxRecommendations))
+	if err != nil {
+		return nil, s.movedOr(ctx, slug, err)
+	}
+	return items, nil
+}
+
+// FrequentlyBoughtTogether lists the products most often in the same paid
+// order as slug, as of the last co-purchase rebuild.
+func (s *Service) FrequentlyBoughtTogether(ctx context.Context, slug string, limit int) ([]CoPurchase, error) {
+	if slug == "" {
+		return nil, ErrNotFound
+	}
+	items, err := s.repo.CoPurchased(ctx, slug, min(pagination.Limit(limit, defaultRecommendations), maxRecommendations))
+	if err != nil {
+		return nil, s.movedOr(ctx, slug, err)
+	}
+	return items, nil
+}
+
+// movedOr turns a not-found slug that a product was renamed from into a
+// *MovedError; any other error is returned as is.
+func (s *Service) movedOr(ctx context.Context, slug string, err error) error {
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	current, lerr := s.repo.CurrentSlug(ctx, slug)
+	if errors.Is(lerr, ErrNotFound) {
+		return err
+	}
+	if lerr != nil {
+		return lerr
+	}
+	return &MovedError{Slug: current}
+}
+
+// maxSitemapURLs is the sitemap protocol's limit for one file.
+const maxSitemapURLs = 50000
+
+// Sitemap lists the storefront pages to index: categories first, then the
+// most recently changed products up to the sitemap limit.
+func (s *Service) Sitemap(ctx context.Context) ([]SitemapEntry, error) {
+	entries, err := s.repo.SitemapEntries(ctx, maxSitemapURLs)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(entries, func(a, b SitemapEntry) int {
+		return strings.Compare(a.Kind, b.Kind) // category < product
+	})
+	return entries[:min(len(entries), maxSitemapURLs)], nil
+}
+
+// metaDescriptionLen is about what search engines show of a description.
+const metaDescriptionLen = 160
+
+// excerpt collapses whitespace and cuts s to at most n runes at a word
+// boundary, marking the cut with an ellipsis.
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	cut := string(r[:n-1])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
+
+func (s *Service) CategoryTree(ctx context.Context) ([]Category, error) {
+	flat, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCategoryTree(flat), nil
+}
+
+func (s *Service) CategoryProducts(ctx context.Context, slug string, p ListParams) (*Category, pagination.Page[ProductListItem], *Facets, error) {
+	var page pagination.Page[ProductListItem]
+	if slug == "" {
+		return nil, page, nil, ErrNotFound
+	}
+	c, err := s.repo.GetCategoryBySlug(ctx, slug)
+	if err != nil {
+		return nil, page, nil, err
+	}
+	p.Category = c.Slug
+	page, facets, err := s.ListProducts(ctx, p)
+	if err != nil {
+		return nil, page, nil, err
+	}
+	return c, page, facets, nil
+}
+
+// BuildCategoryTree nests a flat list by parent_id, keeping the input order among
+// siblings. Categories whose parent is missing from the list become roots.
+func BuildCategoryTree(flat []Category) []Category {
+	children := make(map[string][]Category, len(flat))
+	ids := make(map[string]struct{}, len(flat))
+	for _, c := range flat {
+		ids[c.ID] = struct{}{}
+	}
+	roots := []Category{}
+	for _, c := range flat {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+			continue
+		}
+		if _, ok := ids[*c.ParentID]; !ok {
+			roots = append(roots, c)
+			continue
+		}
+		children[*c.ParentID] = append(children[*c.ParentID], c)
+	}
+
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		for i := range nodes {
+			if kids := children[nodes[i].ID]; len(kids) > 0 {
+				nodes[i].Children = attach(kids)
+			}
+		}
+		return nodes
+	}
+	return attach(roots)
+}
+
+// ===== Admin =====
+
+func (s *Service) CreateProduct(ctx context.Context, in ProductInput) (*Product, error) {
+	if in.Name == nil || strings.TrimSpace(*in.Name) == "" {
+		return nil, ErrInvalidPayload
+	}
+	if in.Slug == nil || strings.TrimSpace(*in.Slug) == "" {
+		slug := Slugify(*in.Name)
+		in.Slug = &slug
+	}
+	in, err := normalizeProduct(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateProduct(ctx, in)
+}
+
+func (s *Service) UpdateProduct(ctx context.Context, productID string, in ProductInput) (*Product, error) {
+	if productID == "" {
+		return nil, ErrNotFound
+	}
+	in, err := normalizeProduct(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateProduct(ctx, productID, in)
+}
+
+// ArchiveProduct hides a product from the storefront; rows are kept for order history.
+func (s *Service) ArchiveProduct(ctx context.Context, productID string) (*Product, error) {
+	inactive := false
+	return s.UpdateProduct(ctx, productID, ProductInput{IsActive: &inactive})
+}
+
+func (s *Service) CreateVariant(ctx context.Context, productID string, in VariantInput) (*Variant, error) {
+	if productID == "" {
+		return nil, ErrNotFound
+	}
+	if in.SKU == nil || in.Name == nil || in.Price == nil {
+		return nil, ErrInvalidPayload
+	}
+	in, err := normalizeVariant(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateVariant(ctx, productID, in)
+}
+
+func (s *Service) UpdateVariant(ctx context.Context, variantID string, in VariantInput) (*Variant, error) {
+	if variantID == "" {
+		return nil, ErrNotFound
+	}
+	in, err := normalizeVariant(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateVariant(ctx, variantID, in)
+}
+
+func (s *Service) DeactivateVariant(ctx context.Context, variantID string) (*Variant, error) {
+	inactive := false
+	return s.UpdateVariant(ctx, variantID, VariantInput{IsActive: &inactive})
+}
+
+func (s *Service) AddImage(ctx context.Context, productID, url string, position *int) (*ProductImage, error) {
+	url = strings.TrimSpace(url)
+	if productID == "" || url == "" || (position != nil && *position < 0) {
+		return nil, ErrInvalidPayload
+	}
+	return s.repo.AddImage(ctx, productID, url, position)
+}
+
+func (s *Service) ReorderImages(ctx context.Context, productID string, imageIDs []string) ([]ProductImage, error) {
+	seen := make(map[string]struct{}, len(imageIDs))
+	for _, id := range imageIDs {
+		if _, dup := seen[id]; dup || id == "" {
+			return nil, ErrInvalidPayload
+		}
+		seen[id] = struct{}{}
+	}
+	return s.repo.ReorderImages(ctx, productID, imageIDs)
+}
+
+func (s *Service) RemoveImage(ctx context.Context, productID, imageID string) error {
+	if productID == "" || imageID == "" {
+		return ErrNotFound
+	}
+	return s.repo.RemoveImage(ctx, productID, imageID)
+}
+
+func normalizeProduct(in ProductInput) (ProductInput, error) {
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" {
+			return in, ErrInvalidPayload
+		}
+		in.Name = &name
+	}
+	if in.Slug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*in.Slug))
+		if slug == "" || slug != Slugify(slug) {
+			return in, ErrInvalidPayload
+		}
+		in.Slug = &slug
+	}
+	for _, f := range []*string{in.MetaTitle, in.MetaDescription, in.CanonicalURL} {
+		if f != nil {
+			*f = strings.TrimSpace(*f)
+		}
+	}
+	if in.CanonicalURL != nil && *in.CanonicalURL != "" {
+		u, err := url.Parse(*in.CanonicalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return in, ErrInvalidPayload
+		}
+	}
+	return in, nil
+}
+
+func normalizeVariant(in VariantInput) (VariantInput, error) {
+	if in.SKU != nil {
+		sku := strings.TrimSpace(*in.SKU)
+		if sku == "" {
+			return in, ErrInvalidPayload
+		}
+		in.SKU = &sku
+	}
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" {
+			return in, ErrInvalidPayload
+		}
+		in.Name = &name
+	}
+	if (in.Price != nil && *in.Price < 0) ||
+		(in.CompareAtPrice != nil && *in.CompareAtPrice < 0) ||
+		(in.WeightGrams != nil && *in.WeightGrams < 0) {
+		return in, ErrInvalidPayload
+	}
+	return in, nil
+}
+
+// Slugify lowercases s and collapses anything outside [a-z0-9] into single dashes.
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func (s *Service) CreateCategory(ctx context.Context, in CategoryInput) (*Category, error) {
+	if in.Name == nil || strings.TrimSpace(*in.Name) == "" {
+		return nil, ErrInvalidPayload
+	}
+	if in.Slug == nil || strings.TrimSpace(*in.Slug) == "" {
+		slug := Slugify(*in.Name)
+		in.Slug = &slug
+	}
+	in, err := normalizeCategory(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateCategory(ctx, in)
+}
+
+func (s *Service) UpdateCategory(ctx context.Context, categoryID string, in CategoryInput) (*Category, error) {
+	if categoryID == "" {
+		return nil, ErrNotFound
+	}
+	if in.ParentID != nil && *in.ParentID == categoryID {
+		return nil, ErrInvalidParent
+	}
+	in, err := normalizeCategory(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateCategory(ctx, categoryID, in)
+}
+
+func (s *Service) DeleteCategory(ctx context.Context, categoryID string) error {
+	if categoryID == "" {
+		return ErrNotFound
+	}
+	return s.repo.DeleteCategory(ctx, categoryID)
+}
+
+// SetProductCategories replaces the product's category assignment.
+func (s *Service) SetProductCategories(ctx context.Context, productID string, categoryIDs []string) ([]Category, error) {
+	if productID == "" {
+		return nil, ErrNotFound
+	}
+	ids := make([]string, 0, len(categoryIDs))
+	seen := make(map[string]struct{}, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if id == "" {
+			return nil, ErrInvalidPayload
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return s.repo.SetProductCategories(ctx, productID, ids)
+}
+
+func normalizeCategory(in CategoryInput) (CategoryInput, error) {
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" {
+			return in, ErrInvalidPayload
+		}
+		in.Name = &name
+	}
+	if in.Slug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*in.Slug))
+		if slug == "" || slug != Slugify(slug) {
+			return in, ErrInvalidPayload
+		}
+		in.Slug = &slug
+	}
+	return in, nil
+}
+
+func (s *Service) CreateOption(ctx context.Context, productID string, in OptionInput) (*ProductOption, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if productID == "" || in.Name == "" || len(in.Values) == 0 {
+		return nil, ErrInvalidPayload
+	}
+	seen := make(map[string]struct{}, len(in.Values))
+	for i, v := range in.Values {
+		v = strings.TrimSpace(v)
+		key := strings.ToLower(v)
+		if _, dup := seen[key]; dup || v == "" {
+			return nil, ErrInvalidPayload
+		}
+		seen[key] = struct{}{}
+		in.Values[i] = v
+	}
+	return s.repo.CreateOption(ctx, productID, in)
+}
+
+func (s *Service) AddOptionValue(ctx context.Context, optionID, value string) (*OptionValue, error) {
+	value = strings.TrimSpace(value)
+	if optionID == "" || value == "" {
+		return nil, ErrInvalidPayload
+	}
+	return s.repo.AddOptionValue(ctx, optionID, value)
+}
+
+func (s *Service) SetVariantOptions(ctx context.Context, variantID string, options map[string]string) (map[string]string, error) {
+	if variantID == "" {
+		return nil, ErrNotFound
+	}
+	return s.repo.SetVariantOptions(ctx, variantID, options)
+}
+
+// SchedulePrice queues a price change for a variant. StartsAt may be in the
+// past; the scheduler then applies it on its next pass.
+func (s *Service) SchedulePrice(ctx context.Context, variantID, actor string, in PriceScheduleInput) (*PriceSchedule, error) {
+	if variantID == "" || in.Price == nil || *in.Price < 0 || in.StartsAt == nil {
+		return nil, ErrInvalidPayload
+	}
+	if in.CompareAtPrice != nil && *in.CompareAtPrice < 0 {
+		return nil, ErrInvalidPayload
+	}
+	if in.EndsAt != nil && !in.EndsAt.After(*in.StartsAt) {
+		return nil, ErrInvalidPayload
+	}
+	if actor == "" {
+		actor = "system"
+	}
+	var endsAt *time.Time
+	if in.EndsAt != nil {
+		t := in.EndsAt.UTC()
+		endsAt = &t
+	}
+	return s.repo.CreatePriceSchedule(ctx, PriceSchedule{
+		VariantID:      variantID,
+		Price:          *in.Price,
+		CompareAtPrice: in.CompareAtPrice,
+		StartsAt:       in.StartsAt.UTC(),
+		EndsAt:         endsAt,
+		CreatedBy:      actor,
+	})
+}
+
+func (s *Service) PriceSchedules(ctx context.Context, variantID string) ([]PriceSchedule, error) {
+	if variantID == "" {
+		return nil, ErrNotFound
+	}
+	return s.repo.ListPriceSchedules(ctx, variantID)
+}
+
+func (s *Service) CancelPriceSchedule(ctx context.Context, scheduleID string) (*PriceSchedule, error) {
+	if scheduleID == "" {
+		return nil, ErrNotFound
+	}
+	return s.repo.CancelPriceSchedule(ctx, scheduleID)
+}
+
+func (s *Service) PriceHistory(ctx context.Context, variantID string, limit int, cursor string) (pagination.Page[PricePoint], error) {
+	limit = pagination.Limit(limit, 50)
+	var after *PriceHistoryCursor
+	if cursor != "" {
+		after = &PriceHistoryCursor{}
+		if err := pagination.Decode(cursor, after); err != nil || after.ID == 0 {
+			return pagination.Page[PricePoint]{}, pagination.ErrInvalidCursor
+		}
+	}
+	rows, err := s.repo.ListPriceHistory(ctx, variantID, limit+1, after)
+	if err != nil {
+		return pagination.Page[PricePoint]{}, err
+	}
+	return pagination.New(rows, limit, func(last PricePoint) any {
+		return PriceHistoryCursor{ChangedAt: last.ChangedAt, ID: last.ID}
+	}), nil
+}
+
+// PriceAt answers "what did this variant cost at t".
+func (s *Service) PriceAt(ctx context.Context, variantID string, at time.Time) (*PricePoint, error) {
+	if variantID == "" || at.IsZero() {
+		return nil, ErrInvalidPayload
+	}
+	return s.repo.PriceAt(ctx, variantID, at)
+}
+
+// Import validates a bulk file and, unless dryRun, upserts it in one
+// transaction. Validation problems come back in the report, not as an error.
+func (s *Service) Import(ctx context.Context, r io.Reader, format string, dryRun bool, actor string) (*ImportReport, error) {
+	rows, errs, err := DecodeTransfer(r, format)
+	if err != nil {
+		return nil, err
+	}
+	return s.importRows(ctx, rows, errs, dryRun, actor)
+}
+
+// ImportRows is Import for rows built in code (e.g. the seeder).
+func (s *Service) ImportRows(ctx context.Context, rows []TransferRow, dryRun bool, actor string) (*ImportReport, error) {
+	return s.importRows(ctx, rows, nil, dryRun, actor)
+}
+
+func (s *Service) importRows(ctx context.Context, rows []TransferRow, errs []RowError, dryRun bool, actor string) (*ImportReport, error) {
+	// a cell that didn't parse is left empty; don't report it as missing too
+	reported := map[RowError]bool{}
+	for _, e := range errs {
+		reported[RowError{Row: e.Row, Field: e.Field}] = true
+	}
+	for _, e := range prepareTransferRows(rows) {
+		if !reported[RowError{Row: e.Row, Field: e.Field}] {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) > 0 {
+		slices.SortStableFunc(errs, func(a, b RowError) int { return a.Row - b.Row })
+		return &ImportReport{DryRun: dryRun, Rows: len(rows), Errors: errs}, nil
+	}
+	if actor == "" {
+		actor = "system"
+	}
+	return s.repo.ImportRows(ctx, rows, actor, dryRun)
+}
+
+// Export writes the whole catalog in a format Import accepts.
+func (s *Service) Export(ctx context.Context, w io.Writer, format string) error {
+	if format != FormatCSV && format != FormatJSON {
+		return ErrInvalidFormat
+	}
+	rows, err := s.repo.ExportRows(ctx)
+	if err != nil {
+		return err
+	}
+	return EncodeTransfer(w, format, rows)
+}
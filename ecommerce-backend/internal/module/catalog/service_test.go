@@ -9,7 +9,7 @@ import (
 
 func TestService_GetProductDetail_EmptySlug_NotFound(t *testing.T) {
 	repo := fakeRepo{
-		listFn: func(ctx context.Context, limit, offset int, search string) ([]ProductListItem, error) {
+		listFn: func(ctx context.Context, p ListParams) ([]ProductListItem, error) {
 			return nil, nil
 		},
 		getFn: func(ctx context.Context, slug string) (*ProductDetail, error) {
@@ -18,7 +18,39 @@ func TestService_GetProductDetail_EmptySlug_NotFound(t *testing.T) {
 	}
 
 	svc := NewService(repo)
-	p, err := svc.GetProductDetail(context.Background(), "")
+	p, err := svc.GetProductDetail(context.Background(), "", false, "")
 	require.Error(t, err)
 	require.Nil(t, p)
 }
+
+func TestSlugify(t *testing.T) {
+	require.Equal(t, "kaos-polos-hitam", Slugify("  Kaos Polos (Hitam) "))
+	require.Equal(t, "a-b", Slugify("a--b--"))
+	require.Equal(t, "", Slugify("!!!"))
+}
+
+func TestBuildCategoryTree_OrphanBecomesRoot(t *testing.T) {
+	a, missing := "a", "gone"
+	tree := BuildCategoryTree([]Category{
+		{ID: "a", Slug: "a"},
+		{ID: "b", ParentID: &a, Slug: "b"},
+		{ID: "c", ParentID: &missing, Slug: "c"},
+	})
+	require.Len(t, tree, 2)
+	require.Equal(t, "b", tree[0].Children[0].ID)
+	require.Equal(t, "c", tree[1].ID)
+}
+
+func TestDiscountPercent(t *testing.T) {
+	cmp := int64(150000)
+	require.Equal(t, 33, DiscountPercent(99900, &cmp))
+	require.True(t, OnSale(99900, &cmp))
+	require.Equal(t, 0, DiscountPercent(150000, &cmp))
+	require.False(t, OnSale(150000, &cmp))
+	require.Equal(t, 0, DiscountPercent(1000, nil))
+}
+
+func TestExcerpt(t *testing.T) {
+	require.Equal(t, "Kaos katun", excerpt("  Kaos\n katun ", 160))
+	require.Equal(t, "Kaos katun…", excerpt("Kaos katun combed, sablon", 14))
+}
@@ -0,0 +1,439 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Bulk import/export format: one row per variant. Product columns repeat on
+// every row of the product; blank product columns on later rows inherit from
+// the first row that sets them. Products are matched by slug and variants by
+// SKU (upsert). A row with only product columns carries a product without
+// variants.
+//
+// In CSV, list and map cells are "|"-separated: categories "apparel/tops|sale",
+// images "https://a|https://b", options "Size=M|Color=Black", stock "MAIN=10|SBY=4".
+// A category is its slug path from the root; missing categories are created.
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var transferColumns = []string{
+	"product_slug", "product_name", "product_description", "product_active",
+	"categories", "images",
+	"sku", "variant_name", "price", "compare_at_price", "weight_grams", "variant_active",
+	"options", "stock",
+}
+
+// TransferRow is one variant in an import/export file. Nil Categories, Images,
+// Options or Stock leave the stored value unchanged on import.
+type TransferRow struct {
+	ProductSlug        string   `json:"product_slug"`
+	ProductName        string   `json:"product_name"`
+	ProductDescription string   `json:"product_description"`
+	ProductActive      *bool    `json:"product_active,omitempty"` // nil = true for new products
+	Categories         []string `json:"categories"`
+	Images             []string `json:"images"`
+
+	SKU            string `json:"sku"`
+	VariantName    string `json:"variant_name"`
+	Price          *int64 `json:"price"`
+	CompareAtPrice *int64 `json:"compare_at_price"`
+	WeightGrams    *int   `json:"weight_grams"`
+	VariantActive  *bool  `json:"variant_active,omitempty"` // nil = true for new variants
+
+	Options map[string]string `json:"options,omitempty"`
+	Stock   map[string]int    `json:"stock,omitempty"` // location code -> stock on hand
+}
+
+// productOnly reports whether the row leaves every variant column blank.
+func (row *TransferRow) productOnly() bool {
+	return row.SKU == "" && row.VariantName == "" && row.Price == nil && row.CompareAtPrice == nil &&
+		row.WeightGrams == nil && row.VariantActive == nil && len(row.Options) == 0 && len(row.Stock) == 0
+}
+
+// RowError reports a problem with one import row (1 = first data row).
+type RowError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport summarises an import. Nothing is written unless Applied: a
+// dry run, or any row error, rolls the whole file back.
+type ImportReport struct {
+	DryRun          bool       `json:"dry_run"`
+	Applied         bool       `json:"applied"`
+	Rows            int        `json:"rows"`
+	ProductsCreated int        `json:"products_created"`
+	ProductsUpdated int        `json:"products_updated"`
+	VariantsCreated int        `json:"variants_created"`
+	VariantsUpdated int        `json:"variants_updated"`
+	Errors          []RowError `json:"errors"`
+}
+
+var ErrInvalidFormat = errors.New("invalid import format")
+
+// DecodeTransfer parses an import file. Cells that don't parse become row
+// errors; a malformed file (bad JSON, unknown CSV header) is ErrInvalidFormat.
+func DecodeTransfer(r io.Reader, format string) ([]TransferRow, []RowError, error) {
+	switch format {
+	case FormatJSON:
+		var rows []TransferRow
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+		}
+		return rows, nil, nil
+	case FormatCSV:
+		return decodeCSV(r)
+	}
+	return nil, nil, ErrInvalidFormat
+}
+
+func decodeCSV(r io.Reader) ([]TransferRow, []RowError, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1 // short rows just leave trailing columns blank
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if !slices.Contains(transferColumns, h) {
+			return nil, nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFormat, h)
+		}
+		col[h] = i
+	}
+	for _, required := range []string{"sku", "price"} {
+		if _, ok := col[required]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing column %q", ErrInvalidFormat, required)
+		}
+	}
+
+	var rows []TransferRow
+	var errs []RowError
+	for n := 1; ; n++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+		}
+		cell := func(name string) (string, bool) {
+			i, ok := col[name]
+			if !ok || i >= len(rec) {
+				return "", ok
+			}
+			return strings.TrimSpace(rec[i]), true
+		}
+		var row TransferRow
+		fail := func(field string, err error) {
+			errs = append(errs, RowError{Row: n, SKU: row.SKU, Field: field, Message: err.Error()})
+		}
+
+		row.SKU, _ = cell("sku")
+		row.ProductSlug, _ = cell("product_slug")
+		row.ProductName, _ = cell("product_name")
+		row.ProductDescription, _ = cell("product_description")
+		row.VariantName, _ = cell("variant_name")
+
+		var perr error
+		if v, _ := cell("product_active"); v != "" {
+			if row.ProductActive, perr = parseBoolCell(v); perr != nil {
+				fail("product_active", perr)
+			}
+		}
+		if v, _ := cell("variant_active"); v != "" {
+			if row.VariantActive, perr = parseBoolCell(v); perr != nil {
+				fail("variant_active", perr)
+			}
+		}
+		if v, _ := cell("price"); v != "" {
+			if row.Price, perr = parseIntCell[int64](v); perr != nil {
+				fail("price", perr)
+			}
+		}
+		if v, _ := cell("compare_at_price"); v != "" {
+			if row.CompareAtPrice, perr = parseIntCell[int64](v); perr != nil {
+				fail("compare_at_price", perr)
+			}
+		}
+		if v, _ := cell("weight_grams"); v != "" {
+			if row.WeightGrams, perr = parseIntCell[int](v); perr != nil {
+				fail("weight_grams", perr)
+			}
+		}
+		// a present column is authoritative, even when blank
+		if v, ok := cell("categories"); ok {
+			row.Categories = splitList(v)
+		}
+		if v, ok := cell("images"); ok {
+			row.Images = splitList(v)
+		}
+		if v, _ := cell("options"); v != "" {
+			if row.Options, perr = splitPairs(v); perr != nil {
+				fail("options", perr)
+			}
+		}
+		if v, _ := cell("stock"); v != "" {
+			pairs, err := splitPairs(v)
+			if err != nil {
+				fail("stock", err)
+			} else {
+				row.Stock = make(map[string]int, len(pairs))
+				for code, qty := range pairs {
+					q, err := strconv.Atoi(qty)
+					if err != nil {
+						fail("stock", fmt.Errorf("%s: not a number", code))
+						continue
+					}
+					row.Stock[code] = q
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs, nil
+}
+
+// EncodeTransfer writes rows in the same format DecodeTransfer reads.
+func EncodeTransfer(w io.Writer, format string, rows []TransferRow) error {
+	switch format {
+	case FormatJSON:
+		if rows == nil {
+			rows = []TransferRow{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(transferColumns); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := cw.Write([]string{
+				row.ProductSlug, row.ProductName, row.ProductDescription, formatBool(row.ProductActive),
+				strings.Join(row.Categories, "|"), strings.Join(row.Images, "|"),
+				row.SKU, row.VariantName, formatInt(row.Price), formatInt(row.CompareAtPrice), formatInt(row.WeightGrams), formatBool(row.VariantActive),
+				joinPairs(row.Options), joinPairs(row.Stock),
+			}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return ErrInvalidFormat
+}
+
+// prepareTransferRows normalises rows in place (trimmed SKUs, derived slugs,
+// product columns inherited from the product's first row) and returns every
+// validation error. Rows are only sent to the database when this is empty.
+func prepareTransferRows(rows []TransferRow) []RowError {
+	var errs []RowError
+	first := map[string]int{} // slug -> index of the product's first row
+	skus := map[string]int{}  // sku -> row number
+
+	for i := range rows {
+		row := &rows[i]
+		n := i + 1
+		fail := func(field, msg string) {
+			errs = append(errs, RowError{Row: n, SKU: row.SKU, Field: field, Message: msg})
+		}
+
+		row.SKU = strings.TrimSpace(row.SKU)
+		row.ProductSlug = strings.ToLower(strings.TrimSpace(row.ProductSlug))
+		row.ProductName = strings.TrimSpace(row.ProductName)
+		row.VariantName = strings.TrimSpace(row.VariantName)
+
+		if !row.productOnly() {
+			if row.SKU == "" {
+				fail("sku", "required")
+			} else if prev, dup := skus[row.SKU]; dup {
+				fail("sku", fmt.Sprintf("duplicate of row %d", prev))
+			} else {
+				skus[row.SKU] = n
+			}
+			if row.VariantName == "" {
+				fail("variant_name", "required")
+			}
+			if row.Price == nil {
+				fail("price", "required")
+			} else if *row.Price < 0 {
+				fail("price", "must be >= 0")
+			}
+		}
+		if row.CompareAtPrice != nil && *row.CompareAtPrice < 0 {
+			fail("compare_at_price", "must be >= 0")
+		}
+		if row.WeightGrams != nil && *row.WeightGrams < 0 {
+			fail("weight_grams", "must be >= 0")
+		}
+		for name, val := range row.Options {
+			if strings.TrimSpace(name) == "" || strings.TrimSpace(val) == "" {
+				fail("options", "empty option name or value")
+			}
+		}
+		for code, qty := range row.Stock {
+			if strings.TrimSpace(code) == "" {
+				fail("stock", "empty location code")
+			}
+			if qty < 0 {
+				fail("stock", code+": must be >= 0")
+			}
+		}
+		for _, path := range row.Categories {
+			for _, seg := range strings.Split(path, "/") {
+				if seg == "" || seg != Slugify(seg) {
+					fail("categories", fmt.Sprintf("%q is not a slug path", path))
+					break
+				}
+			}
+		}
+		for _, u := range row.Images {
+			if strings.TrimSpace(u) == "" {
+				fail("images", "empty url")
+			}
+		}
+
+		if row.ProductSlug == "" {
+			row.ProductSlug = Slugify(row.ProductName)
+		}
+		if row.ProductSlug == "" || row.ProductSlug != Slugify(row.ProductSlug) {
+			fail("product_slug", "required (or a product_name to derive it from) and must be a slug")
+			continue
+		}
+
+		fi, seen := first[row.ProductSlug]
+		if !seen {
+			first[row.ProductSlug] = i
+			continue
+		}
+		// later rows of the product: blank inherits, anything else must match
+		p := &rows[fi]
+		inherit := func(field string, dst *string, src string) {
+			switch {
+			case *dst == "":
+				*dst = src
+			case src == "":
+				// first row left it blank; the product takes this row's value
+			case *dst != src:
+				fail(field, fmt.Sprintf("conflicts with row %d", fi+1))
+			}
+		}
+		inherit("product_name", &p.ProductName, row.ProductName)
+		inherit("product_description", &p.ProductDescription, row.ProductDescription)
+		if row.ProductActive != nil && p.ProductActive != nil && *row.ProductActive != *p.ProductActive {
+			fail("product_active", fmt.Sprintf("conflicts with row %d", fi+1))
+		} else if p.ProductActive == nil {
+			p.ProductActive = row.ProductActive
+		}
+		inheritList := func(field string, dst *[]string, src []string) {
+			switch {
+			case len(src) == 0:
+				if *dst == nil {
+					*dst = src
+				}
+			case len(*dst) == 0:
+				*dst = src
+			case !slices.Equal(*dst, src):
+				fail(field, fmt.Sprintf("conflicts with row %d", fi+1))
+			}
+		}
+		inheritList("categories", &p.Categories, row.Categories)
+		inheritList("images", &p.Images, row.Images)
+	}
+
+	// second pass: products need a name, and every row carries the product's columns
+	for i := range rows {
+		row := &rows[i]
+		fi, ok := first[row.ProductSlug]
+		if !ok {
+			continue
+		}
+		p := rows[fi]
+		if i == fi && p.ProductName == "" {
+			errs = append(errs, RowError{Row: i + 1, SKU: row.SKU, Field: "product_name", Message: "required"})
+		}
+		row.ProductName, row.ProductDescription, row.ProductActive = p.ProductName, p.ProductDescription, p.ProductActive
+		row.Categories, row.Images = p.Categories, p.Images
+	}
+
+	slices.SortStableFunc(errs, func(a, b RowError) int { return a.Row - b.Row })
+	return errs
+}
+
+func parseBoolCell(s string) (*bool, error) {
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return nil, errors.New("not a boolean")
+	}
+	return &b, nil
+}
+
+func parseIntCell[T int | int64](s string) (*T, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, errors.New("not an integer")
+	}
+	v := T(n)
+	return &v, nil
+}
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, part := range strings.Split(s, "|") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func splitPairs(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, part := range splitList(s) {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected name=value", part)
+		}
+		out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return out, nil
+}
+
+func joinPairs[V string | int](m map[string]V) string {
+	parts := make([]string, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, m[k]))
+	}
+	return strings.Join(parts, "|")
+}
+
+func formatBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+func formatInt[T int | int64](n *T) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(*n), 10)
+}
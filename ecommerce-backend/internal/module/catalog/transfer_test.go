@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfer_CSVRoundTrip(t *testing.T) {
+	price, compare, weight, active := int64(99000), int64(120000), 250, false
+	in := []TransferRow{{
+		ProductSlug: "basic-tee", ProductName: "Basic Tee", ProductDescription: "Cotton, \"heavy\"",
+		ProductActive: &active, Categories: []string{"apparel/tops", "sale"}, Images: []string{"https://img/1.jpg"},
+		SKU: "TEE-M", VariantName: "M / Black", Price: &price, CompareAtPrice: &compare, WeightGrams: &weight,
+		Options: map[string]string{"Size": "M", "Color": "Black"}, Stock: map[string]int{"MAIN": 5, "SBY": 0},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeTransfer(&buf, FormatCSV, in))
+	out, errs, err := DecodeTransfer(&buf, FormatCSV)
+	require.NoError(t, err)
+	require.Empty(t, errs)
+	require.Equal(t, in, out)
+}
+
+func TestTransfer_DecodeCSV_Malformed(t *testing.T) {
+	_, _, err := DecodeTransfer(strings.NewReader("sku,colour\nA,red\n"), FormatCSV)
+	require.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, _, err = DecodeTransfer(strings.NewReader("product_name,variant_name\n"), FormatCSV)
+	require.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestTransfer_PrepareRows(t *testing.T) {
+	p := func(n int64) *int64 { return &n }
+	rows := []TransferRow{
+		{ProductName: "Basic Tee", SKU: " TEE-M ", VariantName: "M", Price: p(1), Categories: []string{"apparel"}},
+		{ProductSlug: "basic-tee", SKU: "TEE-L", VariantName: "L", Price: p(1)},
+		{ProductSlug: "basic-tee", ProductName: "Other Name", SKU: "TEE-XL", VariantName: "XL", Price: p(1)},
+		{ProductSlug: "mug", SKU: "TEE-M", VariantName: "Mug", Price: p(-1), Categories: []string{"Bad Slug"}},
+	}
+	errs := prepareTransferRows(rows)
+
+	require.Equal(t, "TEE-M", rows[0].SKU)
+	require.Equal(t, "basic-tee", rows[0].ProductSlug)
+	// blank product columns inherit from the first row
+	require.Equal(t, "Basic Tee", rows[1].ProductName)
+	require.Equal(t, []string{"apparel"}, rows[1].Categories)
+
+	fields := map[int][]string{}
+	for _, e := range errs {
+		fields[e.Row] = append(fields[e.Row], e.Field)
+	}
+	require.Equal(t, []string{"product_name"}, fields[3])
+	require.ElementsMatch(t, []string{"sku", "price", "categories", "product_name"}, fields[4])
+}
+
+func TestTransfer_ProductWithoutVariants(t *testing.T) {
+	active := true
+	in := []TransferRow{{
+		ProductSlug: "gift-card", ProductName: "Gift Card", ProductActive: &active,
+		Categories: []string{}, Images: []string{},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeTransfer(&buf, FormatCSV, in))
+	out, errs, err := DecodeTransfer(&buf, FormatCSV)
+	require.NoError(t, err)
+	require.Empty(t, errs)
+	require.Equal(t, in, out)
+
+	require.Empty(t, prepareTransferRows(out))
+	require.True(t, out[0].productOnly())
+
+	// a variant row still needs its sku and price
+	half := []TransferRow{{ProductSlug: "gift-card", ProductName: "Gift Card", VariantName: "Rp100k"}}
+	fields := []string{}
+	for _, e := range prepareTransferRows(half) {
+		fields = append(fields, e.Field)
+	}
+	require.ElementsMatch(t, []string{"sku", "price"}, fields)
+}
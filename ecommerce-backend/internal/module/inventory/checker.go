@@ -0,0 +1,110 @@
+package inventory
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// AlertNotifier receives low-stock and out-of-stock events.
+type AlertNotifier interface {
+	NotifyStockAlert(ctx context.Context, a StockAlert) error
+}
+
+// LogNotifier writes alerts to the standard logger. Alerts are also kept in
+// the inventory_alerts table, so this is enough until we wire a real channel.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyStockAlert(_ context.Context, a StockAlert) error {
+	log.Printf("inventory alert kind=%s variant=%s sku=%s available=%d reorder_point=%d safety_stock=%d",
+		a.Kind, a.VariantID, a.SKU, a.Available, a.ReorderPoint, a.SafetyStock)
+	return nil
+}
+
+// LowStockChecker periodically compares availability against each variant's
+// thresholds and emits an alert whenever a variant crosses into low or out.
+// Moving back to ok only resets the stored level, so the next drop alerts again.
+type LowStockChecker struct {
+	repo     Repository
+	notifier AlertNotifier
+	interval time.Duration
+}
+
+func NewLowStockChecker(repo Repository, notifier AlertNotifier, interval time.Duration) *LowStockChecker {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &LowStockChecker{repo: repo, notifier: notifier, interval: interval}
+}
+
+// Run checks once immediately and then on every tick until ctx is done.
+func (c *LowStockChecker) Run(ctx context.Context) {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := c.CheckOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("low-stock check failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// CheckOnce returns the alerts emitted during this pass.
+func (c *LowStockChecker) CheckOnce(ctx context.Context) ([]StockAlert, error) {
+	levels, err := c.repo.ListStockLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var emitted []StockAlert
+	for _, lvl := range levels {
+		prev := lvl.Level
+		lvl.Level = ComputeLevel(lvl.Available, lvl.ReorderPoint, lvl.SafetyStock)
+		if lvl.Level == prev {
+			continue
+		}
+
+		changed, err := c.repo.TransitionStockLevel(ctx, lvl, prev)
+		if err != nil {
+			return emitted, err
+		}
+		kind := alertKind(lvl.Level)
+		if !changed || kind == "" {
+			continue
+		}
+
+		a := StockAlert{
+			Kind:          kind,
+			VariantID:     lvl.VariantID,
+			SKU:           lvl.SKU,
+			Available:     lvl.Available,
+			ReorderPoint:  lvl.ReorderPoint,
+			SafetyStock:   lvl.SafetyStock,
+			PreviousLevel: prev,
+			At:            time.Now(),
+		}
+		if err := c.notifier.NotifyStockAlert(ctx, a); err != nil {
+			log.Printf("notify stock alert variant=%s: %v", a.VariantID, err)
+		}
+		emitted = append(emitted, a)
+	}
+	return emitted, nil
+}
+
+func alertKind(level string) string {
+	switch level {
+	case LevelLow:
+		return "low_stock"
+	case LevelOut:
+		return "out_of_stock"
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	alerts []StockAlert
+}
+
+func (n *recordingNotifier) NotifyStockAlert(_ context.Context, a StockAlert) error {
+	n.alerts = append(n.alerts, a)
+	return nil
+}
+
+func TestLowStockChecker_EmitsOnCrossing(t *testing.T) {
+	var transitions []string
+	repo := fakeRepo{
+		levelsFn: func(ctx context.Context) ([]StockLevel, error) {
+			return []StockLevel{
+				{VariantID: "v-ok", Available: 50, ReorderPoint: 10, Level: LevelOK},      // stays ok
+				{VariantID: "v-low", Available: 8, ReorderPoint: 10, Level: LevelOK},      // ok -> low
+				{VariantID: "v-out", Available: 0, ReorderPoint: 10, Level: LevelLow},     // low -> out
+				{VariantID: "v-back", Available: 30, ReorderPoint: 10, Level: LevelOut},   // out -> ok, no alert
+				{VariantID: "v-still-low", Available: 3, SafetyStock: 5, Level: LevelLow}, // already low
+			}, nil
+		},
+		transFn: func(ctx context.Context, lvl StockLevel, from string) (bool, error) {
+			transitions = append(transitions, lvl.VariantID+":"+from+"->"+lvl.Level)
+			return true, nil
+		},
+	}
+	n := &recordingNotifier{}
+	c := NewLowStockChecker(repo, n, 0)
+
+	alerts, err := c.CheckOnce(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"v-low:ok->low", "v-out:low->out", "v-back:out->ok"}, transitions)
+	require.Len(t, alerts, 2)
+	require.Equal(t, n.alerts, alerts)
+	require.Equal(t, "low_stock", alerts[0].Kind)
+	require.Equal(t, "out_of_stock", alerts[1].Kind)
+}
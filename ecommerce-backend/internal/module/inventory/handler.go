@@ -1,11 +1,16 @@
 package inventory
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
 )
 
 type Handler struct {
@@ -18,6 +23,22 @@ func NewHandler(svc *Service) *Handler {
 
 func (h *Handler) Routes(r chi.Router) {
 	r.Get("/variants/{id}/availability", h.getAvailability)
+	r.Post("/variants/availability", h.batchAvailability)
+}
+
+// AdminRoutes must be mounted behind AuthMiddleware + RequireRole("admin").
+func (h *Handler) AdminRoutes(r chi.Router) {
+	r.Post("/admin/inventory/receipts", h.movementHandler(h.svc.Receive))
+	r.Post("/admin/inventory/adjustments", h.movementHandler(h.svc.Adjust))
+	r.Post("/admin/inventory/returns", h.movementHandler(h.svc.Return))
+	r.Get("/admin/inventory/variants/{id}/movements", h.listMovements)
+	r.Put("/admin/inventory/variants/{id}/thresholds", h.setThresholds)
+	r.Put("/admin/inventory/variants/{id}/policy", h.setPolicy)
+	r.Get("/admin/inventory/low-stock", h.listLowStock)
+
+	r.Get("/admin/inventory/locations", h.listLocations)
+	r.Post("/admin/inventory/locations", h.createLocation)
+	r.Patch("/admin/inventory/locations/{id}", h.updateLocation)
 }
 
 func (h *Handler) getAvailability(w http.ResponseWriter, r *http.Request) {
@@ -27,7 +48,9 @@ func (h *Handler) getAvailability(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	a, err := h.svc.Availability(r.Context(), variantID)
+	byLocation := r.URL.Query().Get("by_location") == "true"
+
+	a, err := h.svc.Availability(r.Context(), variantID, byLocation)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
@@ -40,6 +63,225 @@ func (h *Handler) getAvailability(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, a)
 }
 
+type batchAvailabilityReq struct {
+	VariantIDs []string `json:"variant_ids"`
+}
+
+func (h *Handler) batchAvailability(w http.ResponseWriter, r *http.Request) {
+	var req batchAvailabilityReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+
+	items, err := h.svc.BatchAvailability(r.Context(), req.VariantIDs)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPayload) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+type movementReq struct {
+	VariantID  string `json:"variant_id"`
+	LocationID string `json:"location_id"`
+	Qty        int    `json:"qty"`
+	ReasonCode string `json:"reason_code"`
+	OrderID    string `json:"order_id"`
+	Note       string `json:"note"`
+}
+
+func (h *Handler) movementHandler(post func(ctx context.Context, in MovementInput) (*Movement, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, ok := httpx.UserIDFromContext(r.Context())
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+			return
+		}
+
+		var req movementReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+			return
+		}
+
+		m, err := post(r.Context(), MovementInput{
+			VariantID:  req.VariantID,
+			LocationID: req.LocationID,
+			Qty:        req.Qty,
+			ReasonCode: req.ReasonCode,
+			OrderID:    req.OrderID,
+			Note:       req.Note,
+			Actor:      actor,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrInvalidPayload):
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+			case errors.Is(err, ErrInvalidReason):
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_reason_code"})
+			case errors.Is(err, ErrNotFound):
+				writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+			case errors.Is(err, ErrInsufficientStock):
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "insufficient_stock"})
+			case errors.Is(err, ErrBelowReserved):
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "below_reserved"})
+			default:
+				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+			}
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, m)
+	}
+}
+
+func (h *Handler) listMovements(w http.ResponseWriter, r *http.Request) {
+	variantID := chi.URLParam(r, "id")
+	q := r.URL.Query()
+	page, err := h.svc.Movements(r.Context(), variantID, parseInt(q.Get("limit"), 50), q.Get("cursor"))
+	if err != nil {
+		writePageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) setPolicy(w http.ResponseWriter, r *http.Request) {
+	var p Policy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	p.VariantID = chi.URLParam(r, "id")
+
+	out, err := h.svc.SetPolicy(r.Context(), p)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidPayload):
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+		case errors.Is(err, ErrNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+type thresholdsReq struct {
+	ReorderPoint int `json:"reorder_point"`
+	SafetyStock  int `json:"safety_stock"`
+}
+
+func (h *Handler) setThresholds(w http.ResponseWriter, r *http.Request) {
+	var req thresholdsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+
+	t, err := h.svc.SetThresholds(r.Context(), chi.URLParam(r, "id"), req.ReorderPoint, req.SafetyStock)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidPayload):
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+		case errors.Is(err, ErrNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) listLowStock(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := h.svc.LowStock(r.Context(), parseInt(q.Get("limit"), 50), q.Get("cursor"))
+	if err != nil {
+		writePageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) listLocations(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.Locations(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) createLocation(w http.ResponseWriter, r *http.Request) {
+	var l Location
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+
+	out, err := h.svc.CreateLocation(r.Context(), l)
+	if err != nil {
+		writeLocationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (h *Handler) updateLocation(w http.ResponseWriter, r *http.Request) {
+	var l Location
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+
+	out, err := h.svc.UpdateLocation(r.Context(), chi.URLParam(r, "id"), l)
+	if err != nil {
+		writeLocationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func writeLocationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPayload):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+	case errors.Is(err, ErrCodeTaken):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "code_taken"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+	}
+}
+
+func writePageError(w http.ResponseWriter, err error) {
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_cursor"})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+}
+
+func parseInt(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
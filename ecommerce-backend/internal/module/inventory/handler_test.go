@@ -1,28 +1,86 @@
 package inventory
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
 
 type fakeRepo struct {
-	getFn func(ctx context.Context, variantID string) (*Availability, error)
+	getFn       func(ctx context.Context, variantID string, byLocation bool) (*Availability, error)
+	batchFn     func(ctx context.Context, variantIDs []string) ([]Availability, error)
+	recordFn    func(ctx context.Context, m Movement) (*Movement, error)
+	listFn      func(ctx context.Context, variantID string, limit int, after *MovementCursor) ([]Movement, error)
+	listLocFn   func(ctx context.Context) ([]Location, error)
+	createLocFn func(ctx context.Context, l Location) (*Location, error)
+	updateLocFn func(ctx context.Context, locationID string, l Location) (*Location, error)
+	setPolicyFn func(ctx context.Context, p Policy) (*Policy, error)
+	setThrFn    func(ctx context.Context, variantID string, reorderPoint, safetyStock int) (*Thresholds, error)
+	levelsFn    func(ctx context.Context) ([]StockLevel, error)
+	belowFn     func(ctx context.Context, limit int, after *StockLevelCursor) ([]StockLevel, error)
+	transFn     func(ctx context.Context, lvl StockLevel, from string) (bool, error)
+}
+
+func (f fakeRepo) GetAvailability(ctx context.Context, variantID string, byLocation bool) (*Availability, error) {
+	return f.getFn(ctx, variantID, byLocation)
+}
+func (f fakeRepo) GetAvailabilities(ctx context.Context, variantIDs []string) ([]Availability, error) {
+	return f.batchFn(ctx, variantIDs)
+}
+func (f fakeRepo) RecordMovement(ctx context.Context, m Movement) (*Movement, error) {
+	return f.recordFn(ctx, m)
+}
+func (f fakeRepo) ListMovements(ctx context.Context, variantID string, limit int, after *MovementCursor) ([]Movement, error) {
+	return f.listFn(ctx, variantID, limit, after)
+}
+func (f fakeRepo) ListLocations(ctx context.Context) ([]Location, error) { return f.listLocFn(ctx) }
+func (f fakeRepo) CreateLocation(ctx context.Context, l Location) (*Location, error) {
+	return f.createLocFn(ctx, l)
+}
+func (f fakeRepo) UpdateLocation(ctx context.Context, locationID string, l Location) (*Location, error) {
+	return f.updateLocFn(ctx, locationID, l)
+}
+func (f fakeRepo) SetPolicy(ctx context.Context, p Policy) (*Policy, error) {
+	return f.setPolicyFn(ctx, p)
+}
+func (f fakeRepo) SetThresholds(ctx context.Context, variantID string, reorderPoint, safetyStock int) (*Thresholds, error) {
+	return f.setThrFn(ctx, variantID, reorderPoint, safetyStock)
+}
+func (f fakeRepo) ListStockLevels(ctx context.Context) ([]StockLevel, error) { return f.levelsFn(ctx) }
+func (f fakeRepo) ListBelowThreshold(ctx context.Context, limit int, after *StockLevelCursor) ([]StockLevel, error) {
+	return f.belowFn(ctx, limit, after)
 }
+func (f fakeRepo) TransitionStockLevel(ctx context.Context, lvl StockLevel, from string) (bool, error) {
+	return f.transFn(ctx, lvl, from)
+}
+
+func adminRouter(t *testing.T, h *Handler, role string) (chi.Router, string) {
+	t.Helper()
+	secret := []byte("secret")
+	token, err := httpx.SignJWTWithRole("u-admin", role, secret, time.Hour)
+	require.NoError(t, err)
 
-func (f fakeRepo) GetAvailability(ctx context.Context, variantID string) (*Availability, error) {
-	return f.getFn(ctx, variantID)
+	r := chi.NewRouter()
+	r.Use(httpx.AuthMiddleware(secret))
+	r.Use(httpx.RequireRole("admin"))
+	h.AdminRoutes(r)
+	return r, token
 }
 
 func TestAvailability_200(t *testing.T) {
 	repo := fakeRepo{
-		getFn: func(ctx context.Context, variantID string) (*Availability, error) {
+		getFn: func(ctx context.Context, variantID string, byLocation bool) (*Availability, error) {
 			require.Equal(t, "v-1", variantID)
+			require.False(t, byLocation)
 			return &Availability{
 				VariantID:   "v-1",
 				StockOnHand: 10,
@@ -50,7 +108,7 @@ func TestAvailability_200(t *testing.T) {
 
 func TestAvailability_404(t *testing.T) {
 	repo := fakeRepo{
-		getFn: func(ctx context.Context, variantID string) (*Availability, error) {
+		getFn: func(ctx context.Context, variantID string, byLocation bool) (*Availability, error) {
 			return nil, ErrNotFound
 		},
 	}
@@ -66,3 +124,326 @@ func TestAvailability_404(t *testing.T) {
 
 	require.Equal(t, http.StatusNotFound, rec.Code)
 }
+
+func TestAvailability_ByLocation(t *testing.T) {
+	repo := fakeRepo{
+		getFn: func(ctx context.Context, variantID string, byLocation bool) (*Availability, error) {
+			require.True(t, byLocation)
+			return &Availability{
+				VariantID: "v-1", StockOnHand: 12, Reserved: 2, Available: 10,
+				Locations: []LocationStock{
+					{LocationID: "l-1", Code: "JKT", StockOnHand: 10, Reserved: 2, Available: 8},
+					{LocationID: "l-2", Code: "SBY", StockOnHand: 2, Available: 2},
+				},
+			}, nil
+		},
+	}
+	h := NewHandler(NewService(repo))
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/variants/v-1/availability?by_location=true", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out Availability
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, 10, out.Available)
+	require.Len(t, out.Locations, 2)
+}
+
+func TestBatchAvailability_200(t *testing.T) {
+	repo := fakeRepo{
+		batchFn: func(ctx context.Context, variantIDs []string) ([]Availability, error) {
+			require.Equal(t, []string{"v-1", "v-2"}, variantIDs)
+			return []Availability{{VariantID: "v-2", StockOnHand: 4, Available: 4}}, nil
+		},
+	}
+	h := NewHandler(NewService(repo))
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/variants/availability", bytes.NewReader([]byte(`{"variant_ids":["v-1","v-2","v-1"]}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out struct {
+		Items   []Availability `json:"items"`
+		HasMore *bool          `json:"has_more"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.NotNil(t, out.HasMore, "the list envelope")
+	require.False(t, *out.HasMore)
+	require.Len(t, out.Items, 2)
+	require.Equal(t, "v-1", out.Items[0].VariantID)
+	require.Equal(t, 0, out.Items[0].Available)
+	require.Equal(t, 4, out.Items[1].Available)
+}
+
+func TestBatchAvailability_400_Empty(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}))
+
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/variants/availability", bytes.NewReader([]byte(`{"variant_ids":[]}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCreateLocation_409_CodeTaken(t *testing.T) {
+	repo := fakeRepo{
+		createLocFn: func(ctx context.Context, l Location) (*Location, error) {
+			require.Equal(t, "JKT", l.Code)
+			require.Equal(t, "ID", l.Country)
+			return nil, ErrCodeTaken
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/inventory/locations", bytes.NewReader([]byte(`{"code":" jkt ","name":"Jakarta DC","province":"DKI Jakarta"}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestReceipt_201(t *testing.T) {
+	repo := fakeRepo{
+		recordFn: func(ctx context.Context, m Movement) (*Movement, error) {
+			require.Equal(t, "v-1", m.VariantID)
+			require.Equal(t, KindReceipt, m.Kind)
+			require.Equal(t, 5, m.OnHandDelta)
+			require.Equal(t, "purchase_order", m.ReasonCode)
+			require.Equal(t, "u-admin", m.Actor)
+			m.ID = "mv-1"
+			m.OnHandAfter = 5
+			return &m, nil
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/inventory/receipts", bytes.NewReader([]byte(`{"variant_id":"v-1","qty":5}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var out Movement
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, "mv-1", out.ID)
+	require.Equal(t, 5, out.OnHandAfter)
+}
+
+func TestAdjustment_400_InvalidReason(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/inventory/adjustments", bytes.NewReader([]byte(`{"variant_id":"v-1","qty":-2,"reason_code":"stolen_by_aliens"}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdjustment_409_InsufficientStock(t *testing.T) {
+	repo := fakeRepo{
+		recordFn: func(ctx context.Context, m Movement) (*Movement, error) {
+			require.Equal(t, -10, m.OnHandDelta)
+			return nil, ErrInsufficientStock
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/inventory/adjustments", bytes.NewReader([]byte(`{"variant_id":"v-1","qty":-10,"reason_code":"damaged"}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestAdjustment_409_BelowReserved(t *testing.T) {
+	repo := fakeRepo{
+		recordFn: func(ctx context.Context, m Movement) (*Movement, error) {
+			require.Equal(t, -3, m.OnHandDelta)
+			return nil, ErrBelowReserved // e.g. 5 on hand, 4 reserved
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/inventory/adjustments", bytes.NewReader([]byte(`{"variant_id":"v-1","qty":-3,"reason_code":"damaged"}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "below_reserved")
+}
+
+func TestMovements_403_NotAdmin(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}))
+	r, token := adminRouter(t, h, "customer")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/inventory/variants/v-1/movements", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestSetThresholds_200(t *testing.T) {
+	repo := fakeRepo{
+		setThrFn: func(ctx context.Context, variantID string, reorderPoint, safetyStock int) (*Thresholds, error) {
+			require.Equal(t, "v-1", variantID)
+			return &Thresholds{VariantID: variantID, ReorderPoint: reorderPoint, SafetyStock: safetyStock}, nil
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/inventory/variants/v-1/thresholds", bytes.NewReader([]byte(`{"reorder_point":10,"safety_stock":3}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out Thresholds
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, 10, out.ReorderPoint)
+}
+
+func TestSetThresholds_400_Negative(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/inventory/variants/v-1/thresholds", bytes.NewReader([]byte(`{"reorder_point":-1}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestLowStock_200(t *testing.T) {
+	repo := fakeRepo{
+		belowFn: func(ctx context.Context, limit int, after *StockLevelCursor) ([]StockLevel, error) {
+			return []StockLevel{{VariantID: "v-1", SKU: "A", Available: 2, ReorderPoint: 5, Level: LevelLow}}, nil
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/inventory/low-stock", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out struct {
+		Items []StockLevel `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Len(t, out.Items, 1)
+	require.Equal(t, LevelLow, out.Items[0].Level)
+}
+
+func TestSetPolicy_Backorder_200(t *testing.T) {
+	repo := fakeRepo{
+		setPolicyFn: func(ctx context.Context, p Policy) (*Policy, error) {
+			require.Equal(t, "v-1", p.VariantID)
+			require.Equal(t, PolicyBackorder, p.Policy)
+			require.Equal(t, 20, *p.BackorderLimit)
+			require.Nil(t, p.PreorderShipsAt)
+			return &p, nil
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	body := []byte(`{"policy":"backorder","backorder_limit":20,"preorder_ships_at":"2030-01-01T00:00:00Z"}`)
+	req := httptest.NewRequest(http.MethodPut, "/admin/inventory/variants/v-1/policy", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestSetPolicy_400_PreorderWithoutShipDate(t *testing.T) {
+	h := NewHandler(NewService(fakeRepo{}))
+	r, token := adminRouter(t, h, "admin")
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/inventory/variants/v-1/policy", bytes.NewReader([]byte(`{"policy":"preorder"}`)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListMovements_CursorPaging(t *testing.T) {
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	repo := fakeRepo{
+		listFn: func(ctx context.Context, variantID string, limit int, after *MovementCursor) ([]Movement, error) {
+			require.Equal(t, 2, limit)
+			if after == nil {
+				return []Movement{{ID: "mv-2", CreatedAt: at}, {ID: "mv-1", CreatedAt: at.Add(-time.Minute)}}, nil
+			}
+			require.Equal(t, "mv-2", after.ID)
+			require.True(t, at.Equal(after.CreatedAt))
+			return []Movement{{ID: "mv-1", CreatedAt: at.Add(-time.Minute)}}, nil
+		},
+	}
+	h := NewHandler(NewService(repo))
+	r, token := adminRouter(t, h, "admin")
+
+	var page struct {
+		Items      []Movement `json:"items"`
+		HasMore    bool       `json:"has_more"`
+		NextCursor string     `json:"next_cursor"`
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/inventory/variants/v-1/movements?limit=1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Items, 1)
+	require.True(t, page.HasMore)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/inventory/variants/v-1/movements?limit=1&cursor="+page.NextCursor, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	page.NextCursor = ""
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, "mv-1", page.Items[0].ID)
+	require.False(t, page.HasMore)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/inventory/variants/v-1/movements?cursor=not-a-cursor", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
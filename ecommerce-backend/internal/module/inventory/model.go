@@ -1,8 +1,137 @@
 package inventory
 
+import "time"
+
+// Availability is aggregated over all active locations. Locations is only
+// filled when a per-location breakdown is requested.
 type Availability struct {
+	VariantID   string          `json:"variant_id"`
+	StockOnHand int             `json:"stock_on_hand"`
+	Reserved    int             `json:"reserved"`
+	Available   int             `json:"available"`
+	Locations   []LocationStock `json:"locations,omitempty"`
+}
+
+type LocationStock struct {
+	LocationID  string `json:"location_id"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	StockOnHand int    `json:"stock_on_hand"`
+	Reserved    int    `json:"reserved"`
+	Available   int    `json:"available"`
+}
+
+type Location struct {
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	City      string `json:"city"`
+	Province  string `json:"province"`
+	Country   string `json:"country"`
+	IsActive  bool   `json:"is_active"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// Movement kinds recorded in the inventory_movements ledger.
+const (
+	KindReceipt     = "receipt"
+	KindAdjustment  = "adjustment"
+	KindReservation = "reservation"
+	KindRelease     = "release"
+	KindSale        = "sale"
+	KindReturn      = "return"
+)
+
+// Movement is one append-only ledger entry. Deltas are applied to
+// inventory_items; the *After fields hold the resulting balance.
+type Movement struct {
+	ID            string    `json:"id"`
+	VariantID     string    `json:"variant_id"`
+	LocationID    string    `json:"location_id"`
+	Kind          string    `json:"kind"`
+	OnHandDelta   int       `json:"on_hand_delta"`
+	ReservedDelta int       `json:"reserved_delta"`
+	OnHandAfter   int       `json:"on_hand_after"`
+	ReservedAfter int       `json:"reserved_after"`
+	ReasonCode    string    `json:"reason_code"`
+	Actor         string    `json:"actor"`
+	OrderID       string    `json:"order_id,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Variant inventory policies (product_variants.inventory_policy). The cart
+// and order modules use these too.
+const (
+	PolicyDeny      = "deny"
+	PolicyBackorder = "backorder"
+	PolicyPreorder  = "preorder"
+)
+
+// Policy says whether a variant may be sold beyond its stock.
+type Policy struct {
+	VariantID       string     `json:"variant_id"`
+	Policy          string     `json:"policy"`
+	BackorderLimit  *int       `json:"backorder_limit"` // nil = unlimited
+	PreorderShipsAt *time.Time `json:"preorder_ships_at,omitempty"`
+}
+
+// Stock levels tracked by the low-stock checker.
+const (
+	LevelOK  = "ok"
+	LevelLow = "low"
+	LevelOut = "out"
+)
+
+type Thresholds struct {
+	VariantID    string `json:"variant_id"`
+	ReorderPoint int    `json:"reorder_point"`
+	SafetyStock  int    `json:"safety_stock"`
+}
+
+type StockLevel struct {
 	VariantID    string `json:"variant_id"`
-	StockOnHand  int    `json:"stock_on_hand"`
-	Reserved     int    `json:"reserved"`
+	SKU          string `json:"sku"`
+	Name         string `json:"name"`
 	Available    int    `json:"available"`
+	ReorderPoint int    `json:"reorder_point"`
+	SafetyStock  int    `json:"safety_stock"`
+	Level        string `json:"level"`
+}
+
+// ComputeLevel classifies availability against the thresholds: out at zero,
+// low once it reaches the reorder point or dips into safety stock.
+func ComputeLevel(available, reorderPoint, safetyStock int) string {
+	switch {
+	case available <= 0:
+		return LevelOut
+	case available <= max(reorderPoint, safetyStock):
+		return LevelLow
+	default:
+		return LevelOK
+	}
+}
+
+// StockAlert is emitted when a variant moves into the low or out level.
+type StockAlert struct {
+	Kind          string    `json:"kind"` // low_stock/out_of_stock
+	VariantID     string    `json:"variant_id"`
+	SKU           string    `json:"sku"`
+	Available     int       `json:"available"`
+	ReorderPoint  int       `json:"reorder_point"`
+	SafetyStock   int       `json:"safety_stock"`
+	PreviousLevel string    `json:"previous_level"`
+	At            time.Time `json:"at"`
+}
+
+// Keyset cursors for the admin list endpoints.
+
+type MovementCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+type StockLevelCursor struct {
+	Available int    `json:"a"`
+	SKU       string `json:"sku"`
 }
@@ -3,5 +3,31 @@ package inventory
 import "context"
 
 type Repository interface {
-	GetAvailability(ctx context.Context, variantID string) (*Availability, error)
-}
\ No newline at end of file
+	GetAvailability(ctx context.Context, variantID string, byLocation bool) (*Availability, error)
+	// GetAvailabilities aggregates many variants in one query. Variants without
+	// stock rows are simply absent from the result.
+	GetAvailabilities(ctx context.Context, variantIDs []string) ([]Availability, error)
+
+	// RecordMovement applies the deltas to inventory_items and appends the
+	// ledger entry in one transaction. It returns ErrBelowReserved rather than
+	// leave less on hand than is reserved.
+	RecordMovement(ctx context.Context, m Movement) (*Movement, error)
+	// ListMovements is newest first; after is the last row of the previous page.
+	ListMovements(ctx context.Context, variantID string, limit int, after *MovementCursor) ([]Movement, error)
+
+	ListLocations(ctx context.Context) ([]Location, error)
+	CreateLocation(ctx context.Context, l Location) (*Location, error)
+	UpdateLocation(ctx context.Context, locationID string, l Location) (*Location, error)
+
+	SetPolicy(ctx context.Context, p Policy) (*Policy, error)
+
+	SetThresholds(ctx context.Context, variantID string, reorderPoint, safetyStock int) (*Thresholds, error)
+	// ListStockLevels returns every variant that has stock rows or thresholds,
+	// with its live availability and the level last recorded by the checker.
+	ListStockLevels(ctx context.Context) ([]StockLevel, error)
+	// ListBelowThreshold is the live admin view of variants at or under their threshold.
+	ListBelowThreshold(ctx context.Context, limit int, after *StockLevelCursor) ([]StockLevel, error)
+	// TransitionStockLevel records a level change if the stored level is still
+	// `from` and returns false when another checker got there first.
+	TransitionStockLevel(ctx context.Context, lvl StockLevel, from string) (bool, error)
+}
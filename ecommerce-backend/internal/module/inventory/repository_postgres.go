@@ -3,12 +3,18 @@ package inventory
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var ErrNotFound = errors.New("not found")
+var ErrInvalidPayload = errors.New("invalid payload")
+var ErrInsufficientStock = errors.New("insufficient stock")
+var ErrCodeTaken = errors.New("location code already taken")
+var ErrBelowReserved = errors.New("stock on hand below reserved")
 
 type PostgresRepository struct {
 	pool *pgxpool.Pool
@@ -18,25 +24,568 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 	return &PostgresRepository{pool: pool}
 }
 
-func (r *PostgresRepository) GetAvailability(ctx context.Context, variantID string) (*Availability, error) {
-	var a Availability
-	err := r.pool.QueryRow(ctx, `
-SELECT variant_id::text, stock_on_hand, reserved
-FROM inventory_items
+func (r *PostgresRepository) GetAvailability(ctx context.Context, variantID string, byLocation bool) (*Availability, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT l.id::text, l.code, l.name, ii.stock_on_hand, ii.reserved
+FROM inventory_items ii
+JOIN locations l ON l.id = ii.location_id
+WHERE ii.variant_id = $1 AND l.is_active = true
+ORDER BY l.code ASC;
+`, variantID)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	defer rows.Close()
+
+	a := Availability{VariantID: variantID}
+	found := false
+	for rows.Next() {
+		var ls LocationStock
+		if err := rows.Scan(&ls.LocationID, &ls.Code, &ls.Name, &ls.StockOnHand, &ls.Reserved); err != nil {
+			return nil, err
+		}
+		ls.Available = max(ls.StockOnHand-ls.Reserved, 0)
+		found = true
+
+		a.StockOnHand += ls.StockOnHand
+		a.Reserved += ls.Reserved
+		a.Available += ls.Available
+		if byLocation {
+			a.Locations = append(a.Locations, ls)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &a, nil
+}
+
+func (r *PostgresRepository) GetAvailabilities(ctx context.Context, variantIDs []string) ([]Availability, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT ii.variant_id::text,
+       SUM(ii.stock_on_hand)::int,
+       SUM(ii.reserved)::int,
+       SUM(stock_available(ii.stock_on_hand, ii.reserved))::int
+FROM inventory_items ii
+JOIN locations l ON l.id = ii.location_id
+WHERE ii.variant_id = ANY($1::uuid[]) AND l.is_active = true
+GROUP BY ii.variant_id;
+`, variantIDs)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "22P02" {
+			return nil, ErrInvalidPayload
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Availability, 0, len(variantIDs))
+	for rows.Next() {
+		var a Availability
+		if err := rows.Scan(&a.VariantID, &a.StockOnHand, &a.Reserved, &a.Available); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) RecordMovement(ctx context.Context, m Movement) (*Movement, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	out, err := ApplyMovement(ctx, tx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ApplyMovement is RecordMovement inside the caller's transaction, for other
+// modules that change stock as part of a larger write (catalog import).
+func ApplyMovement(ctx context.Context, tx pgx.Tx, m Movement) (*Movement, error) {
+	// movements without a location go to the default one
+	if m.LocationID == "" {
+		err := tx.QueryRow(ctx, `SELECT id::text FROM locations WHERE is_default = true LIMIT 1;`).Scan(&m.LocationID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+	}
+
+	// make sure a balance row exists (first receipt for a variant at this location)
+	_, err := tx.Exec(ctx, `
+INSERT INTO inventory_items (variant_id, location_id)
+VALUES ($1, $2)
+ON CONFLICT (variant_id, location_id) DO NOTHING;
+`, m.VariantID, m.LocationID)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+
+	err = tx.QueryRow(ctx, `
+UPDATE inventory_items
+SET stock_on_hand = stock_on_hand + $3, reserved = reserved + $4, updated_at = now()
+WHERE variant_id = $1 AND location_id = $2
+RETURNING stock_on_hand, reserved;
+`, m.VariantID, m.LocationID, m.OnHandDelta, m.ReservedDelta).Scan(&m.OnHandAfter, &m.ReservedAfter)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	// reserved units must stay on hand, or settling their orders would take
+	// stock_on_hand negative
+	if m.OnHandAfter < m.ReservedAfter && m.OnHandDelta-m.ReservedDelta < 0 {
+		return nil, ErrBelowReserved
+	}
+
+	var orderID *string
+	if m.OrderID != "" {
+		orderID = &m.OrderID
+	}
+	err = tx.QueryRow(ctx, `
+INSERT INTO inventory_movements (
+  variant_id, location_id, kind, on_hand_delta, reserved_delta, on_hand_after, reserved_after, reason_code, actor, order_id, note
+) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+RETURNING id::text, created_at;
+`, m.VariantID, m.LocationID, m.Kind, m.OnHandDelta, m.ReservedDelta, m.OnHandAfter, m.ReservedAfter, m.ReasonCode, m.Actor, orderID, m.Note).
+		Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+
+	if m.OnHandDelta > 0 {
+		if err := fillBackorders(ctx, tx, m.VariantID, m.LocationID); err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// SetOnHand adjusts the on-hand count at a location to qty inside the
+// caller's transaction, recording the difference as an adjustment with
+// ReasonImport. It returns nil when the count already matches and
+// ErrBelowReserved when qty is less than what is reserved there.
+func SetOnHand(ctx context.Context, tx pgx.Tx, variantID, locationID string, qty int, actor string) (*Movement, error) {
+	if _, err := tx.Exec(ctx, `
+INSERT INTO inventory_items (variant_id, location_id)
+VALUES ($1, $2)
+ON CONFLICT (variant_id, location_id) DO NOTHING;
+`, variantID, locationID); err != nil {
+		return nil, mapPgError(err)
+	}
+
+	var onHand, reserved int
+	if err := tx.QueryRow(ctx, `
+SELECT stock_on_hand, reserved FROM inventory_items
+WHERE variant_id = $1 AND location_id = $2
+FOR UPDATE;
+`, variantID, locationID).Scan(&onHand, &reserved); err != nil {
+		return nil, err
+	}
+	if qty == onHand {
+		return nil, nil
+	}
+	if qty < reserved {
+		return nil, ErrBelowReserved
+	}
+
+	return ApplyMovement(ctx, tx, Movement{
+		VariantID:   variantID,
+		LocationID:  locationID,
+		Kind:        KindAdjustment,
+		OnHandDelta: qty - onHand,
+		ReasonCode:  ReasonImport,
+		Actor:       actor,
+	})
+}
+
+// fillBackorders hands unreserved stock at the location to the backordered
+// lines of open orders shipping from there, oldest order first. A pending
+// order gets the units reserved; a paid order's reserved part was sold at
+// payment, so its filled units are sold right away. Either way the line's
+// backordered_qty drops, which keeps backorder_limit a cap on outstanding
+// units rather than on everything ever backordered.
+func fillBackorders(ctx context.Context, tx pgx.Tx, variantID, locationID string) error {
+	rows, err := tx.Query(ctx, `
+SELECT i.id::text, i.order_id::text, o.status, i.backordered_qty
+FROM order_items i
+JOIN orders o ON o.id = i.order_id
+WHERE i.variant_id = $1 AND o.fulfillment_location_id = $2
+  AND i.backordered_qty > 0 AND o.status IN ('pending_payment', 'paid')
+ORDER BY o.created_at, i.id
+FOR UPDATE OF i;
+`, variantID, locationID)
+	if err != nil {
+		return err
+	}
+	type line struct {
+		id, orderID, status string
+		qty                 int
+	}
+	var lines []line
+	for rows.Next() {
+		var l line
+		if err := rows.Scan(&l.id, &l.orderID, &l.status, &l.qty); err != nil {
+			rows.Close()
+			return err
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	// the balance row is already locked by the movement
+	var available int
+	if err := tx.QueryRow(ctx, `
+SELECT stock_on_hand - reserved FROM inventory_items WHERE variant_id = $1 AND location_id = $2;
+`, variantID, locationID).Scan(&available); err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		if available <= 0 {
+			break
+		}
+		n := min(l.qty, available)
+		available -= n
+
+		if _, err := tx.Exec(ctx, `
+UPDATE order_items SET backordered_qty = backordered_qty - $2 WHERE id = $1;
+`, l.id, n); err != nil {
+			return err
+		}
+
+		kind, onHand, reserved := KindReservation, 0, n
+		if l.status == "paid" {
+			kind, onHand, reserved = KindSale, -n, 0
+		}
+		if _, err := tx.Exec(ctx, `
+WITH upd AS (
+  UPDATE inventory_items
+  SET stock_on_hand = stock_on_hand + $3, reserved = reserved + $4, updated_at = now()
+  WHERE variant_id = $1 AND location_id = $2
+  RETURNING stock_on_hand, reserved
+)
+INSERT INTO inventory_movements (variant_id, location_id, kind, on_hand_delta, reserved_delta, on_hand_after, reserved_after, reason_code, order_id)
+SELECT $1, $2, $5, $3, $4, stock_on_hand, reserved, $6, $7
+FROM upd;
+`, variantID, locationID, onHand, reserved, kind, ReasonBackorderFilled, l.orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListMovements(ctx context.Context, variantID string, limit int, after *MovementCursor) ([]Movement, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var afterAt *time.Time
+	var afterID *string
+	if after != nil {
+		afterAt, afterID = &after.CreatedAt, &after.ID
+	}
+
+	rows, err := r.pool.Query(ctx, `
+SELECT id::text, variant_id::text, location_id::text, kind, on_hand_delta, reserved_delta, on_hand_after, reserved_after,
+       reason_code, actor, COALESCE(order_id::text, ''), note, created_at
+FROM inventory_movements
 WHERE variant_id = $1
-LIMIT 1;
-`, variantID).Scan(&a.VariantID, &a.StockOnHand, &a.Reserved)
+  AND ($3::timestamptz IS NULL OR (created_at, id) < ($3::timestamptz, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+`, variantID, limit, afterAt, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Movement, 0, limit)
+	for rows.Next() {
+		var m Movement
+		if err := rows.Scan(&m.ID, &m.VariantID, &m.LocationID, &m.Kind, &m.OnHandDelta, &m.ReservedDelta, &m.OnHandAfter, &m.ReservedAfter,
+			&m.ReasonCode, &m.Actor, &m.OrderID, &m.Note, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) ListLocations(ctx context.Context) ([]Location, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id::text, code, name, city, province, country, is_active, is_default
+FROM locations
+ORDER BY is_default DESC, code ASC;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Location
+	for rows.Next() {
+		var l Location
+		if err := rows.Scan(&l.ID, &l.Code, &l.Name, &l.City, &l.Province, &l.Country, &l.IsActive, &l.IsDefault); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
 
+func (r *PostgresRepository) CreateLocation(ctx context.Context, l Location) (*Location, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if l.IsDefault {
+		if _, err := tx.Exec(ctx, `UPDATE locations SET is_default=false, updated_at=now() WHERE is_default=true;`); err != nil {
+			return nil, err
+		}
+	}
+
+	var out Location
+	err = tx.QueryRow(ctx, `
+INSERT INTO locations (code, name, city, province, country, is_active, is_default)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+RETURNING id::text, code, name, city, province, country, is_active, is_default;
+`, l.Code, l.Name, l.City, l.Province, l.Country, l.IsActive, l.IsDefault).
+		Scan(&out.ID, &out.Code, &out.Name, &out.City, &out.Province, &out.Country, &out.IsActive, &out.IsDefault)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *PostgresRepository) UpdateLocation(ctx context.Context, locationID string, l Location) (*Location, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if l.IsDefault {
+		if _, err := tx.Exec(ctx, `UPDATE locations SET is_default=false, updated_at=now() WHERE is_default=true AND id<>$1;`, locationID); err != nil {
+			return nil, mapPgError(err)
+		}
+	}
+
+	var out Location
+	err = tx.QueryRow(ctx, `
+UPDATE locations
+SET code=$1, name=$2, city=$3, province=$4, country=$5, is_active=$6, is_default=$7, updated_at=now()
+WHERE id=$8
+RETURNING id::text, code, name, city, province, country, is_active, is_default;
+`, l.Code, l.Name, l.City, l.Province, l.Country, l.IsActive, l.IsDefault, locationID).
+		Scan(&out.ID, &out.Code, &out.Name, &out.City, &out.Province, &out.Country, &out.IsActive, &out.IsDefault)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
 		}
+		return nil, mapPgError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
+	return &out, nil
+}
 
-	a.Available = a.StockOnHand - a.Reserved
-	if a.Available < 0 {
-		a.Available = 0
+func (r *PostgresRepository) SetPolicy(ctx context.Context, p Policy) (*Policy, error) {
+	out := Policy{VariantID: p.VariantID}
+	err := r.pool.QueryRow(ctx, `
+UPDATE product_variants
+SET inventory_policy = $2, backorder_limit = $3, preorder_ships_at = $4, updated_at = now()
+WHERE id = $1
+RETURNING inventory_policy, backorder_limit, preorder_ships_at;
+`, p.VariantID, p.Policy, p.BackorderLimit, p.PreorderShipsAt).Scan(&out.Policy, &out.BackorderLimit, &out.PreorderShipsAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
 	}
-	return &a, nil
+	return &out, nil
+}
+
+func (r *PostgresRepository) SetThresholds(ctx context.Context, variantID string, reorderPoint, safetyStock int) (*Thresholds, error) {
+	t := Thresholds{VariantID: variantID}
+	err := r.pool.QueryRow(ctx, `
+INSERT INTO inventory_thresholds (variant_id, reorder_point, safety_stock)
+VALUES ($1, $2, $3)
+ON CONFLICT (variant_id)
+DO UPDATE SET reorder_point = EXCLUDED.reorder_point, safety_stock = EXCLUDED.safety_stock, updated_at = now()
+RETURNING reorder_point, safety_stock;
+`, variantID, reorderPoint, safetyStock).Scan(&t.ReorderPoint, &t.SafetyStock)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return &t, nil
+}
+
+// stockLevelsCTE yields (variant_id, available, reorder_point, safety_stock, stock_level)
+// for every variant with stock rows or thresholds.
+const stockLevelsCTE = `
+WITH avail AS (
+  SELECT ii.variant_id, SUM(stock_available(ii.stock_on_hand, ii.reserved))::int AS available
+  FROM inventory_items ii
+  JOIN locations l ON l.id = ii.location_id
+  WHERE l.is_active = true
+  GROUP BY ii.variant_id
+), levels AS (
+  SELECT COALESCE(a.variant_id, t.variant_id) AS variant_id,
+         COALESCE(a.available, 0) AS available,
+         COALESCE(t.reorder_point, 0) AS reorder_point,
+         COALESCE(t.safety_stock, 0) AS safety_stock,
+         COALESCE(t.stock_level, 'ok') AS stock_level
+  FROM avail a
+  FULL JOIN inventory_thresholds t ON t.variant_id = a.variant_id
+)`
+
+func (r *PostgresRepository) ListStockLevels(ctx context.Context) ([]StockLevel, error) {
+	rows, err := r.pool.Query(ctx, stockLevelsCTE+`
+SELECT lv.variant_id::text, v.sku, v.name, lv.available, lv.reorder_point, lv.safety_stock, lv.stock_level
+FROM levels lv
+JOIN product_variants v ON v.id = lv.variant_id
+WHERE v.is_active = true;
+`)
+	if err != nil {
+		return nil, err
+	}
+	return scanStockLevels(rows)
+}
+
+func (r *PostgresRepository) ListBelowThreshold(ctx context.Context, limit int, after *StockLevelCursor) ([]StockLevel, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var afterAvail *int
+	var afterSKU *string
+	if after != nil {
+		afterAvail, afterSKU = &after.Available, &after.SKU
+	}
+
+	rows, err := r.pool.Query(ctx, stockLevelsCTE+`
+SELECT lv.variant_id::text, v.sku, v.name, lv.available, lv.reorder_point, lv.safety_stock, lv.stock_level
+FROM levels lv
+JOIN product_variants v ON v.id = lv.variant_id
+WHERE v.is_active = true
+  AND lv.available <= GREATEST(lv.reorder_point, lv.safety_stock)
+  AND ($2::int IS NULL OR (lv.available, v.sku) > ($2::int, $3::text))
+ORDER BY lv.available ASC, v.sku ASC
+LIMIT $1;
+`, limit, afterAvail, afterSKU)
+	if err != nil {
+		return nil, err
+	}
+	out, err := scanStockLevels(rows)
+	if err != nil {
+		return nil, err
+	}
+	// report the live level, not the one the checker stored last
+	for i := range out {
+		out[i].Level = ComputeLevel(out[i].Available, out[i].ReorderPoint, out[i].SafetyStock)
+	}
+	return out, nil
+}
+
+func scanStockLevels(rows pgx.Rows) ([]StockLevel, error) {
+	defer rows.Close()
+
+	var out []StockLevel
+	for rows.Next() {
+		var l StockLevel
+		if err := rows.Scan(&l.VariantID, &l.SKU, &l.Name, &l.Available, &l.ReorderPoint, &l.SafetyStock, &l.Level); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) TransitionStockLevel(ctx context.Context, lvl StockLevel, from string) (bool, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+INSERT INTO inventory_thresholds (variant_id)
+VALUES ($1)
+ON CONFLICT (variant_id) DO NOTHING;
+`, lvl.VariantID)
+	if err != nil {
+		return false, mapPgError(err)
+	}
+
+	ct, err := tx.Exec(ctx, `
+UPDATE inventory_thresholds
+SET stock_level = $2, level_changed_at = now()
+WHERE variant_id = $1 AND stock_level = $3;
+`, lvl.VariantID, lvl.Level, from)
+	if err != nil {
+		return false, err
+	}
+	if ct.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if kind := alertKind(lvl.Level); kind != "" {
+		_, err = tx.Exec(ctx, `
+INSERT INTO inventory_alerts (variant_id, kind, available, reorder_point, safety_stock)
+VALUES ($1, $2, $3, $4, $5);
+`, lvl.VariantID, kind, lvl.Available, lvl.ReorderPoint, lvl.SafetyStock)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// mapPgError turns constraint violations into domain errors:
+// unknown variant/order/location (FK) -> ErrNotFound, negative balance (CHECK) -> ErrInsufficientStock,
+// duplicate location code -> ErrCodeTaken.
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return ErrCodeTaken
+		case "23503", "22P02": // foreign_key_violation, invalid_text_representation (bad uuid)
+			return ErrNotFound
+		case "23514": // check_violation
+			return ErrInsufficientStock
+		}
+	}
+	return err
 }
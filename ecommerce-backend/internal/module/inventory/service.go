@@ -1,6 +1,29 @@
 package inventory
 
-import "context"
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
+)
+
+var ErrInvalidReason = errors.New("invalid reason code")
+
+// reasonCodes lists the reason codes accepted per manual movement kind.
+// The first entry is used when the caller doesn't pass one.
+var reasonCodes = map[string][]string{
+	KindReceipt:    {"purchase_order", "transfer_in", "initial_count"},
+	KindAdjustment: {"cycle_count", "damaged", "lost", "found", "correction"},
+	KindReturn:     {"customer_return", "restock"},
+}
+
+// Reason codes the system records on its own; admins can't pick these.
+const (
+	ReasonBackorderFilled = "backorder_filled" // stock handed to backordered order lines
+	ReasonImport          = "import"           // on-hand set by a catalog import
+)
 
 type Service struct {
 	repo Repository
@@ -10,6 +33,204 @@ func NewService(repo Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) Availability(ctx context.Context, variantID string) (*Availability, error) {
-	return s.repo.GetAvailability(ctx, variantID)
+func (s *Service) Availability(ctx context.Context, variantID string, byLocation bool) (*Availability, error) {
+	return s.repo.GetAvailability(ctx, variantID, byLocation)
+}
+
+// MaxBatchVariants caps how many variants one batch availability call may ask for.
+const MaxBatchVariants = 200
+
+// BatchAvailability returns one entry per distinct requested variant, in
+// request order. Variants without stock rows come back with zero availability.
+func (s *Service) BatchAvailability(ctx context.Context, variantIDs []string) ([]Availability, error) {
+	ids := make([]string, 0, len(variantIDs))
+	seen := make(map[string]bool, len(variantIDs))
+	for _, id := range variantIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 || len(ids) > MaxBatchVariants {
+		return nil, ErrInvalidPayload
+	}
+
+	found, err := s.repo.GetAvailabilities(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Availability, len(found))
+	for _, a := range found {
+		byID[a.VariantID] = a
+	}
+
+	out := make([]Availability, 0, len(ids))
+	for _, id := range ids {
+		a, ok := byID[id]
+		if !ok {
+			a = Availability{VariantID: id}
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// MovementInput is what an admin posts for receipts, adjustments and returns.
+type MovementInput struct {
+	VariantID  string
+	LocationID string // empty means the default location
+	Qty        int    // must be > 0 for receipts/returns; signed, non-zero for adjustments
+	ReasonCode string
+	OrderID    string
+	Note       string
+	Actor      string
+}
+
+func (s *Service) Receive(ctx context.Context, in MovementInput) (*Movement, error) {
+	if in.Qty <= 0 {
+		return nil, ErrInvalidPayload
+	}
+	return s.record(ctx, KindReceipt, in)
+}
+
+func (s *Service) Adjust(ctx context.Context, in MovementInput) (*Movement, error) {
+	if in.Qty == 0 || strings.TrimSpace(in.ReasonCode) == "" {
+		return nil, ErrInvalidPayload
+	}
+	return s.record(ctx, KindAdjustment, in)
+}
+
+func (s *Service) Return(ctx context.Context, in MovementInput) (*Movement, error) {
+	if in.Qty <= 0 {
+		return nil, ErrInvalidPayload
+	}
+	return s.record(ctx, KindReturn, in)
+}
+
+func (s *Service) Movements(ctx context.Context, variantID string, limit int, cursor string) (pagination.Page[Movement], error) {
+	limit = pagination.Limit(limit, 50)
+	var after *MovementCursor
+	if cursor != "" {
+		after = &MovementCursor{}
+		if err := pagination.Decode(cursor, after); err != nil || after.ID == "" {
+			return pagination.Page[Movement]{}, pagination.ErrInvalidCursor
+		}
+	}
+	rows, err := s.repo.ListMovements(ctx, variantID, limit+1, after)
+	if err != nil {
+		return pagination.Page[Movement]{}, err
+	}
+	return pagination.New(rows, limit, func(last Movement) any {
+		return MovementCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}), nil
+}
+
+func (s *Service) record(ctx context.Context, kind string, in MovementInput) (*Movement, error) {
+	if in.VariantID == "" || in.Actor == "" {
+		return nil, ErrInvalidPayload
+	}
+
+	reason := strings.TrimSpace(in.ReasonCode)
+	allowed := reasonCodes[kind]
+	if reason == "" {
+		reason = allowed[0]
+	}
+	if !slices.Contains(allowed, reason) {
+		return nil, ErrInvalidReason
+	}
+
+	return s.repo.RecordMovement(ctx, Movement{
+		VariantID:   in.VariantID,
+		LocationID:  strings.TrimSpace(in.LocationID),
+		Kind:        kind,
+		OnHandDelta: in.Qty,
+		ReasonCode:  reason,
+		Actor:       in.Actor,
+		OrderID:     strings.TrimSpace(in.OrderID),
+		Note:        strings.TrimSpace(in.Note),
+	})
+}
+
+func (s *Service) Locations(ctx context.Context) ([]Location, error) {
+	return s.repo.ListLocations(ctx)
+}
+
+func (s *Service) CreateLocation(ctx context.Context, l Location) (*Location, error) {
+	l, err := normalizeLocation(l)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateLocation(ctx, l)
+}
+
+func (s *Service) UpdateLocation(ctx context.Context, locationID string, l Location) (*Location, error) {
+	if locationID == "" {
+		return nil, ErrInvalidPayload
+	}
+	l, err := normalizeLocation(l)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateLocation(ctx, locationID, l)
+}
+
+func normalizeLocation(l Location) (Location, error) {
+	l.Code = strings.ToUpper(strings.TrimSpace(l.Code))
+	l.Name = strings.TrimSpace(l.Name)
+	l.City = strings.TrimSpace(l.City)
+	l.Province = strings.TrimSpace(l.Province)
+	if l.Code == "" || l.Name == "" {
+		return l, ErrInvalidPayload
+	}
+	if l.Country == "" {
+		l.Country = "ID"
+	}
+	return l, nil
+}
+
+func (s *Service) SetPolicy(ctx context.Context, p Policy) (*Policy, error) {
+	if p.VariantID == "" || (p.BackorderLimit != nil && *p.BackorderLimit < 0) {
+		return nil, ErrInvalidPayload
+	}
+	switch p.Policy {
+	case PolicyDeny:
+		p.BackorderLimit = nil
+		p.PreorderShipsAt = nil
+	case PolicyBackorder:
+		p.PreorderShipsAt = nil
+	case PolicyPreorder:
+		if p.PreorderShipsAt == nil {
+			return nil, ErrInvalidPayload
+		}
+	default:
+		return nil, ErrInvalidPayload
+	}
+	return s.repo.SetPolicy(ctx, p)
+}
+
+func (s *Service) SetThresholds(ctx context.Context, variantID string, reorderPoint, safetyStock int) (*Thresholds, error) {
+	if variantID == "" || reorderPoint < 0 || safetyStock < 0 {
+		return nil, ErrInvalidPayload
+	}
+	return s.repo.SetThresholds(ctx, variantID, reorderPoint, safetyStock)
+}
+
+func (s *Service) LowStock(ctx context.Context, limit int, cursor string) (pagination.Page[StockLevel], error) {
+	limit = pagination.Limit(limit, 50)
+	var after *StockLevelCursor
+	if cursor != "" {
+		after = &StockLevelCursor{}
+		if err := pagination.Decode(cursor, after); err != nil || after.SKU == "" {
+			return pagination.Page[StockLevel]{}, pagination.ErrInvalidCursor
+		}
+	}
+	rows, err := s.repo.ListBelowThreshold(ctx, limit+1, after)
+	if err != nil {
+		return pagination.Page[StockLevel]{}, err
+	}
+	return pagination.New(rows, limit, func(last StockLevel) any {
+		return StockLevelCursor{Available: last.Available, SKU: last.SKU}
+	}), nil
 }
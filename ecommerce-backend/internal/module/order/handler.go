@@ -6,14 +6,19 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
 
 type Handler struct {
-	svc *Service
+	svc             *Service
+	cartTokenSecret []byte
 }
 
-func NewHandler(svc *Service) *Handler {
-	return &Handler{svc: svc}
+// NewHandler checks guest cart tokens against cartTokenSecret, the secret
+// the cart handler signs them with.
+func NewHandler(svc *Service, cartTokenSecret string) *Handler {
+	return &Handler{svc: svc, cartTokenSecret: []byte(cartTokenSecret)}
 }
 
 func (h *Handler) Routes(r chi.Router) {
@@ -37,11 +42,25 @@ func (h *Handler) checkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orderID, err := h.svc.Checkout(r.Context(), req.CartID, req.Address)
+	userID, _ := httpx.UserIDFromContext(r.Context())
+	guest := httpx.VerifyCartToken(r.Header.Get(httpx.CartTokenHeader), req.CartID, h.cartTokenSecret)
+	orderID, err := h.svc.Checkout(r.Context(), req.CartID, userID, guest, req.Address)
 	if err != nil {
+		var oos *OutOfStockError
+		var pue *PriceUnavailableError
 		switch {
+		case errors.As(err, &oos):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "out_of_stock", "items": oos.Items})
+		case errors.As(err, &pue):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "price_unavailable", "currency": pue.Currency, "variant_ids": pue.VariantIDs})
+		case errors.Is(err, ErrSplitShipment):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "split_shipment_required"})
+		case errors.Is(err, ErrShippingUnpriced):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "shipping_unpriced"})
 		case errors.Is(err, ErrNotFound):
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+		case errors.Is(err, ErrForbidden):
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "forbidden"})
 		case errors.Is(err, ErrEmptyCart):
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "empty_cart"})
 		default:
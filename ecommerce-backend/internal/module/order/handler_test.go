@@ -7,31 +7,34 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
 
 type fakeRepo struct {
-	createFn func(ctx context.Context, cartID string, addr AddressSnapshot) (string, error)
+	createFn func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error)
 	getFn    func(ctx context.Context, orderID string) (*Order, error)
 }
 
-func (f fakeRepo) CreateOrderFromCart(ctx context.Context, cartID string, addr AddressSnapshot) (string, error) {
-	return f.createFn(ctx, cartID, addr)
+func (f fakeRepo) CreateOrderFromCart(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
+	return f.createFn(ctx, cartID, userID, guestToken, addr, rate)
 }
 func (f fakeRepo) GetOrder(ctx context.Context, orderID string) (*Order, error) { return f.getFn(ctx, orderID) }
 
 func TestCheckout_201(t *testing.T) {
 	repo := fakeRepo{
-		createFn: func(ctx context.Context, cartID string, addr AddressSnapshot) (string, error) {
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
 			require.Equal(t, "cart-1", cartID)
 			return "order-1", nil
 		},
 		getFn: func(ctx context.Context, orderID string) (*Order, error) { return nil, nil },
 	}
-	svc := NewService(repo)
-	h := NewHandler(svc)
+	svc := NewService(repo, ShippingRate{})
+	h := NewHandler(svc, "secret")
 
 	r := chi.NewRouter()
 	h.Routes(r)
@@ -50,11 +53,11 @@ func TestCheckout_201(t *testing.T) {
 
 func TestCheckout_400_InvalidJSON(t *testing.T) {
 	repo := fakeRepo{
-		createFn: func(ctx context.Context, cartID string, addr AddressSnapshot) (string, error) { return "", nil },
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) { return "", nil },
 		getFn:    func(ctx context.Context, orderID string) (*Order, error) { return nil, nil },
 	}
-	svc := NewService(repo)
-	h := NewHandler(svc)
+	svc := NewService(repo, ShippingRate{})
+	h := NewHandler(svc, "secret")
 	r := chi.NewRouter()
 	h.Routes(r)
 
@@ -67,13 +70,13 @@ func TestCheckout_400_InvalidJSON(t *testing.T) {
 
 func TestGetOrder_404(t *testing.T) {
 	repo := fakeRepo{
-		createFn: func(ctx context.Context, cartID string, addr AddressSnapshot) (string, error) { return "", nil },
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) { return "", nil },
 		getFn: func(ctx context.Context, orderID string) (*Order, error) {
 			return nil, ErrNotFound
 		},
 	}
-	svc := NewService(repo)
-	h := NewHandler(svc)
+	svc := NewService(repo, ShippingRate{})
+	h := NewHandler(svc, "secret")
 	r := chi.NewRouter()
 	h.Routes(r)
 
@@ -83,3 +86,162 @@ func TestGetOrder_404(t *testing.T) {
 
 	require.Equal(t, http.StatusNotFound, rec.Code)
 }
+
+func TestCheckout_409_OutOfStock(t *testing.T) {
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
+			return "", &OutOfStockError{Items: []StockShortage{
+				{VariantID: "v-1", SKU: "SKU-1", Requested: 3, Available: 1},
+			}}
+		},
+		getFn: func(ctx context.Context, orderID string) (*Order, error) { return nil, nil },
+	}
+	svc := NewService(repo, ShippingRate{})
+	h := NewHandler(svc, "secret")
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	reqBody := []byte(`{"cart_id":"cart-1"}`)
+	req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	var body struct {
+		Error string          `json:"error"`
+		Items []StockShortage `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, "out_of_stock", body.Error)
+	require.Len(t, body.Items, 1)
+	require.Equal(t, "v-1", body.Items[0].VariantID)
+}
+
+func TestCheckout_409_PriceUnavailable(t *testing.T) {
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
+			return "", &PriceUnavailableError{Currency: "USD", VariantIDs: []string{"v-2"}}
+		},
+		getFn: func(ctx context.Context, orderID string) (*Order, error) { return nil, nil },
+	}
+	h := NewHandler(NewService(repo, ShippingRate{}), "secret")
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader([]byte(`{"cart_id":"cart-1"}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	var body struct {
+		Error      string   `json:"error"`
+		Currency   string   `json:"currency"`
+		VariantIDs []string `json:"variant_ids"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, "price_unavailable", body.Error)
+	require.Equal(t, "USD", body.Currency)
+	require.Equal(t, []string{"v-2"}, body.VariantIDs)
+}
+
+func TestCheckout_409_SplitShipment(t *testing.T) {
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
+			return "", ErrSplitShipment
+		},
+		getFn: func(ctx context.Context, orderID string) (*Order, error) { return nil, nil },
+	}
+	h := NewHandler(NewService(repo, ShippingRate{}), "secret")
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader([]byte(`{"cart_id":"cart-1"}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.JSONEq(t, `{"error":"split_shipment_required"}`, rec.Body.String())
+}
+
+func TestCheckout_409_ShippingUnpriced(t *testing.T) {
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
+			return "", ErrShippingUnpriced
+		},
+		getFn: func(ctx context.Context, orderID string) (*Order, error) { return nil, nil },
+	}
+	h := NewHandler(NewService(repo, ShippingRate{}), "secret")
+	r := chi.NewRouter()
+	h.Routes(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader([]byte(`{"cart_id":"cart-1"}`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.JSONEq(t, `{"error":"shipping_unpriced"}`, rec.Body.String())
+}
+
+func TestCheckout_RecordsSignedInUser(t *testing.T) {
+	var gotUser string
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
+			gotUser = userID
+			return "order-1", nil
+		},
+	}
+	secret := []byte("secret")
+	r := chi.NewRouter()
+	r.Use(httpx.OptionalAuth(secret))
+	NewHandler(NewService(repo, ShippingRate{}), "secret").Routes(r)
+
+	body := `{"cart_id":"cart-1","address":{}}`
+	req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader([]byte(body)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "", gotUser) // guest
+
+	token, err := httpx.SignJWT("u-1", secret, time.Hour)
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader([]byte(body)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "u-1", gotUser)
+
+	req = httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader([]byte(body)))
+	req.Header.Set("Authorization", "Bearer bogus")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestCheckout_GuestCartToken(t *testing.T) {
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot, rate ShippingRate) (string, error) {
+			if !guestToken {
+				return "", ErrForbidden
+			}
+			return "order-1", nil
+		},
+	}
+	r := chi.NewRouter()
+	NewHandler(NewService(repo, ShippingRate{}), "secret").Routes(r)
+
+	checkout := func(cartToken string) int {
+		req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader([]byte(`{"cart_id":"cart-1","address":{}}`)))
+		if cartToken != "" {
+			req.Header.Set(httpx.CartTokenHeader, cartToken)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	require.Equal(t, http.StatusForbidden, checkout(""))
+	require.Equal(t, http.StatusForbidden, checkout(httpx.SignCartToken("cart-2", []byte("secret"))))
+	require.Equal(t, http.StatusCreated, checkout(httpx.SignCartToken("cart-1", []byte("secret"))))
+}
@@ -1,5 +1,14 @@
 package order
 
+import "time"
+
+// Order line fulfillment types.
+const (
+	FulfillmentStock     = "stock"
+	FulfillmentBackorder = "backorder"
+	FulfillmentPreorder  = "preorder"
+)
+
 type Order struct {
 	ID          string      `json:"id"`
 	OrderNumber string      `json:"order_number"`
@@ -10,6 +19,10 @@ type Order struct {
 	Shipping    int64       `json:"shipping_total"`
 	GrandTotal  int64       `json:"grand_total"`
 	Items       []OrderItem `json:"items"`
+
+	WeightGrams int `json:"total_weight_grams"`
+
+	FulfillmentLocationID string `json:"fulfillment_location_id,omitempty"`
 }
 
 type OrderItem struct {
@@ -20,6 +33,26 @@ type OrderItem struct {
 	UnitPrice int64  `json:"unit_price"`
 	Qty       int    `json:"qty"`
 	LineTotal int64  `json:"line_total"`
+
+	// BackorderedQty units were not reserved at checkout and ship later.
+	BackorderedQty  int        `json:"backordered_qty"`
+	FulfillmentType string     `json:"fulfillment_type"`
+	ExpectedShipAt  *time.Time `json:"expected_ship_at,omitempty"`
+}
+
+// ShippingRate prices a shipment by weight: BaseFee plus PerKg for every
+// started kilogram, with a 1 kg minimum (how local couriers bill).
+type ShippingRate struct {
+	BaseFee int64
+	PerKg   int64
+}
+
+func (r ShippingRate) Cost(weightGrams int) int64 {
+	kg := int64((weightGrams + 999) / 1000)
+	if kg < 1 {
+		kg = 1
+	}
+	return r.BaseFee + kg*r.PerKg
 }
 
 type AddressSnapshot struct {
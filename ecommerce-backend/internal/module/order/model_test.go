@@ -0,0 +1,16 @@
+package order
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShippingRate_Cost(t *testing.T) {
+	rate := ShippingRate{BaseFee: 5000, PerKg: 10000}
+
+	require.Equal(t, int64(15000), rate.Cost(0))    // 1 kg minimum
+	require.Equal(t, int64(15000), rate.Cost(1000)) // exactly 1 kg
+	require.Equal(t, int64(25000), rate.Cost(1001)) // started 2nd kg
+	require.Equal(t, int64(35000), rate.Cost(2500))
+}
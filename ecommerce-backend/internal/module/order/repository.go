@@ -3,6 +3,8 @@ package order
 import "context"
 
 type Repository interface {
-	CreateOrderFromCart(ctx context.Context, cartID string, shipAddr AddressSnapshot) (string, error)
+	// CreateOrderFromCart fails with ErrForbidden unless userID owns the cart,
+	// or for a guest cart the caller presented its token (guestToken).
+	CreateOrderFromCart(ctx context.Context, cartID, userID string, guestToken bool, shipAddr AddressSnapshot, rate ShippingRate) (string, error)
 	GetOrder(ctx context.Context, orderID string) (*Order, error)
 }
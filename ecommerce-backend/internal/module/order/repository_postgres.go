@@ -6,14 +6,64 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/synchhans/ecommerce-backend/internal/module/inventory"
 )
 
 var ErrNotFound = errors.New("not found")
 var ErrEmptyCart = errors.New("empty cart")
+var ErrOutOfStock = errors.New("out of stock")
+var ErrForbidden = errors.New("cart belongs to someone else")
+
+// ErrSplitShipment is returned by checkout when every line is in stock
+// somewhere but no single location can ship the whole order; orders ship
+// from one location.
+var ErrSplitShipment = errors.New("no single location can ship the order")
+
+// StockShortage describes a cart line that cannot be reserved.
+type StockShortage struct {
+	VariantID string `json:"variant_id"`
+	SKU       string `json:"sku"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+}
+
+// OutOfStockError is returned by checkout when one or more variants
+// don't have enough unreserved stock. errors.Is(err, ErrOutOfStock) holds.
+type OutOfStockError struct {
+	Items []StockShortage
+}
+
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("out of stock: %d variant(s)", len(e.Items))
+}
+
+func (e *OutOfStockError) Is(target error) bool { return target == ErrOutOfStock }
+
+var ErrPriceUnavailable = errors.New("price unavailable in cart currency")
+
+// PriceUnavailableError is returned by checkout when lines can't be priced
+// in the cart's currency, e.g. the explicit price was removed or the FX rate
+// deleted after the item was added.
+type PriceUnavailableError struct {
+	Currency   string
+	VariantIDs []string
+}
+
+func (e *PriceUnavailableError) Error() string {
+	return fmt.Sprintf("no %s price for %d variant(s)", e.Currency, len(e.VariantIDs))
+}
+
+func (e *PriceUnavailableError) Is(target error) bool { return target == ErrPriceUnavailable }
+
+// ErrShippingUnpriced is returned by checkout when a non-zero shipping cost
+// (configured in the base currency) has no FX rate into the cart's currency.
+var ErrShippingUnpriced = errors.New("shipping can't be priced in cart currency")
 
 type PostgresRepository struct {
 	pool *pgxpool.Pool
@@ -23,7 +73,7 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 	return &PostgresRepository{pool: pool}
 }
 
-func (r *PostgresRepository) CreateOrderFromCart(ctx context.Context, cartID string, shipAddr AddressSnapshot) (string, error) {
+func (r *PostgresRepository) CreateOrderFromCart(ctx context.Context, cartID, userID string, guestToken bool, shipAddr AddressSnapshot, rate ShippingRate) (string, error) {
 	// Transaction is important.
 	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -32,95 +82,139 @@ func (r *PostgresRepository) CreateOrderFromCart(ctx context.Context, cartID str
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	// 1) lock cart row (simple)
-	var cartStatus string
-	err = tx.QueryRow(ctx, `SELECT status FROM carts WHERE id=$1 FOR UPDATE`, cartID).Scan(&cartStatus)
+	var cartStatus, currency, owner string
+	err = tx.QueryRow(ctx, `SELECT status, currency, COALESCE(user_id::text, '') FROM carts WHERE id=$1 FOR UPDATE`, cartID).Scan(&cartStatus, &currency, &owner)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return "", ErrNotFound
 		}
 		return "", err
 	}
-	if cartStatus != "active" {
+	if (owner == "" && !guestToken) || (owner != "" && owner != userID) {
+		return "", ErrForbidden
+	}
+	// an abandoned cart is still the shopper's to check out
+	if cartStatus != "active" && cartStatus != "abandoned" {
 		// treat as not found / invalid for now
 		return "", ErrNotFound
 	}
 
-	// 2) get cart items
+	// 2) get cart items, priced in the cart's currency
 	rows, err := tx.Query(ctx, `
-SELECT ci.variant_id::text, ci.qty, v.sku, v.name, v.price
+SELECT ci.variant_id::text, ci.qty, v.sku, v.name, vp.price, COALESCE(v.weight_grams, 0),
+       v.inventory_policy, v.backorder_limit, v.preorder_ships_at
 FROM cart_items ci
 JOIN product_variants v ON v.id = ci.variant_id
 JOIN products p ON p.id = v.product_id
+LEFT JOIN LATERAL variant_price_in(v.id, $2) vp ON true
 WHERE ci.cart_id = $1 AND v.is_active = true AND p.is_active = true
 ORDER BY ci.created_at ASC;
-`, cartID)
+`, cartID, currency)
 	if err != nil {
 		return "", err
 	}
 	defer rows.Close()
 
-	type itemRow struct {
-		variantID string
-		qty       int
-		sku       string
-		name      string
-		price     int64
-	}
-	var items []itemRow
+	var items []cartLine
 	var subtotal int64
+	var weight int
+	unpriced := &PriceUnavailableError{Currency: currency}
 
 	for rows.Next() {
-		var it itemRow
-		if err := rows.Scan(&it.variantID, &it.qty, &it.sku, &it.name, &it.price); err != nil {
+		var it cartLine
+		var price *int64
+		if err := rows.Scan(&it.variantID, &it.qty, &it.sku, &it.name, &price, &it.weightGrams,
+			&it.policy, &it.backorderLimit, &it.shipsAt); err != nil {
 			return "", err
 		}
 		if it.qty <= 0 {
 			continue
 		}
+		if price == nil {
+			unpriced.VariantIDs = append(unpriced.VariantIDs, it.variantID)
+			continue
+		}
+		it.price = *price
 		line := it.price * int64(it.qty)
 		subtotal += line
+		weight += it.weightGrams * it.qty
 		items = append(items, it)
 	}
 	if err := rows.Err(); err != nil {
 		return "", err
 	}
+	if len(unpriced.VariantIDs) > 0 {
+		return "", unpriced
+	}
 	if len(items) == 0 {
 		return "", ErrEmptyCart
 	}
 
+	// 3) pick the location that ships the order (locks its stock rows) and
+	//    decide how much of each line is reserved vs backordered
+	locationID, err := allocateLocation(ctx, tx, items, shipAddr.Province)
+	if err != nil {
+		return "", err
+	}
+
+	// shipping rates are configured in the base currency; free shipping
+	// needs no FX rate
+	shippingTotal := rate.Cost(weight)
+	if shippingTotal != 0 {
+		var converted *int64
+		err = tx.QueryRow(ctx, `
+SELECT convert_minor($1, (SELECT code FROM currencies WHERE is_base), $2);
+`, shippingTotal, currency).Scan(&converted)
+		if err != nil {
+			return "", err
+		}
+		if converted == nil {
+			return "", ErrShippingUnpriced
+		}
+		shippingTotal = *converted
+	}
+
 	discountTotal := int64(0)
-	shippingTotal := int64(0)
 	grandTotal := subtotal - discountTotal + shippingTotal
 
 	orderNumber := generateOrderNumber()
 
 	addrJSON, _ := json.Marshal(shipAddr)
 
-	// 3) create order
+	// 4) create order
 	var orderID string
 	err = tx.QueryRow(ctx, `
-INSERT INTO orders (order_number, cart_id, status, currency, subtotal, discount_total, shipping_total, grand_total, shipping_address_snapshot)
-VALUES ($1, $2, 'pending_payment', 'IDR', $3, $4, $5, $6, $7)
+INSERT INTO orders (order_number, cart_id, user_id, status, currency, subtotal, discount_total, shipping_total, grand_total, shipping_address_snapshot, fulfillment_location_id, total_weight_grams)
+VALUES ($1, $2, NULLIF($11, '')::uuid, 'pending_payment', $10, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id::text;
-`, orderNumber, cartID, subtotal, discountTotal, shippingTotal, grandTotal, addrJSON).Scan(&orderID)
+`, orderNumber, cartID, subtotal, discountTotal, shippingTotal, grandTotal, addrJSON, locationID, weight, currency, userID).Scan(&orderID)
 	if err != nil {
 		return "", err
 	}
 
-	// 4) create order items
+	// 5) create order items
 	for _, it := range items {
 		lineTotal := it.price * int64(it.qty)
 		_, err := tx.Exec(ctx, `
-INSERT INTO order_items (order_id, variant_id, sku, name, unit_price, qty, line_total)
-VALUES ($1, $2, $3, $4, $5, $6, $7);
-`, orderID, it.variantID, it.sku, it.name, it.price, it.qty, lineTotal)
+INSERT INTO order_items (order_id, variant_id, sku, name, unit_price, qty, line_total, backordered_qty, fulfillment_type, expected_ship_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+`, orderID, it.variantID, it.sku, it.name, it.price, it.qty, lineTotal, it.backorderedQty(), it.fulfillmentType(), it.expectedShipAt())
 		if err != nil {
 			return "", err
 		}
 	}
 
-	// 5) mark cart converted (optional but useful)
-	_, err = tx.Exec(ctx, `UPDATE carts SET status='converted', updated_at=now() WHERE id=$1;`, cartID)
+	// 6) reserve stock for every line (same tx, so a failed checkout reserves nothing)
+	if err := reserveStock(ctx, tx, orderID, locationID, items); err != nil {
+		return "", err
+	}
+
+	// 7) mark cart converted (optional but useful)
+	_, err = tx.Exec(ctx, `
+UPDATE carts
+SET status='converted', updated_at=now(),
+    recovered_at = CASE WHEN status = 'abandoned' THEN now() ELSE recovered_at END
+WHERE id=$1;`, cartID)
 	if err != nil {
 		return "", err
 	}
@@ -131,14 +225,287 @@ VALUES ($1, $2, $3, $4, $5, $6, $7);
 	return orderID, nil
 }
 
+type cartLine struct {
+	variantID string
+	qty       int
+	sku       string
+	name      string
+	price     int64
+
+	weightGrams int // per unit; 0 when the variant has no weight set
+
+	policy         string
+	backorderLimit *int
+	shipsAt        *time.Time
+
+	// filled by allocateLocation
+	reserveQty int
+}
+
+func (l cartLine) backorderable() bool {
+	return l.policy == inventory.PolicyBackorder || l.policy == inventory.PolicyPreorder
+}
+
+func (l cartLine) backorderedQty() int { return l.qty - l.reserveQty }
+
+func (l cartLine) fulfillmentType() string {
+	if l.backorderedQty() == 0 {
+		return FulfillmentStock
+	}
+	if l.policy == inventory.PolicyPreorder {
+		return FulfillmentPreorder
+	}
+	return FulfillmentBackorder
+}
+
+func (l cartLine) expectedShipAt() *time.Time {
+	if l.policy == inventory.PolicyPreorder && l.backorderedQty() > 0 {
+		return l.shipsAt
+	}
+	return nil
+}
+
+type locationStock struct {
+	id        string
+	code      string
+	province  string
+	available map[string]int // variant_id -> stock_on_hand - reserved
+}
+
+// allocateLocation locks the stock rows of all lines at every active location
+// (ordered, to avoid deadlocks between concurrent checkouts) and picks the
+// location that ships the order, see pickLocation. It then sets reserveQty on
+// every line: the full qty for deny lines, as much as the location has for
+// backorder/pre-order lines, the rest being backordered within the variant's
+// backorder_limit.
+// Lines that can't be satisfied are reported in an *OutOfStockError.
+func allocateLocation(ctx context.Context, tx pgx.Tx, lines []cartLine, province string) (string, error) {
+	ids := make([]string, 0, len(lines))
+	for _, l := range lines {
+		ids = append(ids, l.variantID)
+	}
+
+	// serialize checkouts competing for the same backorder allowance
+	allowance, err := backorderAllowance(ctx, tx, lines)
+	if err != nil {
+		return "", err
+	}
+
+	locRows, err := tx.Query(ctx, `
+SELECT id::text, code, province
+FROM locations
+WHERE is_active = true
+ORDER BY is_default DESC, code ASC;
+`)
+	if err != nil {
+		return "", err
+	}
+	defer locRows.Close()
+
+	byID := map[string]*locationStock{}
+	var locations []*locationStock
+	for locRows.Next() {
+		ls := &locationStock{available: map[string]int{}}
+		if err := locRows.Scan(&ls.id, &ls.code, &ls.province); err != nil {
+			return "", err
+		}
+		byID[ls.id] = ls
+		locations = append(locations, ls)
+	}
+	if err := locRows.Err(); err != nil {
+		return "", err
+	}
+
+	rows, err := tx.Query(ctx, `
+SELECT ii.location_id::text, ii.variant_id::text, ii.stock_on_hand - ii.reserved
+FROM inventory_items ii
+JOIN locations l ON l.id = ii.location_id
+WHERE ii.variant_id = ANY($1::uuid[]) AND l.is_active = true
+ORDER BY ii.variant_id, ii.location_id
+FOR UPDATE OF ii;
+`, ids)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var locID, variantID string
+		var n int
+		if err := rows.Scan(&locID, &variantID, &n); err != nil {
+			return "", err
+		}
+		if ls, ok := byID[locID]; ok {
+			ls.available[variantID] = max(n, 0)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	loc := pickLocation(locations, lines, province)
+
+	var shortages []StockShortage
+	for i := range lines {
+		l := &lines[i]
+		best := 0
+		for _, ls := range locations {
+			best = max(best, ls.available[l.variantID])
+		}
+
+		avail := 0
+		if loc != nil {
+			avail = loc.available[l.variantID]
+		}
+		l.reserveQty = min(avail, l.qty)
+
+		short := l.qty - l.reserveQty
+		switch {
+		case short == 0:
+		case !l.backorderable():
+			if loc == nil || best < l.qty {
+				shortages = append(shortages, StockShortage{VariantID: l.variantID, SKU: l.sku, Requested: l.qty, Available: best})
+			}
+		case allowance[l.variantID] >= 0 && short > allowance[l.variantID]:
+			shortages = append(shortages, StockShortage{VariantID: l.variantID, SKU: l.sku, Requested: l.qty, Available: l.reserveQty + allowance[l.variantID]})
+		}
+	}
+	if len(shortages) == 0 && loc == nil {
+		// every deny line is in stock somewhere, just not all at one location
+		return "", ErrSplitShipment
+	}
+	if len(shortages) > 0 {
+		return "", &OutOfStockError{Items: shortages}
+	}
+	return loc.id, nil
+}
+
+// backorderAllowance locks the backorderable variants of the order and returns
+// how many more units each may still backorder (-1 = unlimited). Outstanding
+// backorders come from variant_backordered; receiving stock fills them and
+// lowers backordered_qty (see inventory).
+func backorderAllowance(ctx context.Context, tx pgx.Tx, lines []cartLine) (map[string]int, error) {
+	out := map[string]int{}
+	var ids []string
+	for _, l := range lines {
+		if !l.backorderable() {
+			continue
+		}
+		ids = append(ids, l.variantID)
+		out[l.variantID] = -1
+		if l.backorderLimit != nil {
+			out[l.variantID] = *l.backorderLimit
+		}
+	}
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+SELECT id FROM product_variants
+WHERE id = ANY($1::uuid[])
+ORDER BY id
+FOR UPDATE;
+`, ids); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+SELECT id::text, b.qty
+FROM unnest($1::uuid[]) AS id
+CROSS JOIN LATERAL variant_backordered(id) b;
+`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var outstanding int
+		if err := rows.Scan(&id, &outstanding); err != nil {
+			return nil, err
+		}
+		if out[id] >= 0 {
+			out[id] = max(out[id]-outstanding, 0)
+		}
+	}
+	return out, rows.Err()
+}
+
+// pickLocation returns a location that can ship every deny line in full, or
+// nil. Among those it prefers the one that can reserve the most units (fewest
+// backorders), then a location in the shipping province, then the one with
+// the most stock for the order.
+func pickLocation(locations []*locationStock, lines []cartLine, province string) *locationStock {
+	province = strings.TrimSpace(province)
+
+	var best *locationStock
+	bestCovered, bestLocal, bestTotal := 0, false, 0
+	for _, loc := range locations {
+		covered, total, ok := 0, 0, true
+		for _, l := range lines {
+			avail := loc.available[l.variantID]
+			if avail < l.qty && !l.backorderable() {
+				ok = false
+				break
+			}
+			covered += min(avail, l.qty)
+			total += avail
+		}
+		if !ok {
+			continue
+		}
+
+		local := province != "" && strings.EqualFold(strings.TrimSpace(loc.province), province)
+		switch {
+		case best == nil,
+			covered > bestCovered,
+			covered == bestCovered && local && !bestLocal,
+			covered == bestCovered && local == bestLocal && total > bestTotal,
+			covered == bestCovered && local == bestLocal && total == bestTotal && loc.code < best.code:
+			best, bestCovered, bestLocal, bestTotal = loc, covered, local, total
+		}
+	}
+	return best
+}
+
+// reserveStock moves each line's reserveQty into reserved at the allocated
+// location and writes a "reservation" ledger entry per line. Backordered units
+// are not reserved. The rows are already locked by allocateLocation.
+func reserveStock(ctx context.Context, tx pgx.Tx, orderID, locationID string, lines []cartLine) error {
+	for _, l := range lines {
+		if l.reserveQty == 0 {
+			continue
+		}
+		_, err := tx.Exec(ctx, `
+WITH upd AS (
+  UPDATE inventory_items
+  SET reserved = reserved + $3, updated_at = now()
+  WHERE variant_id = $1 AND location_id = $2
+  RETURNING variant_id, location_id, stock_on_hand, reserved
+)
+INSERT INTO inventory_movements (variant_id, location_id, kind, reserved_delta, on_hand_after, reserved_after, reason_code, order_id)
+SELECT variant_id, location_id, 'reservation', $3, stock_on_hand, reserved, 'checkout', $4
+FROM upd;
+`, l.variantID, locationID, l.reserveQty, orderID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *PostgresRepository) GetOrder(ctx context.Context, orderID string) (*Order, error) {
 	var o Order
 	err := r.pool.QueryRow(ctx, `
-SELECT id::text, order_number, status, currency, subtotal, discount_total, shipping_total, grand_total
+SELECT id::text, order_number, status, currency, subtotal, discount_total, shipping_total, grand_total,
+       COALESCE(fulfillment_location_id::text, ''), total_weight_grams
 FROM orders
 WHERE id=$1
 LIMIT 1;
-`, orderID).Scan(&o.ID, &o.OrderNumber, &o.Status, &o.Currency, &o.Subtotal, &o.Discount, &o.Shipping, &o.GrandTotal)
+`, orderID).Scan(&o.ID, &o.OrderNumber, &o.Status, &o.Currency, &o.Subtotal, &o.Discount, &o.Shipping, &o.GrandTotal,
+		&o.FulfillmentLocationID, &o.WeightGrams)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -147,7 +514,8 @@ LIMIT 1;
 	}
 
 	rows, err := r.pool.Query(ctx, `
-SELECT id::text, variant_id::text, sku, name, unit_price, qty, line_total
+SELECT id::text, variant_id::text, sku, name, unit_price, qty, line_total,
+       backordered_qty, fulfillment_type, expected_ship_at
 FROM order_items
 WHERE order_id=$1
 ORDER BY id ASC;
@@ -159,7 +527,8 @@ ORDER BY id ASC;
 
 	for rows.Next() {
 		var it OrderItem
-		if err := rows.Scan(&it.ID, &it.VariantID, &it.SKU, &it.Name, &it.UnitPrice, &it.Qty, &it.LineTotal); err != nil {
+		if err := rows.Scan(&it.ID, &it.VariantID, &it.SKU, &it.Name, &it.UnitPrice, &it.Qty, &it.LineTotal,
+			&it.BackorderedQty, &it.FulfillmentType, &it.ExpectedShipAt); err != nil {
 			return nil, err
 		}
 		o.Items = append(o.Items, it)
@@ -3,15 +3,18 @@ package order
 import "context"
 
 type Service struct {
-	repo Repository
+	repo     Repository
+	shipping ShippingRate
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, shipping ShippingRate) *Service {
+	return &Service{repo: repo, shipping: shipping}
 }
 
-func (s *Service) Checkout(ctx context.Context, cartID string, addr AddressSnapshot) (string, error) {
-	return s.repo.CreateOrderFromCart(ctx, cartID, addr)
+// Checkout places the order; userID is "" for guest checkouts. guestToken
+// tells whether the caller presented the cart token of a guest cart.
+func (s *Service) Checkout(ctx context.Context, cartID, userID string, guestToken bool, addr AddressSnapshot) (string, error) {
+	return s.repo.CreateOrderFromCart(ctx, cartID, userID, guestToken, addr, s.shipping)
 }
 
 func (s *Service) GetOrder(ctx context.Context, orderID string) (*Order, error) {
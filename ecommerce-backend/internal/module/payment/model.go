@@ -4,7 +4,8 @@ type InitiateResult struct {
 	PaymentID   string `json:"payment_id"`
 	OrderID     string `json:"order_id"`
 	Status      string `json:"status"`
-	Amount      int64  `json:"amount"`
+	Amount      int64  `json:"amount"` // minor units of Currency
+	Currency    string `json:"currency"`
 	Provider    string `json:"provider"`
 	ProviderRef string `json:"provider_ref"`
 	PayURL      string `json:"pay_url,omitempty"`
@@ -24,14 +24,14 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 
 func (r *PostgresRepository) InitiatePayment(ctx context.Context, orderID, provider string) (*InitiateResult, error) {
 	// get order total + status
-	var status string
+	var status, currency string
 	var amount int64
 	err := r.pool.QueryRow(ctx, `
-SELECT status, grand_total
+SELECT status, grand_total, currency
 FROM orders
 WHERE id=$1
 LIMIT 1;
-`, orderID).Scan(&status, &amount)
+`, orderID).Scan(&status, &amount, &currency)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -51,10 +51,10 @@ LIMIT 1;
 	var payURL *string
 	// for provider "manual", no pay_url; later provider can return URL
 	err = r.pool.QueryRow(ctx, `
-INSERT INTO payments (order_id, provider, status, amount, provider_ref, pay_url)
-VALUES ($1, $2, 'initiated', $3, $4, $5)
+INSERT INTO payments (order_id, provider, status, amount, currency, provider_ref, pay_url)
+VALUES ($1, $2, 'initiated', $3, $6, $4, $5)
 RETURNING id::text;
-`, orderID, provider, amount, providerRef, payURL).Scan(&paymentID)
+`, orderID, provider, amount, providerRef, payURL, currency).Scan(&paymentID)
 	if err != nil {
 		return nil, err
 	}
@@ -64,6 +64,7 @@ RETURNING id::text;
 		OrderID:     orderID,
 		Status:      "initiated",
 		Amount:      amount,
+		Currency:    currency,
 		Provider:    provider,
 		ProviderRef: providerRef,
 	}, nil
@@ -110,24 +111,36 @@ WHERE provider=$3 AND provider_ref=$4;
 		return nil, err
 	}
 
-	// map payment status -> order status (simple)
+	// map payment status -> order status (simple).
+	// Reservations are only settled when the order actually transitions out of
+	// pending_payment, so replayed webhooks don't touch stock twice.
 	if newStatus == "paid" {
-		_, err = tx.Exec(ctx, `
+		ct, err := tx.Exec(ctx, `
 UPDATE orders SET status='paid', updated_at=now()
 WHERE id=$1 AND status='pending_payment';
 `, orderID)
 		if err != nil {
 			return nil, err
 		}
+		if ct.RowsAffected() > 0 {
+			if err := commitReservation(ctx, tx, orderID); err != nil {
+				return nil, err
+			}
+		}
 	}
 	if newStatus == "failed" || newStatus == "expired" {
-		_, err = tx.Exec(ctx, `
+		ct, err := tx.Exec(ctx, `
 UPDATE orders SET status='canceled', updated_at=now()
 WHERE id=$1 AND status='pending_payment';
 `, orderID)
 		if err != nil {
 			return nil, err
 		}
+		if ct.RowsAffected() > 0 {
+			if err := releaseReservation(ctx, tx, orderID, "payment_"+newStatus); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -141,6 +154,59 @@ WHERE id=$1 AND status='pending_payment';
 	}, nil
 }
 
+// commitReservation turns the stock reserved at checkout into a sale at the
+// order's fulfillment location: both stock_on_hand and reserved drop by the
+// reserved part of each line (backordered units were never reserved).
+func commitReservation(ctx context.Context, tx pgx.Tx, orderID string) error {
+	_, err := tx.Exec(ctx, `
+WITH oi AS (
+  SELECT i.variant_id, o.fulfillment_location_id AS location_id, SUM(i.qty - i.backordered_qty)::int AS qty
+  FROM order_items i
+  JOIN orders o ON o.id = i.order_id
+  WHERE i.order_id = $1
+  GROUP BY i.variant_id, o.fulfillment_location_id
+  HAVING SUM(i.qty - i.backordered_qty) > 0
+), upd AS (
+  UPDATE inventory_items ii
+  SET stock_on_hand = ii.stock_on_hand - oi.qty,
+      reserved = ii.reserved - oi.qty,
+      updated_at = now()
+  FROM oi
+  WHERE ii.variant_id = oi.variant_id AND ii.location_id = oi.location_id
+  RETURNING ii.variant_id, ii.location_id, ii.stock_on_hand, ii.reserved, oi.qty
+)
+INSERT INTO inventory_movements (variant_id, location_id, kind, on_hand_delta, reserved_delta, on_hand_after, reserved_after, reason_code, order_id)
+SELECT variant_id, location_id, 'sale', -qty, -qty, stock_on_hand, reserved, 'payment_paid', $1
+FROM upd;
+`, orderID)
+	return err
+}
+
+// releaseReservation gives the stock reserved at checkout back to the pool.
+func releaseReservation(ctx context.Context, tx pgx.Tx, orderID, reason string) error {
+	_, err := tx.Exec(ctx, `
+WITH oi AS (
+  SELECT i.variant_id, o.fulfillment_location_id AS location_id, SUM(i.qty - i.backordered_qty)::int AS qty
+  FROM order_items i
+  JOIN orders o ON o.id = i.order_id
+  WHERE i.order_id = $1
+  GROUP BY i.variant_id, o.fulfillment_location_id
+  HAVING SUM(i.qty - i.backordered_qty) > 0
+), upd AS (
+  UPDATE inventory_items ii
+  SET reserved = ii.reserved - oi.qty,
+      updated_at = now()
+  FROM oi
+  WHERE ii.variant_id = oi.variant_id AND ii.location_id = oi.location_id
+  RETURNING ii.variant_id, ii.location_id, ii.stock_on_hand, ii.reserved, oi.qty
+)
+INSERT INTO inventory_movements (variant_id, location_id, kind, reserved_delta, on_hand_after, reserved_after, reason_code, order_id)
+SELECT variant_id, location_id, 'release', -qty, stock_on_hand, reserved, $2, $1
+FROM upd;
+`, orderID, reason)
+	return err
+}
+
 func newRef() string {
 	b := make([]byte, 16)
 	_, _ = rand.Read(b)
@@ -0,0 +1,156 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler { return &Handler{svc: svc} }
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/currencies", h.listActiveCurrencies)
+}
+
+// AdminRoutes must be mounted behind AuthMiddleware + RequireRole("admin").
+func (h *Handler) AdminRoutes(r chi.Router) {
+	r.Get("/admin/currencies", h.listCurrencies)
+	r.Put("/admin/currencies/{code}", h.saveCurrency)
+
+	r.Get("/admin/fx-rates", h.listFXRates)
+	r.Put("/admin/fx-rates/{base}/{quote}", h.setFXRate)
+	r.Delete("/admin/fx-rates/{base}/{quote}", h.deleteFXRate)
+
+	r.Get("/admin/variants/{id}/prices", h.variantPrices)
+	r.Put("/admin/variants/{id}/prices/{currency}", h.setVariantPrice)
+	r.Delete("/admin/variants/{id}/prices/{currency}", h.deleteVariantPrice)
+}
+
+func (h *Handler) listActiveCurrencies(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.Currencies(r.Context(), true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) listCurrencies(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.Currencies(r.Context(), false)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) saveCurrency(w http.ResponseWriter, r *http.Request) {
+	var in CurrencyInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	c, err := h.svc.SaveCurrency(r.Context(), chi.URLParam(r, "code"), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Handler) listFXRates(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.FXRates(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+type fxRateReq struct {
+	Rate json.Number `json:"rate"` // "16250.5" or 16250.5
+}
+
+func (h *Handler) setFXRate(w http.ResponseWriter, r *http.Request) {
+	var req fxRateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	fx, err := h.svc.SetFXRate(r.Context(), chi.URLParam(r, "base"), chi.URLParam(r, "quote"), req.Rate.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fx)
+}
+
+func (h *Handler) deleteFXRate(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteFXRate(r.Context(), chi.URLParam(r, "base"), chi.URLParam(r, "quote")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) variantPrices(w http.ResponseWriter, r *http.Request) {
+	items, err := h.svc.VariantPrices(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) setVariantPrice(w http.ResponseWriter, r *http.Request) {
+	var in VariantPriceInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	items, err := h.svc.SetVariantPrice(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "currency"), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) deleteVariantPrice(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteVariantPrice(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "currency")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPayload):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+	case errors.Is(err, ErrInvalidCode):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_currency"})
+	case errors.Is(err, ErrBaseCurrency):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "base_currency"})
+	case errors.Is(err, ErrExponentLocked):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "exponent_locked"})
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
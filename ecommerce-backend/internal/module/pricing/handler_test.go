@@ -0,0 +1,181 @@
+package pricing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+)
+
+type fakeRepo struct {
+	currencies map[string]Currency
+	upserted   *Currency
+	fxRate     string
+	prices     map[string]VariantPriceInput
+}
+
+func (f *fakeRepo) ListCurrencies(ctx context.Context, activeOnly bool) ([]Currency, error) {
+	out := []Currency{}
+	for _, c := range f.currencies {
+		if c.IsActive || !activeOnly {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+func (f *fakeRepo) GetCurrency(ctx context.Context, code string) (*Currency, error) {
+	c, ok := f.currencies[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &c, nil
+}
+func (f *fakeRepo) UpsertCurrency(ctx context.Context, c Currency) (*Currency, error) {
+	f.upserted = &c
+	f.currencies[c.Code] = c
+	return &c, nil
+}
+func (f *fakeRepo) ListFXRates(ctx context.Context) ([]FXRate, error) { return []FXRate{}, nil }
+func (f *fakeRepo) SetFXRate(ctx context.Context, base, quote, rate string) (*FXRate, error) {
+	f.fxRate = base + "/" + quote + "=" + rate
+	return &FXRate{Base: base, Quote: quote, Rate: rate, UpdatedAt: time.Now()}, nil
+}
+func (f *fakeRepo) DeleteFXRate(ctx context.Context, base, quote string) error { return nil }
+func (f *fakeRepo) VariantPrices(ctx context.Context, variantID string) ([]VariantPrice, error) {
+	return []VariantPrice{}, nil
+}
+func (f *fakeRepo) SetVariantPrice(ctx context.Context, variantID, currency string, in VariantPriceInput) error {
+	f.prices[variantID+":"+currency] = in
+	return nil
+}
+func (f *fakeRepo) DeleteVariantPrice(ctx context.Context, variantID, currency string) error {
+	return nil
+}
+
+func newFake() *fakeRepo {
+	return &fakeRepo{
+		currencies: map[string]Currency{
+			"IDR": {Code: "IDR", Exponent: 0, IsBase: true, IsActive: true},
+			"USD": {Code: "USD", Exponent: 2, IsActive: true},
+			"EUR": {Code: "EUR", Exponent: 2, IsActive: false},
+		},
+		prices: map[string]VariantPriceInput{},
+	}
+}
+
+func adminRouter(t *testing.T, h *Handler) (chi.Router, string) {
+	t.Helper()
+	secret := []byte("secret")
+	token, err := httpx.SignJWTWithRole("u-admin", "admin", secret, time.Hour)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	h.Routes(r)
+	r.Group(func(ar chi.Router) {
+		ar.Use(httpx.AuthMiddleware(secret))
+		ar.Use(httpx.RequireRole("admin"))
+		h.AdminRoutes(ar)
+	})
+	return r, token
+}
+
+func do(r chi.Router, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPricing_ListActiveCurrencies(t *testing.T) {
+	r, _ := adminRouter(t, NewHandler(NewService(newFake())))
+
+	rec := do(r, "", http.MethodGet, "/currencies", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"USD"`)
+	require.NotContains(t, rec.Body.String(), `"EUR"`)
+}
+
+func TestPricing_SaveCurrency_ISOExponent(t *testing.T) {
+	repo := newFake()
+	r, token := adminRouter(t, NewHandler(NewService(repo)))
+
+	rec := do(r, token, http.MethodPut, "/admin/currencies/kwd", `{"fx_fallback":true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "KWD", repo.upserted.Code)
+	require.Equal(t, 3, repo.upserted.Exponent)
+	require.True(t, repo.upserted.IsActive)
+	require.True(t, repo.upserted.FXFallback)
+
+	// not in the ISO table and no explicit exponent
+	rec = do(r, token, http.MethodPut, "/admin/currencies/XYZ", `{}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(r, token, http.MethodPut, "/admin/currencies/US1", `{"exponent":2}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "invalid_currency")
+
+	// base currency stays active
+	rec = do(r, token, http.MethodPut, "/admin/currencies/IDR", `{"is_active":false}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestPricing_SaveCurrency_ExponentLocked(t *testing.T) {
+	repo := newFake()
+	r, token := adminRouter(t, NewHandler(NewService(repo)))
+
+	rec := do(r, token, http.MethodPut, "/admin/currencies/USD", `{"exponent":3}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "exponent_locked")
+
+	// restating the current exponent is fine
+	rec = do(r, token, http.MethodPut, "/admin/currencies/USD", `{"exponent":2,"fx_fallback":true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 2, repo.upserted.Exponent)
+}
+
+func TestPricing_SetFXRate(t *testing.T) {
+	repo := newFake()
+	r, token := adminRouter(t, NewHandler(NewService(repo)))
+
+	rec := do(r, token, http.MethodPut, "/admin/fx-rates/idr/usd", `{"rate":"0.0000615"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "IDR/USD=0.0000615", repo.fxRate)
+
+	rec = do(r, token, http.MethodPut, "/admin/fx-rates/USD/IDR", `{"rate":16250}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "USD/IDR=16250", repo.fxRate)
+
+	for _, body := range []string{`{"rate":0}`, `{"rate":-1}`, `{"rate":"1/3"}`, `{"rate":"1e3"}`} {
+		rec = do(r, token, http.MethodPut, "/admin/fx-rates/USD/IDR", body)
+		require.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestPricing_SetVariantPrice(t *testing.T) {
+	repo := newFake()
+	r, token := adminRouter(t, NewHandler(NewService(repo)))
+
+	rec := do(r, token, http.MethodPut, "/admin/variants/v1/prices/usd", `{"price":1999,"compare_at_price":2499}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, int64(1999), *repo.prices["v1:USD"].Price)
+
+	// the base price list is product_variants.price
+	rec = do(r, token, http.MethodPut, "/admin/variants/v1/prices/IDR", `{"price":300000}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = do(r, token, http.MethodPut, "/admin/variants/v1/prices/JPY", `{"price":300}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = do(r, token, http.MethodPut, "/admin/variants/v1/prices/USD", `{"price":-1}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
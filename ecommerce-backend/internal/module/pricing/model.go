@@ -0,0 +1,53 @@
+package pricing
+
+import "time"
+
+// Currency is a currency we can sell in. Amounts are stored in minor units;
+// Exponent says how many of them make a major unit (ISO 4217).
+type Currency struct {
+	Code       string    `json:"code"`
+	Exponent   int       `json:"exponent"`
+	IsBase     bool      `json:"is_base"`
+	IsActive   bool      `json:"is_active"`
+	FXFallback bool      `json:"fx_fallback"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CurrencyInput upserts a currency; nil fields keep their current value
+// (or the default when the currency is new).
+type CurrencyInput struct {
+	Exponent   *int  `json:"exponent"`
+	IsActive   *bool `json:"is_active"`
+	FXFallback *bool `json:"fx_fallback"`
+}
+
+// FXRate: 1 major unit of Base = Rate major units of Quote.
+// Rate is a decimal string to avoid float rounding.
+type FXRate struct {
+	Base      string    `json:"base"`
+	Quote     string    `json:"quote"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Price sources reported by VariantPrices.
+const (
+	SourceBase        = "base"     // product_variants.price
+	SourceExplicit    = "explicit" // variant_prices row
+	SourceFX          = "fx"       // base price converted with fx_rates
+	SourceUnavailable = "unavailable"
+)
+
+// VariantPrice is a variant's resolved price in one currency.
+type VariantPrice struct {
+	VariantID      string `json:"variant_id"`
+	Currency       string `json:"currency"`
+	Price          *int64 `json:"price"`
+	CompareAtPrice *int64 `json:"compare_at_price,omitempty"`
+	Source         string `json:"source"`
+}
+
+type VariantPriceInput struct {
+	Price          *int64 `json:"price"`
+	CompareAtPrice *int64 `json:"compare_at_price"`
+}
@@ -0,0 +1,18 @@
+package pricing
+
+import "context"
+
+type Repository interface {
+	ListCurrencies(ctx context.Context, activeOnly bool) ([]Currency, error)
+	GetCurrency(ctx context.Context, code string) (*Currency, error)
+	UpsertCurrency(ctx context.Context, c Currency) (*Currency, error)
+
+	ListFXRates(ctx context.Context) ([]FXRate, error)
+	SetFXRate(ctx context.Context, base, quote, rate string) (*FXRate, error)
+	DeleteFXRate(ctx context.Context, base, quote string) error
+
+	// VariantPrices resolves the variant's price in every active currency.
+	VariantPrices(ctx context.Context, variantID string) ([]VariantPrice, error)
+	SetVariantPrice(ctx context.Context, variantID, currency string, in VariantPriceInput) error
+	DeleteVariantPrice(ctx context.Context, variantID, currency string) error
+}
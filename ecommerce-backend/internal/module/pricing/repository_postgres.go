@@ -0,0 +1,192 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+const currencyCols = `code, exponent, is_base, is_active, fx_fallback, updated_at`
+
+func scanCurrency(row pgx.Row) (*Currency, error) {
+	var c Currency
+	if err := row.Scan(&c.Code, &c.Exponent, &c.IsBase, &c.IsActive, &c.FXFallback, &c.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, mapPgError(err)
+	}
+	return &c, nil
+}
+
+func (r *PostgresRepository) ListCurrencies(ctx context.Context, activeOnly bool) ([]Currency, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT `+currencyCols+`
+FROM currencies
+WHERE is_active OR NOT $1
+ORDER BY is_base DESC, code ASC;
+`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Currency{}
+	for rows.Next() {
+		c, err := scanCurrency(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) GetCurrency(ctx context.Context, code string) (*Currency, error) {
+	return scanCurrency(r.pool.QueryRow(ctx, `SELECT `+currencyCols+` FROM currencies WHERE code = $1;`, code))
+}
+
+func (r *PostgresRepository) UpsertCurrency(ctx context.Context, c Currency) (*Currency, error) {
+	return scanCurrency(r.pool.QueryRow(ctx, `
+INSERT INTO currencies (code, exponent, is_active, fx_fallback)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (code) DO UPDATE
+SET exponent = EXCLUDED.exponent,
+    is_active = EXCLUDED.is_active,
+    fx_fallback = EXCLUDED.fx_fallback,
+    updated_at = now()
+RETURNING `+currencyCols+`;
+`, c.Code, c.Exponent, c.IsActive, c.FXFallback))
+}
+
+func (r *PostgresRepository) ListFXRates(ctx context.Context) ([]FXRate, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT base_currency, quote_currency, rate::text, updated_at
+FROM fx_rates
+ORDER BY base_currency, quote_currency;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []FXRate{}
+	for rows.Next() {
+		var fx FXRate
+		if err := rows.Scan(&fx.Base, &fx.Quote, &fx.Rate, &fx.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, fx)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) SetFXRate(ctx context.Context, base, quote, rate string) (*FXRate, error) {
+	var fx FXRate
+	err := r.pool.QueryRow(ctx, `
+INSERT INTO fx_rates (base_currency, quote_currency, rate)
+VALUES ($1, $2, $3::text::numeric)
+ON CONFLICT (base_currency, quote_currency) DO UPDATE
+SET rate = EXCLUDED.rate, updated_at = now()
+RETURNING base_currency, quote_currency, rate::text, updated_at;
+`, base, quote, rate).Scan(&fx.Base, &fx.Quote, &fx.Rate, &fx.UpdatedAt)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return &fx, nil
+}
+
+func (r *PostgresRepository) DeleteFXRate(ctx context.Context, base, quote string) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM fx_rates WHERE base_currency = $1 AND quote_currency = $2;`, base, quote)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) VariantPrices(ctx context.Context, variantID string) ([]VariantPrice, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT c.code, p.price, p.compare_at_price,
+       CASE
+         WHEN p.price IS NULL THEN 'unavailable'
+         WHEN vp.variant_id IS NOT NULL THEN 'explicit'
+         WHEN c.is_base THEN 'base'
+         ELSE 'fx'
+       END
+FROM product_variants v
+CROSS JOIN currencies c
+LEFT JOIN variant_prices vp ON vp.variant_id = v.id AND vp.currency = c.code
+LEFT JOIN LATERAL variant_price_in(v.id, c.code) p ON true
+WHERE v.id = $1 AND c.is_active
+ORDER BY c.is_base DESC, c.code ASC;
+`, variantID)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	defer rows.Close()
+
+	out := []VariantPrice{}
+	for rows.Next() {
+		vp := VariantPrice{VariantID: variantID}
+		if err := rows.Scan(&vp.Currency, &vp.Price, &vp.CompareAtPrice, &vp.Source); err != nil {
+			return nil, err
+		}
+		out = append(out, vp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		// the base currency is always active, so no rows means no variant
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+func (r *PostgresRepository) SetVariantPrice(ctx context.Context, variantID, currency string, in VariantPriceInput) error {
+	_, err := r.pool.Exec(ctx, `
+INSERT INTO variant_prices (variant_id, currency, price, compare_at_price)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (variant_id, currency) DO UPDATE
+SET price = EXCLUDED.price, compare_at_price = EXCLUDED.compare_at_price, updated_at = now();
+`, variantID, currency, in.Price, in.CompareAtPrice)
+	return mapPgError(err)
+}
+
+func (r *PostgresRepository) DeleteVariantPrice(ctx context.Context, variantID, currency string) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM variant_prices WHERE variant_id = $1 AND currency = $2;`, variantID, currency)
+	if err != nil {
+		return mapPgError(err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503", "22P02": // foreign_key_violation, invalid_text_representation (bad uuid)
+			return ErrNotFound
+		case "23514": // check_violation
+			return ErrInvalidPayload
+		}
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/synchhans/ecommerce-backend/internal/platform/money"
+)
+
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrInvalidPayload = errors.New("invalid payload")
+	ErrInvalidCode    = errors.New("invalid currency code")
+	// ErrBaseCurrency: the base currency is priced by product_variants.price
+	// and must stay active.
+	ErrBaseCurrency = errors.New("operation not allowed on the base currency")
+	// ErrExponentLocked: stored amounts are minor units, so a currency's
+	// exponent can't change once it exists.
+	ErrExponentLocked = errors.New("currency exponent can't be changed")
+)
+
+// plain positive decimals only, as numeric(20,10) stores them
+var rateRe = regexp.MustCompile(`^[0-9]{1,10}(\.[0-9]{1,10})?$`)
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Currencies(ctx context.Context, activeOnly bool) ([]Currency, error) {
+	return s.repo.ListCurrencies(ctx, activeOnly)
+}
+
+// SaveCurrency creates or updates a currency. New ISO currencies get their
+// exponent from the ISO table; anything else must pass it explicitly. The
+// exponent of an existing currency is fixed.
+func (s *Service) SaveCurrency(ctx context.Context, code string, in CurrencyInput) (*Currency, error) {
+	code = money.Normalize(code)
+	if !money.ValidCode(code) {
+		return nil, ErrInvalidCode
+	}
+
+	c, err := s.repo.GetCurrency(ctx, code)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c = &Currency{Code: code, IsActive: true}
+		exp, ok := money.Exponent(code)
+		if in.Exponent == nil && !ok {
+			return nil, ErrInvalidPayload
+		}
+		c.Exponent = exp
+		if in.Exponent != nil {
+			if *in.Exponent < 0 || *in.Exponent > 4 {
+				return nil, ErrInvalidPayload
+			}
+			c.Exponent = *in.Exponent
+		}
+	case err != nil:
+		return nil, err
+	case in.Exponent != nil && *in.Exponent != c.Exponent:
+		return nil, ErrExponentLocked
+	}
+	if in.IsActive != nil {
+		c.IsActive = *in.IsActive
+	}
+	if in.FXFallback != nil {
+		c.FXFallback = *in.FXFallback
+	}
+	if c.IsBase && !c.IsActive {
+		return nil, ErrBaseCurrency
+	}
+	return s.repo.UpsertCurrency(ctx, *c)
+}
+
+func (s *Service) FXRates(ctx context.Context) ([]FXRate, error) {
+	return s.repo.ListFXRates(ctx)
+}
+
+// SetFXRate stores a positive decimal rate: 1 base = rate quote, e.g.
+// base=USD quote=IDR rate="16250". One pair serves both directions, the
+// inverse is used when only the other direction is stored.
+func (s *Service) SetFXRate(ctx context.Context, base, quote, rate string) (*FXRate, error) {
+	base, quote = money.Normalize(base), money.Normalize(quote)
+	if !money.ValidCode(base) || !money.ValidCode(quote) {
+		return nil, ErrInvalidCode
+	}
+	if base == quote {
+		return nil, ErrInvalidPayload
+	}
+	if !rateRe.MatchString(rate) || strings.Trim(rate, "0.") == "" {
+		return nil, ErrInvalidPayload
+	}
+	return s.repo.SetFXRate(ctx, base, quote, rate)
+}
+
+func (s *Service) DeleteFXRate(ctx context.Context, base, quote string) error {
+	return s.repo.DeleteFXRate(ctx, money.Normalize(base), money.Normalize(quote))
+}
+
+func (s *Service) VariantPrices(ctx context.Context, variantID string) ([]VariantPrice, error) {
+	if variantID == "" {
+		return nil, ErrNotFound
+	}
+	return s.repo.VariantPrices(ctx, variantID)
+}
+
+// SetVariantPrice puts an explicit price for the variant on a currency's price list.
+func (s *Service) SetVariantPrice(ctx context.Context, variantID, currency string, in VariantPriceInput) ([]VariantPrice, error) {
+	currency = money.Normalize(currency)
+	if in.Price == nil || *in.Price < 0 || (in.CompareAtPrice != nil && *in.CompareAtPrice < 0) {
+		return nil, ErrInvalidPayload
+	}
+	c, err := s.repo.GetCurrency(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	if c.IsBase {
+		return nil, ErrBaseCurrency
+	}
+	if err := s.repo.SetVariantPrice(ctx, variantID, currency, in); err != nil {
+		return nil, err
+	}
+	return s.repo.VariantPrices(ctx, variantID)
+}
+
+func (s *Service) DeleteVariantPrice(ctx context.Context, variantID, currency string) error {
+	return s.repo.DeleteVariantPrice(ctx, variantID, money.Normalize(currency))
+}
@@ -0,0 +1,182 @@
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/products/{slug}/reviews", h.listProductReviews)
+}
+
+// UserRoutes must be mounted behind AuthMiddleware.
+func (h *Handler) UserRoutes(r chi.Router) {
+	r.Post("/products/{slug}/reviews", h.submit)
+	r.Get("/me/reviews", h.myReviews)
+	r.Delete("/me/reviews/{id}", h.deleteOwn)
+}
+
+func (h *Handler) AdminRoutes(r chi.Router) {
+	r.Get("/admin/reviews", h.queue)
+	r.Post("/admin/reviews/{id}/approve", h.approve)
+	r.Post("/admin/reviews/{id}/reject", h.reject)
+	r.Delete("/admin/reviews/{id}", h.delete)
+}
+
+func (h *Handler) listProductReviews(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, sum, err := h.svc.ProductReviews(r.Context(), chi.URLParam(r, "slug"),
+		parseInt(q.Get("rating"), 0), parseInt(q.Get("limit"), 0), q.Get("cursor"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Summary *Summary `json:"summary"`
+		pagination.Page[Review]
+	}{sum, page})
+}
+
+func (h *Handler) submit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+	var in ReviewInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	rv, err := h.svc.Submit(r.Context(), userID, chi.URLParam(r, "slug"), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, rv)
+}
+
+func (h *Handler) myReviews(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+	items, err := h.svc.MyReviews(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+func (h *Handler) deleteOwn(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+	if err := h.svc.DeleteOwn(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) queue(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := h.svc.Queue(r.Context(), q.Get("status"), parseInt(q.Get("limit"), 0), q.Get("cursor"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) approve(w http.ResponseWriter, r *http.Request) {
+	actor, _ := httpx.UserIDFromContext(r.Context())
+	rv, err := h.svc.Approve(r.Context(), chi.URLParam(r, "id"), actor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rv)
+}
+
+type rejectReq struct {
+	Note string `json:"note"`
+}
+
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request) {
+	actor, _ := httpx.UserIDFromContext(r.Context())
+	var req rejectReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+			return
+		}
+	}
+	rv, err := h.svc.Reject(r.Context(), chi.URLParam(r, "id"), actor, req.Note)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rv)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPayload):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_cursor"})
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+	case errors.Is(err, ErrNotPurchased):
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "not_purchased"})
+	case errors.Is(err, ErrAlreadyReviewed):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "already_reviewed"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+	}
+}
+
+func parseInt(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
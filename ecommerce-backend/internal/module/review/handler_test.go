@@ -0,0 +1,201 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+)
+
+type fakeRepo struct {
+	createFn   func(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error)
+	listFn     func(ctx context.Context, p ListParams) ([]Review, error)
+	summaryFn  func(ctx context.Context, productSlug string) (*Summary, error)
+	byUserFn   func(ctx context.Context, userID string) ([]Review, error)
+	moderateFn func(ctx context.Context, reviewID, status, actor, note string) (*Review, error)
+	deleteFn   func(ctx context.Context, reviewID, userID string) error
+}
+
+func (f fakeRepo) Create(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error) {
+	return f.createFn(ctx, userID, productSlug, in)
+}
+func (f fakeRepo) List(ctx context.Context, p ListParams) ([]Review, error) { return f.listFn(ctx, p) }
+func (f fakeRepo) Summary(ctx context.Context, productSlug string) (*Summary, error) {
+	return f.summaryFn(ctx, productSlug)
+}
+func (f fakeRepo) ListByUser(ctx context.Context, userID string) ([]Review, error) {
+	return f.byUserFn(ctx, userID)
+}
+func (f fakeRepo) Moderate(ctx context.Context, reviewID, status, actor, note string) (*Review, error) {
+	return f.moderateFn(ctx, reviewID, status, actor, note)
+}
+func (f fakeRepo) Delete(ctx context.Context, reviewID, userID string) error {
+	return f.deleteFn(ctx, reviewID, userID)
+}
+
+func router(t *testing.T, repo fakeRepo, role string) (chi.Router, string) {
+	t.Helper()
+	secret := []byte("secret")
+	token, err := httpx.SignJWTWithRole("u-1", role, secret, time.Hour)
+	require.NoError(t, err)
+
+	h := NewHandler(NewService(repo))
+	r := chi.NewRouter()
+	h.Routes(r)
+	r.Group(func(pr chi.Router) {
+		pr.Use(httpx.AuthMiddleware(secret))
+		h.UserRoutes(pr)
+		pr.Group(func(ar chi.Router) {
+			ar.Use(httpx.RequireRole("admin"))
+			h.AdminRoutes(ar)
+		})
+	})
+	return r, token
+}
+
+func do(r chi.Router, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReview_Submit(t *testing.T) {
+	var got ReviewInput
+	repo := fakeRepo{
+		createFn: func(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error) {
+			require.Equal(t, "u-1", userID)
+			require.Equal(t, "basic-tee", productSlug)
+			got = in
+			return &Review{ID: "r1", VariantID: in.VariantID, Rating: in.Rating, Status: StatusPending}, nil
+		},
+	}
+	r, token := router(t, repo, "")
+
+	rec := do(r, token, http.MethodPost, "/products/basic-tee/reviews", `{"variant_id":"v1","rating":5,"title":"  Great  ","body":"Fits well"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "Great", got.Title)
+	require.Contains(t, rec.Body.String(), `"status":"pending"`)
+
+	rec = do(r, "", http.MethodPost, "/products/basic-tee/reviews", `{"variant_id":"v1","rating":5}`)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = do(r, token, http.MethodPost, "/products/basic-tee/reviews", `{"variant_id":"v1","rating":6}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	repo.createFn = func(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error) {
+		return nil, ErrNotPurchased
+	}
+	r, token = router(t, repo, "")
+	rec = do(r, token, http.MethodPost, "/products/basic-tee/reviews", `{"variant_id":"v1","rating":4}`)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "not_purchased")
+
+	repo.createFn = func(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error) {
+		return nil, ErrAlreadyReviewed
+	}
+	r, token = router(t, repo, "")
+	rec = do(r, token, http.MethodPost, "/products/basic-tee/reviews", `{"variant_id":"v1","rating":4}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestReview_ProductReviews_PublicAndPaged(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	note := "u-admin"
+	repo := fakeRepo{
+		listFn: func(ctx context.Context, p ListParams) ([]Review, error) {
+			require.Equal(t, StatusApproved, p.Status)
+			require.Equal(t, 5, p.Rating)
+			require.Equal(t, 2, p.Limit)
+			return []Review{
+				{ID: "r2", UserID: "u-9", AuthorName: "Budi Santoso", Rating: 5, ModeratedBy: &note, CreatedAt: t0.Add(time.Hour)},
+				{ID: "r1", UserID: "u-8", AuthorName: "Sari", Rating: 5, CreatedAt: t0},
+			}, nil
+		},
+		summaryFn: func(ctx context.Context, productSlug string) (*Summary, error) {
+			return &Summary{Average: 4.5, Count: 2, Stars: map[string]int{"4": 1, "5": 1}}, nil
+		},
+	}
+	r, _ := router(t, repo, "")
+
+	rec := do(r, "", http.MethodGet, "/products/basic-tee/reviews?rating=5&limit=1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Summary    Summary           `json:"summary"`
+		Items      []json.RawMessage `json:"items"`
+		HasMore    bool              `json:"has_more"`
+		NextCursor string            `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, 4.5, body.Summary.Average)
+	require.Len(t, body.Items, 1)
+	require.True(t, body.HasMore)
+	require.Contains(t, string(body.Items[0]), `"author_name":"Budi S."`)
+	require.NotContains(t, string(body.Items[0]), "user_id")
+	require.NotContains(t, string(body.Items[0]), "moderated_by")
+
+	rec = do(r, "", http.MethodGet, "/products/basic-tee/reviews?cursor=bogus", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestReview_Admin_Moderation(t *testing.T) {
+	var gotStatus, gotNote, gotActor string
+	repo := fakeRepo{
+		listFn: func(ctx context.Context, p ListParams) ([]Review, error) {
+			require.Equal(t, StatusPending, p.Status)
+			return []Review{{ID: "r1", Status: StatusPending}}, nil
+		},
+		moderateFn: func(ctx context.Context, reviewID, status, actor, note string) (*Review, error) {
+			gotStatus, gotNote, gotActor = status, note, actor
+			return &Review{ID: reviewID, Status: status, ModerationNote: note}, nil
+		},
+	}
+
+	r, token := router(t, repo, "")
+	rec := do(r, token, http.MethodGet, "/admin/reviews?status=pending", "")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	r, token = router(t, repo, "admin")
+	rec = do(r, token, http.MethodGet, "/admin/reviews?status=pending", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(r, token, http.MethodGet, "/admin/reviews?status=spam", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(r, token, http.MethodPost, "/admin/reviews/r1/approve", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, StatusApproved, gotStatus)
+	require.Equal(t, "u-1", gotActor)
+
+	rec = do(r, token, http.MethodPost, "/admin/reviews/r1/reject", `{"note":"off-topic"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, StatusRejected, gotStatus)
+	require.Equal(t, "off-topic", gotNote)
+}
+
+func TestReview_DeleteOwn(t *testing.T) {
+	repo := fakeRepo{
+		deleteFn: func(ctx context.Context, reviewID, userID string) error {
+			require.Equal(t, "u-1", userID)
+			if reviewID != "r1" {
+				return ErrNotFound
+			}
+			return nil
+		},
+	}
+	r, token := router(t, repo, "")
+
+	require.Equal(t, http.StatusNoContent, do(r, token, http.MethodDelete, "/me/reviews/r1", "").Code)
+	require.Equal(t, http.StatusNotFound, do(r, token, http.MethodDelete, "/me/reviews/r2", "").Code)
+}
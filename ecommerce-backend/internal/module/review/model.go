@@ -0,0 +1,59 @@
+package review
+
+import "time"
+
+// Review statuses; only approved reviews are public and counted in ratings.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+type Review struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id"`
+	VariantID   string `json:"variant_id"`
+	VariantName string `json:"variant_name"`
+	UserID      string `json:"user_id,omitempty"` // blanked on public listings
+	AuthorName  string `json:"author_name"`
+
+	Rating int    `json:"rating"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+
+	Status         string     `json:"status"`
+	ModerationNote string     `json:"moderation_note,omitempty"`
+	ModeratedBy    *string    `json:"moderated_by,omitempty"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReviewInput struct {
+	VariantID string `json:"variant_id"`
+	Rating    int    `json:"rating"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+}
+
+// Summary is the approved-review rating breakdown of a product.
+type Summary struct {
+	Average float64        `json:"average"`
+	Count   int            `json:"count"`
+	Stars   map[string]int `json:"stars"` // "1".."5" -> count
+}
+
+// ReviewCursor is the keyset of review listings (newest first).
+type ReviewCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+type ListParams struct {
+	ProductSlug string // public listing
+	Status      string // admin queue; "" = any
+	Rating      int    // exact star filter; 0 = any
+	Limit       int
+	After       *ReviewCursor
+}
@@ -0,0 +1,26 @@
+package review
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrInvalidPayload = errors.New("invalid payload")
+	// ErrNotPurchased: the user has no paid order containing the variant.
+	ErrNotPurchased    = errors.New("variant not purchased")
+	ErrAlreadyReviewed = errors.New("variant already reviewed")
+)
+
+type Repository interface {
+	// Create checks the variant belongs to the product and was bought by the
+	// user on a paid order, then stores the review as pending.
+	Create(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error)
+	List(ctx context.Context, p ListParams) ([]Review, error)
+	Summary(ctx context.Context, productSlug string) (*Summary, error)
+	ListByUser(ctx context.Context, userID string) ([]Review, error)
+	Moderate(ctx context.Context, reviewID, status, actor, note string) (*Review, error)
+	// Delete removes a review; a non-empty userID restricts it to their own.
+	Delete(ctx context.Context, reviewID, userID string) error
+}
@@ -0,0 +1,232 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// reviewCols expects reviews r JOIN product_variants v JOIN users u.
+const reviewCols = `r.id::text, r.product_id::text, r.variant_id::text, v.name, r.user_id::text, u.name,
+  r.rating, r.title, r.body, r.status, r.moderation_note, r.moderated_by, r.moderated_at,
+  r.created_at, r.updated_at`
+
+const reviewFrom = `reviews r
+JOIN product_variants v ON v.id = r.variant_id
+JOIN users u ON u.id = r.user_id`
+
+func scanReview(row pgx.Row) (*Review, error) {
+	var rv Review
+	err := row.Scan(&rv.ID, &rv.ProductID, &rv.VariantID, &rv.VariantName, &rv.UserID, &rv.AuthorName,
+		&rv.Rating, &rv.Title, &rv.Body, &rv.Status, &rv.ModerationNote, &rv.ModeratedBy, &rv.ModeratedAt,
+		&rv.CreatedAt, &rv.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return &rv, nil
+}
+
+func scanReviews(rows pgx.Rows) ([]Review, error) {
+	defer rows.Close()
+	out := []Review{}
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rv)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error) {
+	var productID, orderID string
+	err := r.pool.QueryRow(ctx, `
+SELECT p.id::text
+FROM products p
+JOIN product_variants v ON v.product_id = p.id
+WHERE p.slug = $1 AND p.is_active = true AND v.id = $2;
+`, productSlug, in.VariantID).Scan(&productID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+
+	// the most recent paid order of the variant backs the review
+	err = r.pool.QueryRow(ctx, `
+SELECT o.id::text
+FROM orders o
+JOIN order_items oi ON oi.order_id = o.id
+WHERE o.user_id = $1 AND o.status = 'paid' AND oi.variant_id = $2
+ORDER BY o.created_at DESC
+LIMIT 1;
+`, userID, in.VariantID).Scan(&orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotPurchased
+	}
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+
+	var id string
+	err = r.pool.QueryRow(ctx, `
+INSERT INTO reviews (product_id, variant_id, user_id, order_id, rating, title, body)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id::text;
+`, productID, in.VariantID, userID, orderID, in.Rating, in.Title, in.Body).Scan(&id)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return r.get(ctx, id)
+}
+
+func (r *PostgresRepository) get(ctx context.Context, reviewID string) (*Review, error) {
+	return scanReview(r.pool.QueryRow(ctx, `SELECT `+reviewCols+` FROM `+reviewFrom+` WHERE r.id = $1;`, reviewID))
+}
+
+func (r *PostgresRepository) List(ctx context.Context, p ListParams) ([]Review, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds := []string{"TRUE"}
+	if p.ProductSlug != "" {
+		var productID string
+		err := r.pool.QueryRow(ctx, `SELECT id::text FROM products WHERE slug = $1 AND is_active = true;`, p.ProductSlug).Scan(&productID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, "r.product_id = "+arg(productID))
+	}
+	if p.Status != "" {
+		conds = append(conds, "r.status = "+arg(p.Status))
+	}
+	if p.Rating != 0 {
+		conds = append(conds, "r.rating = "+arg(p.Rating))
+	}
+	if p.After != nil {
+		conds = append(conds, fmt.Sprintf("(r.created_at, r.id) < (%s::timestamptz, %s::uuid)", arg(p.After.CreatedAt), arg(p.After.ID)))
+	}
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+SELECT `+reviewCols+`
+FROM `+reviewFrom+`
+WHERE %s
+ORDER BY r.created_at DESC, r.id DESC
+LIMIT %s;
+`, strings.Join(conds, " AND "), arg(p.Limit)), args...)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return scanReviews(rows)
+}
+
+func (r *PostgresRepository) Summary(ctx context.Context, productSlug string) (*Summary, error) {
+	s := Summary{Stars: map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}}
+	var productID string
+	err := r.pool.QueryRow(ctx, `
+SELECT id::text, rating_avg::float8, rating_count FROM products WHERE slug = $1 AND is_active = true;
+`, productSlug).Scan(&productID, &s.Average, &s.Count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+SELECT rating, COUNT(*)::int FROM reviews
+WHERE product_id = $1 AND status = 'approved'
+GROUP BY rating;
+`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var stars, n int
+		if err := rows.Scan(&stars, &n); err != nil {
+			return nil, err
+		}
+		s.Stars[strconv.Itoa(stars)] = n
+	}
+	return &s, rows.Err()
+}
+
+func (r *PostgresRepository) ListByUser(ctx context.Context, userID string) ([]Review, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT `+reviewCols+`
+FROM `+reviewFrom+`
+WHERE r.user_id = $1
+ORDER BY r.created_at DESC, r.id DESC;
+`, userID)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return scanReviews(rows)
+}
+
+func (r *PostgresRepository) Moderate(ctx context.Context, reviewID, status, actor, note string) (*Review, error) {
+	ct, err := r.pool.Exec(ctx, `
+UPDATE reviews
+SET status = $2, moderation_note = $4, moderated_by = $3, moderated_at = now(), updated_at = now()
+WHERE id = $1;
+`, reviewID, status, actor, note)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+	return r.get(ctx, reviewID)
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, reviewID, userID string) error {
+	ct, err := r.pool.Exec(ctx, `
+DELETE FROM reviews WHERE id = $1 AND ($2 = '' OR user_id::text = $2);
+`, reviewID, userID)
+	if err != nil {
+		return mapPgError(err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.ConstraintName == "uq_reviews_user_variant":
+			return ErrAlreadyReviewed
+		case pgErr.Code == "22P02": // invalid_text_representation (bad uuid)
+			return ErrNotFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package review
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
+)
+
+const (
+	maxTitleLen = 120
+	maxBodyLen  = 5000
+)
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Submit posts a review of a bought variant; it stays pending until an admin
+// approves it.
+func (s *Service) Submit(ctx context.Context, userID, productSlug string, in ReviewInput) (*Review, error) {
+	in.VariantID = strings.TrimSpace(in.VariantID)
+	in.Title = strings.TrimSpace(in.Title)
+	in.Body = strings.TrimSpace(in.Body)
+	if userID == "" || productSlug == "" || in.VariantID == "" || in.Rating < 1 || in.Rating > 5 ||
+		utf8.RuneCountInString(in.Title) > maxTitleLen || utf8.RuneCountInString(in.Body) > maxBodyLen {
+		return nil, ErrInvalidPayload
+	}
+	return s.repo.Create(ctx, userID, productSlug, in)
+}
+
+// ProductReviews lists a product's approved reviews, newest first, with the
+// rating summary. Author identities are reduced to a display name.
+func (s *Service) ProductReviews(ctx context.Context, productSlug string, rating, limit int, cursor string) (pagination.Page[Review], *Summary, error) {
+	if rating < 0 || rating > 5 {
+		return pagination.Page[Review]{}, nil, ErrInvalidPayload
+	}
+	p := ListParams{ProductSlug: productSlug, Status: StatusApproved, Rating: rating}
+	page, err := s.list(ctx, p, limit, cursor)
+	if err != nil {
+		return page, nil, err
+	}
+	for i := range page.Items {
+		rv := &page.Items[i]
+		rv.UserID, rv.ModerationNote, rv.ModeratedBy, rv.ModeratedAt = "", "", nil, nil
+		rv.AuthorName = displayName(rv.AuthorName)
+	}
+	sum, err := s.repo.Summary(ctx, productSlug)
+	if err != nil {
+		return page, nil, err
+	}
+	return page, sum, nil
+}
+
+// Queue is the admin listing, optionally by status.
+func (s *Service) Queue(ctx context.Context, status string, limit int, cursor string) (pagination.Page[Review], error) {
+	switch status {
+	case "", StatusPending, StatusApproved, StatusRejected:
+	default:
+		return pagination.Page[Review]{}, ErrInvalidPayload
+	}
+	return s.list(ctx, ListParams{Status: status}, limit, cursor)
+}
+
+func (s *Service) list(ctx context.Context, p ListParams, limit int, cursor string) (pagination.Page[Review], error) {
+	limit = pagination.Limit(limit, pagination.DefaultLimit)
+	if cursor != "" {
+		p.After = &ReviewCursor{}
+		if err := pagination.Decode(cursor, p.After); err != nil || p.After.ID == "" {
+			return pagination.Page[Review]{}, pagination.ErrInvalidCursor
+		}
+	}
+	p.Limit = limit + 1
+	rows, err := s.repo.List(ctx, p)
+	if err != nil {
+		return pagination.Page[Review]{}, err
+	}
+	return pagination.New(rows, limit, func(last Review) any {
+		return ReviewCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}), nil
+}
+
+func (s *Service) MyReviews(ctx context.Context, userID string) ([]Review, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *Service) DeleteOwn(ctx context.Context, userID, reviewID string) error {
+	if userID == "" {
+		return ErrNotFound
+	}
+	return s.repo.Delete(ctx, reviewID, userID)
+}
+
+func (s *Service) Approve(ctx context.Context, reviewID, actor string) (*Review, error) {
+	return s.repo.Moderate(ctx, reviewID, StatusApproved, actor, "")
+}
+
+func (s *Service) Reject(ctx context.Context, reviewID, actor, note string) (*Review, error) {
+	return s.repo.Moderate(ctx, reviewID, StatusRejected, actor, strings.TrimSpace(note))
+}
+
+func (s *Service) Delete(ctx context.Context, reviewID string) error {
+	return s.repo.Delete(ctx, reviewID, "")
+}
+
+// displayName shortens "Budi Santoso Wijaya" to "Budi W.".
+func displayName(name string) string {
+	parts := strings.Fields(name)
+	switch len(parts) {
+	case 0:
+		return "Customer"
+	case 1:
+		return parts[0]
+	}
+	last, _ := utf8.DecodeRuneInString(parts[len(parts)-1])
+	return parts[0] + " " + strings.ToUpper(string(last)) + "."
+}
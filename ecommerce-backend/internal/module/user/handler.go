@@ -61,6 +61,9 @@ func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
 type loginReq struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	CartID   string `json:"cart_id"` // optional guest cart to merge
+	// the guest cart's token; the X-Cart-Token header works too
+	CartToken string `json:"cart_token"`
 }
 
 func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
@@ -70,7 +73,10 @@ func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.svc.Login(r.Context(), req.Email, req.Password)
+	if req.CartToken == "" {
+		req.CartToken = r.Header.Get(httpx.CartTokenHeader)
+	}
+	res, err := h.svc.Login(r.Context(), req.Email, req.Password, req.CartID, req.CartToken)
 	if err != nil {
 		if errors.Is(err, ErrInvalidPayload) {
 			httpx.Fail(w, http.StatusBadRequest, "invalid_payload", "Invalid payload")
@@ -10,6 +10,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
 )
 
 type fakeRepo struct {
@@ -39,7 +42,7 @@ func TestRegister_201(t *testing.T) {
 		getByID: func(ctx context.Context, userID string) (*User, error) { return &User{ID: "u1"}, nil },
 	}
 
-	svc := NewService(repo, "secret")
+	svc := NewService(repo, nil, "secret")
 	h := NewHandler(svc, "secret")
 
 	r := chi.NewRouter()
@@ -60,7 +63,7 @@ func TestRegister_201(t *testing.T) {
 
 func TestRegister_400(t *testing.T) {
 	repo := fakeRepo{} // not called
-	svc := NewService(repo, "secret")
+	svc := NewService(repo, nil, "secret")
 	h := NewHandler(svc, "secret")
 	r := chi.NewRouter()
 	h.Routes(r)
@@ -79,7 +82,7 @@ func TestRegister_409(t *testing.T) {
 			return nil, ErrEmailTaken
 		},
 	}
-	svc := NewService(repo, "secret")
+	svc := NewService(repo, nil, "secret")
 	h := NewHandler(svc, "secret")
 	r := chi.NewRouter()
 	h.Routes(r)
@@ -100,7 +103,7 @@ func TestLogin_401(t *testing.T) {
 		},
 		getByID: func(ctx context.Context, userID string) (*User, error) { return nil, ErrNotFound },
 	}
-	svc := NewService(repo, "secret")
+	svc := NewService(repo, nil, "secret")
 	h := NewHandler(svc, "secret")
 
 	r := chi.NewRouter()
@@ -113,3 +116,69 @@ func TestLogin_401(t *testing.T) {
 
 	require.Equal(t, http.StatusUnauthorized, rec.Code)
 }
+
+type fakeCarts func(ctx context.Context, guestCartID, userID string) (string, error)
+
+func (f fakeCarts) MergeGuestCart(ctx context.Context, guestCartID, userID string) (string, error) {
+	return f(ctx, guestCartID, userID)
+}
+
+func TestLogin_MergesGuestCart(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo := fakeRepo{
+		getByE: func(ctx context.Context, email string) (*User, string, error) {
+			return &User{ID: "u1", Email: email, Status: "active"}, string(hash), nil
+		},
+	}
+	carts := fakeCarts(func(ctx context.Context, guestCartID, userID string) (string, error) {
+		require.Equal(t, "guest-cart", guestCartID)
+		require.Equal(t, "u1", userID)
+		return "user-cart", nil
+	})
+	r := chi.NewRouter()
+	NewHandler(NewService(repo, carts, "secret"), "secret").Routes(r)
+
+	token := httpx.SignCartToken("guest-cart", []byte("secret"))
+	reqBody := []byte(`{"email":"a@b.com","password":"pass123","cart_id":"guest-cart","cart_token":"` + token + `"}`)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(reqBody)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out AuthResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, "user-cart", out.CartID)
+	require.NotEmpty(t, out.Token)
+}
+
+func TestLogin_IgnoresGuestCartWithoutToken(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo := fakeRepo{
+		getByE: func(ctx context.Context, email string) (*User, string, error) {
+			return &User{ID: "u1", Email: email, Status: "active"}, string(hash), nil
+		},
+	}
+	carts := fakeCarts(func(ctx context.Context, guestCartID, userID string) (string, error) {
+		t.Fatalf("merged cart %s without its token", guestCartID)
+		return "", nil
+	})
+	r := chi.NewRouter()
+	NewHandler(NewService(repo, carts, "secret"), "secret").Routes(r)
+
+	for name, body := range map[string]string{
+		"no token":              `{"email":"a@b.com","password":"pass123","cart_id":"someone-elses-cart"}`,
+		"token of another cart": `{"email":"a@b.com","password":"pass123","cart_id":"someone-elses-cart","cart_token":"` + httpx.SignCartToken("my-cart", []byte("secret")) + `"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBufferString(body)))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var out AuthResult
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+			require.Empty(t, out.CartID)
+			require.NotEmpty(t, out.Token)
+		})
+	}
+}
@@ -5,9 +5,12 @@ type User struct {
 	Email  string `json:"email"`
 	Name   string `json:"name"`
 	Status string `json:"status"`
+	Role   string `json:"role"`
 }
 
 type AuthResult struct {
 	User  User   `json:"user"`
 	Token string `json:"token"`
+	// the user's active cart after a login that sent a guest cart
+	CartID string `json:"cart_id,omitempty"`
 }
@@ -21,8 +21,8 @@ func (r *PostgresRepository) CreateUser(ctx context.Context, email, passwordHash
 	err := r.pool.QueryRow(ctx, `
 INSERT INTO users (email, password_hash, name, status)
 VALUES ($1, $2, $3, 'active')
-RETURNING id::text, email, name, status;
-`, email, passwordHash, name).Scan(&u.ID, &u.Email, &u.Name, &u.Status)
+RETURNING id::text, email, name, status, role;
+`, email, passwordHash, name).Scan(&u.ID, &u.Email, &u.Name, &u.Status, &u.Role)
 
 	if err != nil {
 		return nil, ErrEmailTaken
@@ -34,11 +34,11 @@ func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (
 	var u User
 	var ph string
 	err := r.pool.QueryRow(ctx, `
-SELECT id::text, email, name, status, password_hash
+SELECT id::text, email, name, status, role, password_hash
 FROM users
 WHERE email=$1
 LIMIT 1;
-`, email).Scan(&u.ID, &u.Email, &u.Name, &u.Status, &ph)
+`, email).Scan(&u.ID, &u.Email, &u.Name, &u.Status, &u.Role, &ph)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -52,11 +52,11 @@ LIMIT 1;
 func (r *PostgresRepository) GetUserByID(ctx context.Context, userID string) (*User, error) {
 	var u User
 	err := r.pool.QueryRow(ctx, `
-SELECT id::text, email, name, status
+SELECT id::text, email, name, status, role
 FROM users
 WHERE id=$1
 LIMIT 1;
-`, userID).Scan(&u.ID, &u.Email, &u.Name, &u.Status)
+`, userID).Scan(&u.ID, &u.Email, &u.Name, &u.Status, &u.Role)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"errors"
+	"log"
 	"strings"
 	"time"
 
@@ -14,15 +15,23 @@ import (
 var ErrInvalidCredentials = errors.New("invalid credentials")
 var ErrInvalidPayload = errors.New("invalid payload")
 
+// CartMerger moves a guest cart into the user's cart at login and returns
+// the user's active cart ("" when they have none). The cart module provides it.
+type CartMerger interface {
+	MergeGuestCart(ctx context.Context, guestCartID, userID string) (string, error)
+}
+
 type Service struct {
 	repo      Repository
+	carts     CartMerger // optional
 	jwtSecret []byte
 	jwtTTL    time.Duration
 }
 
-func NewService(repo Repository, jwtSecret string) *Service {
+func NewService(repo Repository, carts CartMerger, jwtSecret string) *Service {
 	return &Service{
 		repo:      repo,
+		carts:     carts,
 		jwtSecret: []byte(jwtSecret),
 		jwtTTL:    24 * time.Hour,
 	}
@@ -45,7 +54,7 @@ func (s *Service) Register(ctx context.Context, email, password, name string) (*
 		return nil, err
 	}
 
-	tok, err := httpx.SignJWT(u.ID, s.jwtSecret, s.jwtTTL)
+	tok, err := httpx.SignJWTWithRole(u.ID, u.Role, s.jwtSecret, s.jwtTTL)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +62,12 @@ func (s *Service) Register(ctx context.Context, email, password, name string) (*
 	return &AuthResult{User: *u, Token: tok}, nil
 }
 
-func (s *Service) Login(ctx context.Context, email, password string) (*AuthResult, error) {
+// Login checks the credentials. guestCartID ("" = none) is the cart the
+// shopper filled before signing in; it is merged into their cart, but only
+// with the cart token POST /cart returned for it, so knowing a cart's id is
+// not enough to take it over. A failed merge doesn't fail the login, the
+// items just stay in the guest cart.
+func (s *Service) Login(ctx context.Context, email, password, guestCartID, guestCartToken string) (*AuthResult, error) {
 	email = strings.TrimSpace(strings.ToLower(email))
 	if email == "" || password == "" {
 		return nil, ErrInvalidPayload
@@ -68,12 +82,20 @@ func (s *Service) Login(ctx context.Context, email, password string) (*AuthResul
 		return nil, ErrInvalidCredentials
 	}
 
-	tok, err := httpx.SignJWT(u.ID, s.jwtSecret, s.jwtTTL)
+	tok, err := httpx.SignJWTWithRole(u.ID, u.Role, s.jwtSecret, s.jwtTTL)
 	if err != nil {
 		return nil, err
 	}
 
-	return &AuthResult{User: *u, Token: tok}, nil
+	res := &AuthResult{User: *u, Token: tok}
+	if s.carts != nil && guestCartID != "" && httpx.VerifyCartToken(guestCartToken, guestCartID, s.jwtSecret) {
+		cartID, err := s.carts.MergeGuestCart(ctx, guestCartID, u.ID)
+		if err != nil {
+			log.Printf("login %s: merge cart %s: %v", u.ID, guestCartID, err)
+		}
+		res.CartID = cartID
+	}
+	return res, nil
 }
 
 func (s *Service) Me(ctx context.Context, userID string) (*User, error) {
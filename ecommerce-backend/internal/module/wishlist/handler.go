@@ -0,0 +1,143 @@
+package wishlist
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+	"github.com/synchhans/ecommerce-backend/internal/platform/pagination"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler { return &Handler{svc: svc} }
+
+// Routes must be mounted behind AuthMiddleware.
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/wishlist", h.list)
+	r.Post("/wishlist", h.add)
+	r.Delete("/wishlist/{variantId}", h.remove)
+	r.Post("/wishlist/{variantId}/move-to-cart", h.moveToCart)
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+	items, err := h.svc.List(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, pagination.All(items))
+}
+
+type addReq struct {
+	VariantID string `json:"variant_id"`
+	Currency  string `json:"currency"`
+}
+
+func (h *Handler) add(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+
+	var req addReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	if req.VariantID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_payload"})
+		return
+	}
+
+	if err := h.svc.Add(r.Context(), userID, req.VariantID, req.Currency); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "variant_not_found"})
+		case errors.Is(err, ErrInvalidCurrency):
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_currency"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"ok": true})
+}
+
+func (h *Handler) remove(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.svc.Remove(r.Context(), userID, chi.URLParam(r, "variantId")); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+type moveReq struct {
+	Qty int `json:"qty"`
+}
+
+// moveToCart takes an optional {"qty": n}, default 1.
+func (h *Handler) moveToCart(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return
+	}
+
+	req := moveReq{Qty: 1}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+
+	cartID, err := h.svc.MoveToCart(r.Context(), userID, chi.URLParam(r, "variantId"), req.Qty)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+		case errors.Is(err, ErrInvalidQty):
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_qty"})
+		case errors.Is(err, ErrUnavailable):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "unavailable"})
+		case errors.Is(err, ErrInsufficientStock):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "insufficient_stock"})
+		case errors.Is(err, ErrPriceUnavailable):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "price_unavailable"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "internal_error"})
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"cart_id": cartID})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
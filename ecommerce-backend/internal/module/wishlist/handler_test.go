@@ -0,0 +1,175 @@
+package wishlist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	httpx "github.com/synchhans/ecommerce-backend/internal/platform/http"
+)
+
+type fakeRepo struct {
+	addFn      func(ctx context.Context, userID, variantID, currency string) error
+	removeFn   func(ctx context.Context, userID, variantID string) error
+	containsFn func(ctx context.Context, userID, variantID string) (bool, error)
+	listFn     func(ctx context.Context, userID string) ([]Item, error)
+}
+
+func (f fakeRepo) Add(ctx context.Context, userID, variantID, currency string) error {
+	return f.addFn(ctx, userID, variantID, currency)
+}
+func (f fakeRepo) Remove(ctx context.Context, userID, variantID string) error {
+	return f.removeFn(ctx, userID, variantID)
+}
+func (f fakeRepo) Contains(ctx context.Context, userID, variantID string) (bool, error) {
+	return f.containsFn(ctx, userID, variantID)
+}
+func (f fakeRepo) List(ctx context.Context, userID string) ([]Item, error) {
+	return f.listFn(ctx, userID)
+}
+
+type fakeCarts func(ctx context.Context, userID, variantID string, qty int) (string, error)
+
+func (f fakeCarts) AddToUserCart(ctx context.Context, userID, variantID string, qty int) (string, error) {
+	return f(ctx, userID, variantID, qty)
+}
+
+var secret = []byte("secret")
+
+func newRouter(t *testing.T, repo fakeRepo, carts CartAdder) chi.Router {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Group(func(pr chi.Router) {
+		pr.Use(httpx.AuthMiddleware(secret))
+		NewHandler(NewService(repo, carts)).Routes(pr)
+	})
+	return r
+}
+
+func authed(t *testing.T, req *http.Request) *http.Request {
+	t.Helper()
+	token, err := httpx.SignJWT("u1", secret, time.Hour)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func ptr(v int64) *int64 { return &v }
+
+func TestWishlist_Unauthorized(t *testing.T) {
+	r := newRouter(t, fakeRepo{}, nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/wishlist", nil))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestWishlist_List_Flags(t *testing.T) {
+	repo := fakeRepo{
+		listFn: func(ctx context.Context, userID string) ([]Item, error) {
+			require.Equal(t, "u1", userID)
+			return []Item{
+				{VariantID: "back", Active: true, InStock: true, InStockAtSave: false, UnitPrice: ptr(100), PriceAtSave: ptr(100)},
+				{VariantID: "cheaper", Active: true, InStock: true, InStockAtSave: true, UnitPrice: ptr(80), PriceAtSave: ptr(100)},
+				{VariantID: "dearer", Active: true, InStock: false, InStockAtSave: true, UnitPrice: ptr(120), PriceAtSave: ptr(100)},
+				{VariantID: "gone", Active: false, InStock: true, InStockAtSave: false, UnitPrice: ptr(50), PriceAtSave: ptr(100)},
+				{VariantID: "unpriced", Active: true, InStock: true, InStockAtSave: true, PriceAtSave: ptr(100)},
+			}, nil
+		},
+	}
+	r := newRouter(t, repo, nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, authed(t, httptest.NewRequest(http.MethodGet, "/wishlist", nil)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Items []Item `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	flags := map[string][2]bool{}
+	for _, it := range body.Items {
+		flags[it.VariantID] = [2]bool{it.BackInStock, it.PriceDrop}
+	}
+	require.Equal(t, map[string][2]bool{
+		"back":     {true, false},
+		"cheaper":  {false, true},
+		"dearer":   {false, false},
+		"gone":     {false, false},
+		"unpriced": {false, false},
+	}, flags)
+}
+
+func TestWishlist_Add(t *testing.T) {
+	repo := fakeRepo{
+		addFn: func(ctx context.Context, userID, variantID, currency string) error {
+			if variantID == "missing" {
+				return ErrNotFound
+			}
+			require.Equal(t, "USD", currency)
+			return nil
+		},
+	}
+	r := newRouter(t, repo, nil)
+
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`{"variant_id":"v1","currency":"usd"}`, http.StatusCreated},
+		{`{"variant_id":"missing","currency":"USD"}`, http.StatusNotFound},
+		{`{"variant_id":"v1","currency":"dollars"}`, http.StatusBadRequest},
+		{`{}`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, authed(t, httptest.NewRequest(http.MethodPost, "/wishlist", bytes.NewBufferString(tc.body))))
+		require.Equal(t, tc.want, rec.Code, tc.body)
+	}
+}
+
+func TestWishlist_MoveToCart(t *testing.T) {
+	var removed []string
+	repo := fakeRepo{
+		containsFn: func(ctx context.Context, userID, variantID string) (bool, error) {
+			return variantID != "not-saved", nil
+		},
+		removeFn: func(ctx context.Context, userID, variantID string) error {
+			removed = append(removed, variantID)
+			return nil
+		},
+	}
+	carts := fakeCarts(func(ctx context.Context, userID, variantID string, qty int) (string, error) {
+		require.Equal(t, "u1", userID)
+		if variantID == "sold-out" {
+			return "", ErrInsufficientStock
+		}
+		require.Equal(t, 2, qty)
+		return "cart-1", nil
+	})
+	r := newRouter(t, repo, carts)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, authed(t, httptest.NewRequest(http.MethodPost, "/wishlist/v1/move-to-cart", bytes.NewBufferString(`{"qty":2}`))))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"cart_id":"cart-1"}`, rec.Body.String())
+	require.Equal(t, []string{"v1"}, removed)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, authed(t, httptest.NewRequest(http.MethodPost, "/wishlist/sold-out/move-to-cart", nil)))
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "insufficient_stock")
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, authed(t, httptest.NewRequest(http.MethodPost, "/wishlist/not-saved/move-to-cart", nil)))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.Equal(t, []string{"v1"}, removed, "failed moves stay saved")
+}
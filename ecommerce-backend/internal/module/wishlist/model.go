@@ -0,0 +1,31 @@
+package wishlist
+
+import "time"
+
+// Item is a saved variant with its current price and stock.
+type Item struct {
+	ID          string `json:"id"`
+	VariantID   string `json:"variant_id"`
+	ProductName string `json:"product_name"`
+	ProductSlug string `json:"product_slug"`
+	VariantName string `json:"variant_name"`
+	SKU         string `json:"sku"`
+	ImageURL    string `json:"image_url"`
+
+	// prices are in Currency, the one the item was saved in; nil when the
+	// variant isn't sold in it
+	Currency    string `json:"currency"`
+	UnitPrice   *int64 `json:"unit_price"`
+	PriceAtSave *int64 `json:"price_at_save"`
+
+	Active  bool `json:"active"` // false once the variant or product is no longer sold
+	InStock bool `json:"in_stock"`
+
+	// set by the service against what the shopper saw when saving
+	BackInStock bool `json:"back_in_stock"`
+	PriceDrop   bool `json:"price_drop"`
+
+	SavedAt time.Time `json:"saved_at"`
+
+	InStockAtSave bool `json:"-"`
+}
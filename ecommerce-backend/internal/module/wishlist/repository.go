@@ -0,0 +1,15 @@
+package wishlist
+
+import "context"
+
+type Repository interface {
+	// Add saves an active variant, snapshotting its price in currency ("" =
+	// the base currency) and whether it is in stock. Saving it again is a
+	// no-op that keeps the first snapshot.
+	Add(ctx context.Context, userID, variantID, currency string) error
+	// Remove returns ErrNotFound when the variant isn't saved.
+	Remove(ctx context.Context, userID, variantID string) error
+	Contains(ctx context.Context, userID, variantID string) (bool, error)
+	// List returns saved items, newest first.
+	List(ctx context.Context, userID string) ([]Item, error)
+}
@@ -0,0 +1,131 @@
+package wishlist
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNotFound = errors.New("not found")
+var ErrInvalidCurrency = errors.New("unknown or inactive currency")
+var ErrInvalidQty = errors.New("invalid qty")
+
+// Rejections from the cart on "move to cart".
+var ErrUnavailable = errors.New("variant no longer sold")
+var ErrInsufficientStock = errors.New("insufficient stock")
+var ErrPriceUnavailable = errors.New("variant not sold in the cart currency")
+
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// inStock reports whether at least one unit of variant v can be sold: there
+// is unreserved stock, or its policy still takes backorders/preorders.
+const inStock = `((SELECT available FROM variant_available(v.id)) > 0
+       OR (v.inventory_policy IN ('backorder', 'preorder')
+           AND (v.backorder_limit IS NULL OR v.backorder_limit > (SELECT qty FROM variant_backordered(v.id)))))`
+
+func (r *PostgresRepository) Add(ctx context.Context, userID, variantID, currency string) error {
+	var active bool
+	err := r.pool.QueryRow(ctx, `
+SELECT v.is_active AND p.is_active
+FROM product_variants v
+JOIN products p ON p.id = v.product_id
+WHERE v.id = $1;
+`, variantID).Scan(&active)
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == "22P02") || (err == nil && !active) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = r.pool.QueryRow(ctx, `
+SELECT code FROM currencies WHERE is_active AND (code = $1 OR ($1 = '' AND is_base));
+`, currency).Scan(&currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidCurrency
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pool.Exec(ctx, `
+INSERT INTO wishlist_items (user_id, variant_id, currency, price_at_save, in_stock_at_save)
+SELECT $1, v.id, $3, vp.price, `+inStock+`
+FROM product_variants v
+LEFT JOIN LATERAL variant_price_in(v.id, $3) vp ON true
+WHERE v.id = $2
+ON CONFLICT (user_id, variant_id) DO NOTHING;
+`, userID, variantID, currency)
+	return err
+}
+
+func (r *PostgresRepository) Remove(ctx context.Context, userID, variantID string) error {
+	ct, err := r.pool.Exec(ctx, `
+DELETE FROM wishlist_items WHERE user_id = $1 AND variant_id = $2;
+`, userID, variantID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22P02" {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) Contains(ctx context.Context, userID, variantID string) (bool, error) {
+	var ok bool
+	err := r.pool.QueryRow(ctx, `
+SELECT EXISTS (SELECT 1 FROM wishlist_items WHERE user_id = $1 AND variant_id = $2);
+`, userID, variantID).Scan(&ok)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22P02" {
+		return false, nil
+	}
+	return ok, err
+}
+
+func (r *PostgresRepository) List(ctx context.Context, userID string) ([]Item, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT w.id::text, w.variant_id::text, p.name, p.slug, v.name, v.sku,
+       COALESCE((SELECT pi.url FROM product_images pi WHERE pi.product_id = p.id ORDER BY pi.position LIMIT 1), ''),
+       w.currency, vp.price, w.price_at_save,
+       v.is_active AND p.is_active, `+inStock+`,
+       w.in_stock_at_save, w.created_at
+FROM wishlist_items w
+JOIN product_variants v ON v.id = w.variant_id
+JOIN products p ON p.id = v.product_id
+LEFT JOIN LATERAL variant_price_in(v.id, w.currency) vp ON true
+WHERE w.user_id = $1
+ORDER BY w.created_at DESC, w.id;
+`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Item
+	for rows.Next() {
+		var it Item
+		if err := rows.Scan(&it.ID, &it.VariantID, &it.ProductName, &it.ProductSlug, &it.VariantName, &it.SKU,
+			&it.ImageURL, &it.Currency, &it.UnitPrice, &it.PriceAtSave,
+			&it.Active, &it.InStock, &it.InStockAtSave, &it.SavedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,79 @@
+package wishlist
+
+import (
+	"context"
+	"log"
+
+	"github.com/synchhans/ecommerce-backend/internal/platform/money"
+)
+
+// CartAdder adds qty of a variant on top of what the user's active cart
+// already holds, opening the cart if needed, and returns the cart. The cart
+// module provides it; its rejections come back as ErrUnavailable,
+// ErrInsufficientStock or ErrPriceUnavailable.
+type CartAdder interface {
+	AddToUserCart(ctx context.Context, userID, variantID string, qty int) (string, error)
+}
+
+type Service struct {
+	repo  Repository
+	carts CartAdder
+}
+
+func NewService(repo Repository, carts CartAdder) *Service {
+	return &Service{repo: repo, carts: carts}
+}
+
+// Add saves a variant for the user; currency "" uses the base currency.
+func (s *Service) Add(ctx context.Context, userID, variantID, currency string) error {
+	currency = money.Normalize(currency)
+	if currency != "" && !money.ValidCode(currency) {
+		return ErrInvalidCurrency
+	}
+	return s.repo.Add(ctx, userID, variantID, currency)
+}
+
+func (s *Service) Remove(ctx context.Context, userID, variantID string) error {
+	return s.repo.Remove(ctx, userID, variantID)
+}
+
+// List returns saved items with back_in_stock and price_drop set against
+// what the user saw when saving.
+func (s *Service) List(ctx context.Context, userID string) ([]Item, error) {
+	items, err := s.repo.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		it := &items[i]
+		it.BackInStock = it.Active && it.InStock && !it.InStockAtSave
+		it.PriceDrop = it.Active && it.UnitPrice != nil && it.PriceAtSave != nil && *it.UnitPrice < *it.PriceAtSave
+	}
+	return items, nil
+}
+
+// MoveToCart adds a saved variant to the user's cart and takes it off the
+// wishlist. It returns the cart.
+func (s *Service) MoveToCart(ctx context.Context, userID, variantID string, qty int) (string, error) {
+	if qty <= 0 {
+		return "", ErrInvalidQty
+	}
+	ok, err := s.repo.Contains(ctx, userID, variantID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	cartID, err := s.carts.AddToUserCart(ctx, userID, variantID, qty)
+	if err != nil {
+		return "", err
+	}
+	// the item is in the cart now; failing here would invite a retry that
+	// adds it twice
+	if err := s.repo.Remove(ctx, userID, variantID); err != nil {
+		log.Printf("wishlist remove after move user=%s variant=%s: %v", userID, variantID, err)
+	}
+	return cartID, nil
+}
@@ -12,21 +12,39 @@ import (
 
 type ctxKey string
 
-const userIDKey ctxKey = "user_id"
+const (
+	userIDKey ctxKey = "user_id"
+	roleKey   ctxKey = "role"
+)
 
 var ErrUnauthorized = errors.New("unauthorized")
 
 func SignJWT(userID string, secret []byte, ttl time.Duration) (string, error) {
+	return SignJWTWithRole(userID, "", secret, ttl)
+}
+
+// SignJWTWithRole adds a "role" claim. The role is baked into the token, so a
+// role change only takes effect once the user logs in again.
+func SignJWTWithRole(userID, role string, secret []byte, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": userID,
 		"exp": time.Now().Add(ttl).Unix(),
 		"iat": time.Now().Unix(),
 	}
+	if role != "" {
+		claims["role"] = role
+	}
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return t.SignedString(secret)
 }
 
 func ParseJWT(tokenStr string, secret []byte) (string, error) {
+	sub, _, err := ParseJWTClaims(tokenStr, secret)
+	return sub, err
+}
+
+// ParseJWTClaims returns the subject and (possibly empty) role of a token.
+func ParseJWTClaims(tokenStr string, secret []byte) (string, string, error) {
 	tok, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
 		if t.Method != jwt.SigningMethodHS256 {
 			return nil, ErrUnauthorized
@@ -34,17 +52,18 @@ func ParseJWT(tokenStr string, secret []byte) (string, error) {
 		return secret, nil
 	})
 	if err != nil || !tok.Valid {
-		return "", ErrUnauthorized
+		return "", "", ErrUnauthorized
 	}
 	claims, ok := tok.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", ErrUnauthorized
+		return "", "", ErrUnauthorized
 	}
 	sub, _ := claims["sub"].(string)
 	if sub == "" {
-		return "", ErrUnauthorized
+		return "", "", ErrUnauthorized
 	}
-	return sub, nil
+	role, _ := claims["role"].(string)
+	return sub, role, nil
 }
 
 func AuthMiddleware(secret []byte) func(http.Handler) http.Handler {
@@ -56,19 +75,54 @@ func AuthMiddleware(secret []byte) func(http.Handler) http.Handler {
 				return
 			}
 			tokenStr := strings.TrimPrefix(h, "Bearer ")
-			userID, err := ParseJWT(tokenStr, secret)
+			userID, role, err := ParseJWTClaims(tokenStr, secret)
 			if err != nil {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
 			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			ctx = context.WithValue(ctx, roleKey, role)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// OptionalAuth identifies the caller when a bearer token is sent and lets
+// anonymous requests through. A token that doesn't verify is still a 401.
+func OptionalAuth(secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		auth := AuthMiddleware(secret)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			auth.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole must run after AuthMiddleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got, _ := RoleFromContext(r.Context()); got != role {
+				Fail(w, http.StatusForbidden, "forbidden")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func UserIDFromContext(ctx context.Context) (string, bool) {
 	v := ctx.Value(userIDKey)
 	s, ok := v.(string)
 	return s, ok
 }
+
+func RoleFromContext(ctx context.Context) (string, bool) {
+	v := ctx.Value(roleKey)
+	s, ok := v.(string)
+	return s, ok
+}
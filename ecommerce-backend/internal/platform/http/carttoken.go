@@ -0,0 +1,32 @@
+package httpx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// CartTokenHeader carries the token that grants access to a guest cart.
+const CartTokenHeader = "X-Cart-Token"
+
+// SignCartToken returns the access token of a guest cart: an HMAC of the cart
+// id, so it can be checked without a lookup. It has no expiry; a cart stops
+// being usable once it is checked out or merged.
+func SignCartToken(cartID string, secret []byte) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte("cart:" + cartID)) // keeps it apart from other uses of the secret
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// VerifyCartToken reports whether token was issued for cartID.
+func VerifyCartToken(token, cartID string, secret []byte) bool {
+	if token == "" || cartID == "" {
+		return false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return false
+	}
+	want, _ := base64.RawURLEncoding.DecodeString(SignCartToken(cartID, secret))
+	return hmac.Equal(got, want)
+}
@@ -0,0 +1,17 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Includes reports whether the comma separated ?include= query param names the
+// given expansion, e.g. "?include=availability,images".
+func Includes(r *http.Request, name string) bool {
+	for _, part := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(part) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+// Package money holds currency-code helpers shared by the modules that store
+// amounts. Amounts are always int64 minor units of their currency.
+package money
+
+import "strings"
+
+// exponents are ISO 4217 minor-unit digits for the currencies we expect to
+// sell in. IDR is the exception: the store has always kept whole rupiah.
+var exponents = map[string]int{
+	"IDR": 0,
+	"AUD": 2, "CNY": 2, "EUR": 2, "GBP": 2, "HKD": 2, "MYR": 2,
+	"PHP": 2, "SGD": 2, "THB": 2, "TWD": 2, "USD": 2,
+	"JPY": 0, "KRW": 0, "VND": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3,
+}
+
+// Normalize upper-cases and trims a currency code.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidCode reports whether code looks like an ISO 4217 alpha code.
+func ValidCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Exponent returns the known minor-unit exponent for code.
+func Exponent(code string) (int, bool) {
+	e, ok := exponents[code]
+	return e, ok
+}
@@ -0,0 +1,81 @@
+// Package pagination holds the keyset cursor helpers and the response envelope
+// shared by list endpoints.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page is the list envelope: {items, has_more, next_cursor, total}.
+// Total is only set when the caller asked for it (?include=total).
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// Limit clamps a requested page size; 0/negative means def.
+func Limit(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	if n > MaxLimit {
+		return MaxLimit
+	}
+	return n
+}
+
+// Encode turns a cursor value (a struct of the last row's sort keys) into an
+// opaque URL-safe token.
+func Encode(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode parses a token produced by Encode into v.
+func Decode(token string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// New builds a page from rows fetched with limit+1: the extra row only signals
+// that another page exists. cursor maps the last returned row to its keys.
+func New[T any](rows []T, limit int, cursor func(last T) any) Page[T] {
+	p := Page[T]{Items: rows}
+	if p.Items == nil {
+		p.Items = []T{}
+	}
+	if len(rows) > limit {
+		p.Items = rows[:limit]
+		p.HasMore = true
+		p.NextCursor = Encode(cursor(p.Items[limit-1]))
+	}
+	return p
+}
+
+// All wraps a complete, unpaginated result in the same envelope.
+func All[T any](items []T) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{Items: items}
+}
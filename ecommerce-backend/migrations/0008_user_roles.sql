@@ -0,0 +1,4 @@
+-- ===== User Roles =====
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'customer'; -- customer/admin
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
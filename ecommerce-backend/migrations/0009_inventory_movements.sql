@@ -0,0 +1,35 @@
+-- ===== Inventory Movements (append-only ledger) =====
+-- inventory_items stays the derived balance; every change to it is recorded here.
+CREATE TABLE IF NOT EXISTS inventory_movements (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE RESTRICT,
+
+  kind text NOT NULL CHECK (kind IN ('receipt', 'adjustment', 'reservation', 'release', 'sale', 'return')),
+  on_hand_delta int NOT NULL DEFAULT 0,
+  reserved_delta int NOT NULL DEFAULT 0,
+
+  -- balance right after this movement was applied
+  on_hand_after int NOT NULL,
+  reserved_after int NOT NULL,
+
+  reason_code text NOT NULL DEFAULT '',
+  actor text NOT NULL DEFAULT 'system', -- user id or "system"
+  order_id uuid NULL REFERENCES orders(id),
+  note text NOT NULL DEFAULT '',
+
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_variant ON inventory_movements(variant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_order ON inventory_movements(order_id) WHERE order_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION inventory_movements_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'inventory_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_inventory_movements_append_only ON inventory_movements;
+CREATE TRIGGER trg_inventory_movements_append_only
+BEFORE UPDATE OR DELETE ON inventory_movements
+FOR EACH ROW EXECUTE FUNCTION inventory_movements_append_only();
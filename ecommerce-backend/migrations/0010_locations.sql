@@ -0,0 +1,53 @@
+-- ===== Locations (multi-warehouse) =====
+CREATE TABLE IF NOT EXISTS locations (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  code text UNIQUE NOT NULL,
+  name text NOT NULL,
+
+  city text NOT NULL DEFAULT '',
+  province text NOT NULL DEFAULT '',
+  country text NOT NULL DEFAULT 'ID',
+
+  is_active boolean NOT NULL DEFAULT true,
+  is_default boolean NOT NULL DEFAULT false, -- used when a movement doesn't name a location
+
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Only one default location
+CREATE UNIQUE INDEX IF NOT EXISTS ux_locations_default
+ON locations(is_default)
+WHERE is_default = true;
+
+-- Existing single-warehouse stock moves to the default location
+INSERT INTO locations (code, name, is_default)
+VALUES ('MAIN', 'Main warehouse', true)
+ON CONFLICT (code) DO NOTHING;
+
+-- ===== Per-location stock =====
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS location_id uuid REFERENCES locations(id) ON DELETE RESTRICT;
+UPDATE inventory_items SET location_id = (SELECT id FROM locations WHERE code = 'MAIN') WHERE location_id IS NULL;
+ALTER TABLE inventory_items ALTER COLUMN location_id SET NOT NULL;
+
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_pkey;
+ALTER TABLE inventory_items ADD PRIMARY KEY (variant_id, location_id);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_items_location ON inventory_items(location_id);
+
+-- ===== Ledger entries carry their location =====
+ALTER TABLE inventory_movements ADD COLUMN IF NOT EXISTS location_id uuid REFERENCES locations(id);
+
+ALTER TABLE inventory_movements DISABLE TRIGGER trg_inventory_movements_append_only;
+UPDATE inventory_movements SET location_id = (SELECT id FROM locations WHERE code = 'MAIN') WHERE location_id IS NULL;
+ALTER TABLE inventory_movements ENABLE TRIGGER trg_inventory_movements_append_only;
+
+ALTER TABLE inventory_movements ALTER COLUMN location_id SET NOT NULL;
+
+-- ===== Orders record where they ship from =====
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fulfillment_location_id uuid NULL REFERENCES locations(id);
+
+-- Existing orders all ship from the single warehouse; pending_payment ones
+-- hold their reservations there and are released against this location.
+UPDATE orders SET fulfillment_location_id = (SELECT id FROM locations WHERE code = 'MAIN')
+WHERE fulfillment_location_id IS NULL;
@@ -0,0 +1,27 @@
+-- ===== Reorder thresholds & low-stock alerts =====
+CREATE TABLE IF NOT EXISTS inventory_thresholds (
+  variant_id uuid PRIMARY KEY REFERENCES product_variants(id) ON DELETE CASCADE,
+  reorder_point int NOT NULL DEFAULT 0 CHECK (reorder_point >= 0),
+  safety_stock int NOT NULL DEFAULT 0 CHECK (safety_stock >= 0),
+
+  -- last level seen by the low-stock checker: ok/low/out
+  stock_level text NOT NULL DEFAULT 'ok',
+  level_changed_at timestamptz NULL,
+
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_thresholds_level ON inventory_thresholds(stock_level);
+
+-- Emitted events (one row per level change into low/out)
+CREATE TABLE IF NOT EXISTS inventory_alerts (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  kind text NOT NULL, -- low_stock/out_of_stock
+  available int NOT NULL,
+  reorder_point int NOT NULL,
+  safety_stock int NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_alerts_variant ON inventory_alerts(variant_id, created_at DESC);
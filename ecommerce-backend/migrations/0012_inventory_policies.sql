@@ -0,0 +1,23 @@
+-- ===== Backorder / pre-order policies =====
+-- deny:      never sell beyond available stock
+-- backorder: sell beyond stock, up to backorder_limit outstanding units (NULL = unlimited)
+-- preorder:  like backorder, lines ship around preorder_ships_at
+ALTER TABLE product_variants
+  ADD COLUMN IF NOT EXISTS inventory_policy text NOT NULL DEFAULT 'deny'
+    CHECK (inventory_policy IN ('deny', 'backorder', 'preorder')),
+  ADD COLUMN IF NOT EXISTS backorder_limit int NULL CHECK (backorder_limit IS NULL OR backorder_limit >= 0),
+  ADD COLUMN IF NOT EXISTS preorder_ships_at timestamptz NULL;
+
+-- Order lines remember how much of them could not be reserved at checkout,
+-- so fulfillment can split shipments later.
+ALTER TABLE order_items
+  ADD COLUMN IF NOT EXISTS backordered_qty int NOT NULL DEFAULT 0 CHECK (backordered_qty >= 0),
+  ADD COLUMN IF NOT EXISTS fulfillment_type text NOT NULL DEFAULT 'stock', -- stock/backorder/preorder
+  ADD COLUMN IF NOT EXISTS expected_ship_at timestamptz NULL;
+
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS chk_order_items_backordered_qty;
+ALTER TABLE order_items ADD CONSTRAINT chk_order_items_backordered_qty CHECK (backordered_qty <= qty);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_backordered
+ON order_items(variant_id)
+WHERE backordered_qty > 0;
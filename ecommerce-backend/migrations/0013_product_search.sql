@@ -0,0 +1,61 @@
+-- ===== Product full-text search =====
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS search_vector tsvector NOT NULL DEFAULT ''::tsvector;
+
+-- Weighted document: A = product name, B = variant names + SKUs, C = description.
+-- 'simple' config: catalog text is mixed Indonesian/English and full of SKUs.
+CREATE OR REPLACE FUNCTION product_search_document(p_id uuid, p_name text, p_description text)
+RETURNS tsvector AS $$
+  SELECT
+    setweight(to_tsvector('simple', coalesce(p_name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce((
+      SELECT string_agg(v.name || ' ' || v.sku, ' ')
+      FROM product_variants v
+      WHERE v.product_id = p_id AND v.is_active = true
+    ), '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(p_description, '')), 'C');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION products_search_vector_update() RETURNS trigger AS $$
+BEGIN
+  NEW.search_vector := product_search_document(NEW.id, NEW.name, NEW.description);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_products_search_vector ON products;
+CREATE TRIGGER trg_products_search_vector
+BEFORE INSERT OR UPDATE OF name, description ON products
+FOR EACH ROW EXECUTE FUNCTION products_search_vector_update();
+
+-- Variant changes refresh the parent product's document.
+CREATE OR REPLACE FUNCTION product_variants_search_refresh() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    UPDATE products SET search_vector = product_search_document(id, name, description)
+    WHERE id = OLD.product_id;
+  END IF;
+  IF TG_OP = 'INSERT' THEN
+    UPDATE products SET search_vector = product_search_document(id, name, description)
+    WHERE id = NEW.product_id;
+  ELSIF TG_OP = 'UPDATE' AND NEW.product_id <> OLD.product_id THEN
+    UPDATE products SET search_vector = product_search_document(id, name, description)
+    WHERE id = NEW.product_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_product_variants_search_refresh ON product_variants;
+CREATE TRIGGER trg_product_variants_search_refresh
+AFTER INSERT OR DELETE OR UPDATE OF name, sku, is_active, product_id ON product_variants
+FOR EACH ROW EXECUTE FUNCTION product_variants_search_refresh();
+
+-- Backfill
+UPDATE products SET search_vector = product_search_document(id, name, description);
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING gin(search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING gin(name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_variants_sku_trgm ON product_variants USING gin(sku gin_trgm_ops);
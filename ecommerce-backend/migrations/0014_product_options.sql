@@ -0,0 +1,43 @@
+-- ===== Product options (size/color/...) =====
+CREATE TABLE IF NOT EXISTS product_options (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id uuid NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  position int NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- "Size" and "size" are the same option (lookups compare lower-cased).
+CREATE UNIQUE INDEX IF NOT EXISTS uq_product_options_name
+  ON product_options(product_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS product_option_values (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  option_id uuid NOT NULL REFERENCES product_options(id) ON DELETE CASCADE,
+  value text NOT NULL,
+  position int NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT uq_product_option_values_id_option UNIQUE (id, option_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_product_option_values_value
+  ON product_option_values(option_id, lower(value));
+
+-- One value per option per variant; the composite FK keeps value and option consistent.
+CREATE TABLE IF NOT EXISTS product_variant_option_values (
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  option_id uuid NOT NULL REFERENCES product_options(id) ON DELETE CASCADE,
+  value_id uuid NOT NULL,
+  PRIMARY KEY (variant_id, option_id),
+  FOREIGN KEY (value_id, option_id) REFERENCES product_option_values(id, option_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_option_values_value ON product_variant_option_values(value_id);
+
+-- Sorted, comma-joined value ids; NULL for variants without options.
+ALTER TABLE product_variants
+  ADD COLUMN IF NOT EXISTS option_signature text NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_variants_option_signature
+  ON product_variants(product_id, option_signature)
+  WHERE option_signature IS NOT NULL;
@@ -0,0 +1,3 @@
+-- ===== Order weight (drives shipping_total) =====
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS total_weight_grams int NOT NULL DEFAULT 0 CHECK (total_weight_grams >= 0);
@@ -0,0 +1,75 @@
+-- ===== Scheduled price changes =====
+-- A schedule without ends_at is a permanent change; with ends_at it is a sale
+-- window and the worker restores the captured revert_* prices when it closes.
+CREATE TABLE IF NOT EXISTS price_schedules (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  price bigint NOT NULL CHECK (price >= 0),
+  compare_at_price bigint NULL CHECK (compare_at_price IS NULL OR compare_at_price >= 0),
+  starts_at timestamptz NOT NULL,
+  ends_at timestamptz NULL,
+
+  -- pending -> active (window open) -> completed; pending -> completed (permanent);
+  -- pending -> cancelled; pending -> expired (window closed before it was applied)
+  status text NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'active', 'completed', 'cancelled', 'expired')),
+
+  revert_price bigint NULL,
+  revert_compare_at_price bigint NULL,
+
+  created_by text NOT NULL DEFAULT 'system',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  applied_at timestamptz NULL,
+  reverted_at timestamptz NULL,
+
+  CONSTRAINT chk_price_schedules_window CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_schedules_variant ON price_schedules(variant_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_price_schedules_due_start ON price_schedules(starts_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_price_schedules_due_end ON price_schedules(ends_at) WHERE status = 'active';
+
+-- ===== Price history =====
+CREATE TABLE IF NOT EXISTS variant_price_history (
+  id bigserial PRIMARY KEY,
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  price bigint NOT NULL,
+  compare_at_price bigint NULL,
+  source text NOT NULL DEFAULT 'manual', -- manual/schedule/revert/backfill
+  schedule_id uuid NULL REFERENCES price_schedules(id) ON DELETE SET NULL,
+  changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_price_history_variant ON variant_price_history(variant_id, changed_at DESC, id DESC);
+
+-- Writers tag their change with SET LOCAL app.price_change_source / app.price_schedule_id;
+-- anything untagged (admin PATCH, SQL console) is recorded as 'manual'.
+CREATE OR REPLACE FUNCTION product_variants_price_history() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' THEN
+    IF NEW.price IS NOT DISTINCT FROM OLD.price
+       AND NEW.compare_at_price IS NOT DISTINCT FROM OLD.compare_at_price THEN
+      RETURN NULL;
+    END IF;
+  END IF;
+
+  INSERT INTO variant_price_history (variant_id, price, compare_at_price, source, schedule_id)
+  VALUES (
+    NEW.id, NEW.price, NEW.compare_at_price,
+    COALESCE(NULLIF(current_setting('app.price_change_source', true), ''), 'manual'),
+    NULLIF(current_setting('app.price_schedule_id', true), '')::uuid
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_product_variants_price_history ON product_variants;
+CREATE TRIGGER trg_product_variants_price_history
+AFTER INSERT OR UPDATE OF price, compare_at_price ON product_variants
+FOR EACH ROW EXECUTE FUNCTION product_variants_price_history();
+
+-- Backfill: the current price has held at least since the variant's last update.
+INSERT INTO variant_price_history (variant_id, price, compare_at_price, source, changed_at)
+SELECT v.id, v.price, v.compare_at_price, 'backfill', v.updated_at
+FROM product_variants v
+WHERE NOT EXISTS (SELECT 1 FROM variant_price_history h WHERE h.variant_id = v.id);
@@ -0,0 +1,121 @@
+-- ===== Currencies =====
+-- Every amount column stores minor units of its row's currency; exponent is
+-- the ISO 4217 number of minor-unit digits. IDR is kept at 0: the existing
+-- catalog stores whole rupiah and sen are not in circulation.
+-- product_variants.price is the price list of the base currency.
+CREATE TABLE IF NOT EXISTS currencies (
+  code text PRIMARY KEY CHECK (code ~ '^[A-Z]{3}$'),
+  exponent smallint NOT NULL CHECK (exponent BETWEEN 0 AND 4),
+  is_base boolean NOT NULL DEFAULT false,
+  is_active boolean NOT NULL DEFAULT true,
+  -- price variants without an explicit price by converting the base price
+  fx_fallback boolean NOT NULL DEFAULT false,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_currencies_base ON currencies(is_base) WHERE is_base;
+
+INSERT INTO currencies (code, exponent, is_base)
+VALUES ('IDR', 0, true)
+ON CONFLICT (code) DO NOTHING;
+
+-- ===== Price lists =====
+CREATE TABLE IF NOT EXISTS variant_prices (
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  currency text NOT NULL REFERENCES currencies(code),
+  price bigint NOT NULL CHECK (price >= 0),
+  compare_at_price bigint NULL CHECK (compare_at_price IS NULL OR compare_at_price >= 0),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (variant_id, currency)
+);
+
+-- 1 major unit of base_currency = rate major units of quote_currency.
+-- Maintained locally by admins, no live feed.
+CREATE TABLE IF NOT EXISTS fx_rates (
+  base_currency text NOT NULL REFERENCES currencies(code),
+  quote_currency text NOT NULL REFERENCES currencies(code),
+  rate numeric(20,10) NOT NULL CHECK (rate > 0),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (base_currency, quote_currency),
+  CHECK (base_currency <> quote_currency)
+);
+
+-- convert_minor converts an amount in minor units between currencies using
+-- fx_rates, rounding half away from zero. The direct from->to rate wins; a
+-- stored to->from rate is used inverted. NULL when neither is configured.
+CREATE OR REPLACE FUNCTION convert_minor(p_amount bigint, p_from text, p_to text)
+RETURNS bigint LANGUAGE sql STABLE AS $$
+  SELECT CASE
+    WHEN p_amount IS NULL THEN NULL
+    WHEN p_from = p_to THEN p_amount
+    ELSE (
+      SELECT round(p_amount * x.rate * power(10::numeric, t.exponent - f.exponent))::bigint
+      FROM (
+        SELECT rate
+        FROM (
+          SELECT r.rate, 1 AS pref FROM fx_rates r WHERE r.base_currency = p_from AND r.quote_currency = p_to
+          UNION ALL
+          SELECT 1 / r.rate, 2 FROM fx_rates r WHERE r.base_currency = p_to AND r.quote_currency = p_from
+        ) rates
+        ORDER BY pref
+        LIMIT 1
+      ) x
+      CROSS JOIN currencies f
+      CROSS JOIN currencies t
+      WHERE f.code = p_from AND t.code = p_to
+    )
+  END;
+$$;
+
+-- variant_price_in resolves a variant's price in a currency: the explicit
+-- price list entry first, then the base price converted when the currency
+-- allows FX fallback. No row means the variant can't be sold in it.
+CREATE OR REPLACE FUNCTION variant_price_in(p_variant uuid, p_currency text)
+RETURNS TABLE (price bigint, compare_at_price bigint) LANGUAGE sql STABLE AS $$
+  SELECT vp.price, vp.compare_at_price
+  FROM variant_prices vp
+  JOIN currencies c ON c.code = vp.currency AND c.is_active
+  WHERE vp.variant_id = p_variant AND vp.currency = p_currency
+  UNION ALL
+  (
+    SELECT x.price, x.compare_at_price
+    FROM (
+      SELECT convert_minor(v.price, b.code, c.code) AS price,
+             convert_minor(v.compare_at_price, b.code, c.code) AS compare_at_price
+      FROM product_variants v
+      CROSS JOIN currencies b
+      JOIN currencies c ON c.code = p_currency AND c.is_active
+      WHERE v.id = p_variant AND b.is_base AND (c.is_base OR c.fx_fallback)
+        AND NOT EXISTS (
+          SELECT 1 FROM variant_prices vp WHERE vp.variant_id = p_variant AND vp.currency = p_currency
+        )
+    ) x
+    WHERE x.price IS NOT NULL
+  )
+  LIMIT 1;
+$$;
+
+-- ===== Currency on carts and payments =====
+ALTER TABLE carts
+  ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'IDR' REFERENCES currencies(code);
+
+ALTER TABLE payments
+  ADD COLUMN IF NOT EXISTS currency text NULL;
+
+UPDATE payments p SET currency = o.currency
+FROM orders o
+WHERE o.id = p.order_id AND p.currency IS NULL;
+
+ALTER TABLE payments ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE payments ALTER COLUMN currency SET DEFAULT 'IDR';
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_orders_currency') THEN
+    ALTER TABLE orders ADD CONSTRAINT fk_orders_currency FOREIGN KEY (currency) REFERENCES currencies(code);
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_payments_currency') THEN
+    ALTER TABLE payments ADD CONSTRAINT fk_payments_currency FOREIGN KEY (currency) REFERENCES currencies(code);
+  END IF;
+END $$;
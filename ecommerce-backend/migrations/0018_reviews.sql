@@ -0,0 +1,65 @@
+-- ===== Reviews =====
+-- One review per user and variant, backed by a paid order of that variant.
+CREATE TABLE IF NOT EXISTS reviews (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id uuid NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  order_id uuid NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+
+  rating smallint NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  title text NOT NULL DEFAULT '',
+  body text NOT NULL DEFAULT '',
+
+  -- only approved reviews are public and counted
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+  moderation_note text NOT NULL DEFAULT '',
+  moderated_by text NULL,
+  moderated_at timestamptz NULL,
+
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT uq_reviews_user_variant UNIQUE (user_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_product_public ON reviews(product_id, created_at DESC, id DESC) WHERE status = 'approved';
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews(status, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_reviews_user ON reviews(user_id, created_at DESC);
+
+-- Purchase lookups go by user
+CREATE INDEX IF NOT EXISTS idx_orders_user ON orders(user_id, created_at DESC) WHERE user_id IS NOT NULL;
+
+-- ===== Product rating rollup =====
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS rating_avg numeric(3,2) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS rating_count int NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_products_rating ON products(rating_avg DESC, rating_count DESC, id DESC);
+
+CREATE OR REPLACE FUNCTION refresh_product_rating(p_product uuid) RETURNS void AS $$
+  UPDATE products p
+  SET rating_avg = s.avg, rating_count = s.n
+  FROM (
+    SELECT COALESCE(round(AVG(rating), 2), 0) AS avg, COUNT(*)::int AS n
+    FROM reviews
+    WHERE product_id = p_product AND status = 'approved'
+  ) s
+  WHERE p.id = p_product;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION reviews_rating_refresh() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    PERFORM refresh_product_rating(OLD.product_id);
+  END IF;
+  IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.product_id <> OLD.product_id) THEN
+    PERFORM refresh_product_rating(NEW.product_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_reviews_rating_refresh ON reviews;
+CREATE TRIGGER trg_reviews_rating_refresh
+AFTER INSERT OR DELETE OR UPDATE OF rating, status, product_id ON reviews
+FOR EACH ROW EXECUTE FUNCTION reviews_rating_refresh();
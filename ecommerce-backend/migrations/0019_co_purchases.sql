@@ -0,0 +1,13 @@
+-- ===== Co-purchase recommendations =====
+-- Rebuilt in full by the catalog's recommendation job from paid orders;
+-- each product keeps only its top pairs.
+CREATE TABLE IF NOT EXISTS product_co_purchases (
+  product_id uuid NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  related_product_id uuid NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  orders_count int NOT NULL CHECK (orders_count > 0), -- paid orders containing both
+  computed_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (product_id, related_product_id),
+  CHECK (product_id <> related_product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_co_purchases_rank ON product_co_purchases(product_id, orders_count DESC, related_product_id);
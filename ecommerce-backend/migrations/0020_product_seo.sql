@@ -0,0 +1,34 @@
+-- ===== Product SEO =====
+-- Empty means "derive from the product" (name, description, storefront URL).
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS meta_title text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS meta_description text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS canonical_url text NOT NULL DEFAULT '';
+
+-- ===== Product slug history =====
+-- Old slugs keep resolving to the renamed product. A live product slug always
+-- wins: taking a slug (new product or rename) drops it from the history.
+CREATE TABLE IF NOT EXISTS product_slug_history (
+  slug text PRIMARY KEY,
+  product_id uuid NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_slug_history_product ON product_slug_history(product_id);
+
+CREATE OR REPLACE FUNCTION products_slug_history() RETURNS trigger AS $$
+BEGIN
+  DELETE FROM product_slug_history WHERE slug = NEW.slug;
+  IF TG_OP = 'UPDATE' AND NEW.slug <> OLD.slug THEN
+    INSERT INTO product_slug_history (slug, product_id)
+    VALUES (OLD.slug, NEW.id)
+    ON CONFLICT (slug) DO UPDATE SET product_id = EXCLUDED.product_id, created_at = now();
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_products_slug_history ON products;
+CREATE TRIGGER trg_products_slug_history
+AFTER INSERT OR UPDATE OF slug ON products
+FOR EACH ROW EXECUTE FUNCTION products_slug_history();
@@ -0,0 +1,12 @@
+-- ===== Carts owned by users =====
+-- Statuses: active/converted/abandoned, plus merged for a guest cart whose
+-- items moved into the user's cart at login.
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_carts_user') THEN
+    ALTER TABLE carts ADD CONSTRAINT fk_carts_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+  END IF;
+END $$;
+
+-- A user has at most one active cart
+CREATE UNIQUE INDEX IF NOT EXISTS uq_carts_user_active ON carts(user_id) WHERE status = 'active' AND user_id IS NOT NULL;
@@ -0,0 +1,6 @@
+-- ===== Cart item prices =====
+-- Unit price (cart currency) when the item was added or its currency last
+-- switched, so the cart can flag lines whose price changed since. NULL for
+-- items added before this column existed.
+ALTER TABLE cart_items
+  ADD COLUMN IF NOT EXISTS price_at_add bigint NULL;
@@ -0,0 +1,11 @@
+-- ===== Abandoned carts =====
+-- A cart with items and no activity for the configured window is marked
+-- abandoned; abandoned_value is its priced total at that moment. A cart that
+-- is recovered (link or the shopper coming back) keeps abandoned_at and gets
+-- recovered_at, so the report can follow it through to checkout.
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS abandoned_at timestamptz NULL;
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS abandoned_value bigint NULL;
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS recovered_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS idx_carts_active_updated ON carts(updated_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_carts_created ON carts(created_at);
@@ -0,0 +1,15 @@
+-- ===== Wishlist =====
+-- price_at_save and in_stock_at_save are what the shopper saw when saving;
+-- listing compares against them for the price_drop and back_in_stock flags.
+CREATE TABLE IF NOT EXISTS wishlist_items (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  variant_id uuid NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  currency text NOT NULL REFERENCES currencies(code),
+  price_at_save bigint NULL, -- NULL when not sold in currency at the time
+  in_stock_at_save boolean NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (user_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_user ON wishlist_items(user_id, created_at DESC);
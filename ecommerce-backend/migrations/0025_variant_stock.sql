@@ -0,0 +1,21 @@
+-- ===== Shared stock figures =====
+-- Catalog, cart, checkout and wishlist read sellable stock through these, so
+-- "available" means the same thing everywhere. Both are plain SQL the planner
+-- inlines: stock_available as an expression, variant_available as a
+-- subquery when called from FROM (LATERAL or a scalar subquery).
+
+-- stock_available is the unreserved part of one inventory_items row.
+CREATE OR REPLACE FUNCTION stock_available(p_on_hand int, p_reserved int)
+RETURNS int LANGUAGE sql IMMUTABLE AS $$
+  SELECT GREATEST(p_on_hand - p_reserved, 0)
+$$;
+
+-- variant_available is the unreserved stock of a variant over active
+-- locations; always exactly one row.
+CREATE OR REPLACE FUNCTION variant_available(p_variant uuid)
+RETURNS TABLE (available int) LANGUAGE sql STABLE AS $$
+  SELECT COALESCE(SUM(stock_available(ii.stock_on_hand, ii.reserved)), 0)::int
+  FROM inventory_items ii
+  JOIN locations l ON l.id = ii.location_id
+  WHERE ii.variant_id = p_variant AND l.is_active = true
+$$;
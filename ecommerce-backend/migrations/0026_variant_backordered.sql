@@ -0,0 +1,12 @@
+-- ===== Outstanding backorders =====
+-- variant_backordered is the units of a variant owed to open orders that
+-- received stock hasn't filled yet; backorder_limit caps this. Like
+-- variant_available it is inlined when called from FROM; always one row.
+CREATE OR REPLACE FUNCTION variant_backordered(p_variant uuid)
+RETURNS TABLE (qty int) LANGUAGE sql STABLE AS $$
+  SELECT COALESCE(SUM(oi.backordered_qty), 0)::int
+  FROM order_items oi
+  JOIN orders o ON o.id = oi.order_id
+  WHERE oi.variant_id = p_variant AND oi.backordered_qty > 0
+    AND o.status IN ('pending_payment', 'paid')
+$$;